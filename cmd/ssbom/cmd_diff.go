@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/canonical/ssbom/internal/diff"
+)
+
+const diffDescription = `Compare the packages and files described by two SBOM documents, which may
+be in different formats. The exit status is 0 if they are equivalent, 1 if
+they differ and 2 on usage errors.`
+
+func init() {
+	addCommand("diff", "Compare two SBOM documents", runDiff)
+}
+
+func runDiff(args []string) error {
+	fs := newFlagSet("diff", "<old-sbom-file> <new-sbom-file>", diffDescription)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageErrorf("expected two SBOM files")
+	}
+
+	var inventories []*diff.Inventory
+	for _, name := range positional {
+		data, err := readInput(name)
+		if err != nil {
+			return err
+		}
+		inv, err := diff.Read(data)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		inventories = append(inventories, inv)
+	}
+
+	changes := diff.Compare(inventories[0], inventories[1])
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c)
+	}
+	if len(changes) > 0 {
+		return errSilent
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/canonical/ssbom/internal/format"
+	"github.com/canonical/ssbom/sbom"
+)
+
+const generateDescription = `Build an SBOM document with the chisel jsonwall manifest of the chiselled
+rootfs and save it out as a json file to the output file if specified;
+otherwise as manifest.spdx.json (manifest.spdx3.json or manifest.cdx.json)
+in the current working directory. Use "-o -" to write it to stdout.
+
+The rootfs may be a directory, a tar archive of the rootfs, an OCI image
+layout directory, or a tar archive of an OCI image layout or of the output
+of "docker save". Tar archives may be gzip or zstd compressed; they are
+streamed through without being extracted.
+
+Every manifest.wall file found in the rootfs is read, as chisel may write
+the manifest anywhere. Several manifests are merged into one SBOM, with a
+warning for each entry on which they disagree.
+
+Alternatively, a chisel manifest may be given directly with --manifest ("-"
+for stdin), compressed with zstd or not, together with an optional
+os-release file. A rootfs is not needed then.`
+
+func init() {
+	addCommand("generate", "Generate an SBOM for a chiselled rootfs", runGenerate)
+}
+
+func runGenerate(args []string) error {
+	flags := newFlagSet("generate", "<path-to-chiselled-rootfs-or-image>", generateDescription)
+	formatName := flags.String("format", string(format.SPDX), "output format: spdx, spdx3, cyclonedx or cyclonedx-1.5")
+	outPath := flags.String("o", "", "output file, or - for stdout")
+	image := flags.String("image", "", "image to read from an archive holding several, by tag or reference name")
+	platform := flags.String("platform", "", "platform to read from a multi-platform image, as os/arch[/variant]")
+	manifestFile := flags.String("manifest", "", "chisel manifest file to read instead of the one in the rootfs, or - for stdin")
+	osReleaseFile := flags.String("os-release", "", "os-release file to read instead of the one in the rootfs")
+	copyrightDir := flags.String("copyright-dir", "", "directory of Debian copyright files, as <package>/copyright, to read instead of the ones in the rootfs")
+	aptIndexes := flags.String("apt-index", "", "comma-separated apt Packages or Sources index files describing the packages")
+	aptMirror := flags.String("apt-mirror", "", "apt archive mirror directory whose indexes describe the packages")
+	archiveURL := flags.String("archive-url", "", "apt archive URL for the download location of the packages found in the apt indexes")
+	cpeMapFile := flags.String("cpe-map", "", "file of package name to CPE vendor:product mappings overriding the bundled ones")
+	proPocket := flags.String("pro-pocket", "", "Ubuntu Pro pocket of the ESM or FIPS packages: esm-infra, esm-apps, fips, fips-updates or fips-preview")
+	documentName := flags.String("document-name", "", "name of the document (default \"Chiselled Ubuntu Rootfs\")")
+	namespacePrefix := flags.String("namespace-prefix", "", "URI prefix of the SPDX document namespace (default \"https://spdx.org/spdxdocs\")")
+	creatorOrganization := flags.String("creator-organization", "", "organization that creates the document, recorded in the SPDX creators")
+	reproducible := flags.Bool("reproducible", false, "write the same SBOM for the same content, dated with SOURCE_DATE_EPOCH if set")
+	verify := flags.Bool("verify", false, "check the files of the rootfs against the manifest and report the differences")
+	verifyStrict := flags.Bool("verify-strict", false, "fail if the files of the rootfs do not match the manifest; implies --verify")
+	verifyExtra := flags.Bool("verify-extra", false, "also report the files of the rootfs that are not in the manifest; implies --verify")
+	unmanaged := flags.Bool("unmanaged", false, "add the files of the rootfs that are not in the manifest to the SBOM and summarize them")
+	goModules := flags.Bool("go-modules", false, "add the modules linked into the Go executables of the rootfs to the SBOM")
+	catalogNames := flags.String("catalog", "", "comma-separated ecosystems whose packages to add to the SBOM: python, node, java or all")
+	lenient := flags.Bool("lenient", false, "skip manifest entries that cannot be read, annotating the document, instead of failing")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	outFormat, err := format.Parse(*formatName)
+	if err != nil {
+		return &usageError{msg: err.Error()}
+	}
+	switch {
+	case len(positional) == 2 && *outPath == "":
+		// Backwards compatibility: the output file may be given as the
+		// second positional argument.
+		*outPath = positional[1]
+		positional = positional[:1]
+	case len(positional) == 0 && *manifestFile != "":
+	case len(positional) != 1:
+		return usageErrorf("expected a single path to a chiselled rootfs")
+	}
+
+	var cataloguers []sbom.Cataloguer
+	for _, name := range strings.Split(*catalogNames, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "all":
+			cataloguers = append(cataloguers, sbom.Cataloguers()...)
+		default:
+			c, err := sbom.LookupCataloguer(name)
+			if err != nil {
+				return &usageError{msg: err.Error()}
+			}
+			cataloguers = append(cataloguers, c)
+		}
+	}
+
+	var cpeMap sbom.CPEMap
+	if *cpeMapFile != "" {
+		data, err := os.ReadFile(*cpeMapFile)
+		if err != nil {
+			return err
+		}
+		if cpeMap, err = sbom.ParseCPEMap(data); err != nil {
+			return err
+		}
+	}
+
+	if *outPath == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		*outPath = filepath.Join(cwd, outFormat.DefaultFileName())
+	}
+
+	source := &sbom.Source{}
+	if len(positional) == 1 {
+		source.Path = positional[0]
+	}
+	switch *manifestFile {
+	case "":
+	case "-":
+		source.Manifest = stdin
+	default:
+		f, err := os.Open(*manifestFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		source.Manifest = f
+	}
+	if *osReleaseFile != "" {
+		f, err := os.Open(*osReleaseFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		source.OSRelease = f
+	}
+	if *copyrightDir != "" {
+		source.Copyrights = os.DirFS(*copyrightDir)
+	}
+	for _, name := range strings.Split(*aptIndexes, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		source.AptIndexes = append(source.AptIndexes, f)
+	}
+	if *aptMirror != "" {
+		source.AptMirror = os.DirFS(*aptMirror)
+	}
+
+	result, err := sbom.Generate(context.Background(), source, &sbom.Options{
+		Format:              outFormat,
+		Image:               *image,
+		Platform:            *platform,
+		Lenient:             *lenient,
+		Verify:              *verify,
+		VerifyStrict:        *verifyStrict,
+		VerifyExtra:         *verifyExtra,
+		Unmanaged:           *unmanaged,
+		GoModules:           *goModules,
+		Cataloguers:         cataloguers,
+		ArchiveURL:          *archiveURL,
+		CPEMap:              cpeMap,
+		ProPocket:           *proPocket,
+		DocumentName:        *documentName,
+		NamespacePrefix:     *namespacePrefix,
+		CreatorOrganization: *creatorOrganization,
+		ToolVersion:         ssbomVersion(),
+		Reproducible:        *reproducible,
+	})
+	var verifyErr *sbom.VerifyError
+	if errors.As(err, &verifyErr) {
+		for _, d := range verifyErr.Discrepancies {
+			fmt.Fprintf(stderr, "Verification: %s\n", d)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Manifests) > 1 {
+		fmt.Fprintf(stderr, "Merged %d chisel manifests found in the rootfs.\n", len(result.Manifests))
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", warning)
+	}
+	for _, d := range result.Discrepancies {
+		fmt.Fprintf(stderr, "Verification: %s\n", d)
+	}
+	if *verify || *verifyStrict || *verifyExtra {
+		fmt.Fprintf(stderr, "Verified the rootfs against its manifest: %d discrepancies found.\n", len(result.Discrepancies))
+	}
+	if *unmanaged {
+		printUnmanagedSummary(result.Unmanaged)
+	}
+	if *goModules {
+		fmt.Fprintf(stderr, "Found %d Go executables in the rootfs.\n", len(result.GoBinaries))
+	}
+	if len(cataloguers) > 0 {
+		fmt.Fprintf(stderr, "Found %d language ecosystem packages in the rootfs.\n", len(result.EcosystemPackages))
+	}
+
+	invalid := 0
+	for _, issue := range result.Validation {
+		fmt.Fprintf(stderr, "Validation: %s\n", issue)
+		if issue.Severity == sbom.ValidationError {
+			invalid++
+		}
+	}
+
+	if *outPath == "-" {
+		if err := result.Write(stdout); err != nil {
+			return err
+		}
+	} else {
+		fileOut, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer fileOut.Close()
+
+		if err := result.Write(fileOut); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "%s document created at %v\n", formatNames[outFormat], *outPath)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("generated %s document is invalid: %d validation error(s) found", formatNames[outFormat], invalid)
+	}
+	return nil
+}
+
+// printUnmanagedSummary prints the number and total size of the unmanaged
+// files, overall and per top-level directory.
+func printUnmanagedSummary(files []*sbom.UnmanagedFile) {
+	type dirSummary struct {
+		count int
+		size  int64
+	}
+	var total dirSummary
+	dirs := make(map[string]*dirSummary)
+	for _, f := range files {
+		dir, _, ok := strings.Cut(strings.TrimPrefix(f.Path, "/"), "/")
+		if !ok {
+			dir = ""
+		}
+		dir = "/" + dir
+		if dirs[dir] == nil {
+			dirs[dir] = &dirSummary{}
+		}
+		dirs[dir].count++
+		dirs[dir].size += f.Size
+		total.count++
+		total.size += f.Size
+	}
+	fmt.Fprintf(stderr, "Found %d files not in the manifest, %d bytes in total.\n", total.count, total.size)
+	names := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+	for _, dir := range names {
+		fmt.Fprintf(stderr, "  %s: %d files, %d bytes\n", dir, dirs[dir].count, dirs[dir].size)
+	}
+}
+
+var formatNames = map[format.Format]string{
+	format.SPDX:        "SPDX",
+	format.SPDX3:       "SPDX 3.0",
+	format.CycloneDX:   "CycloneDX",
+	format.CycloneDX15: "CycloneDX",
+}
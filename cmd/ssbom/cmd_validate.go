@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/canonical/ssbom/internal/validate"
+)
+
+const validateDescription = `Check that an SBOM document in any of the supported formats follows its
+specification: required fields are present, identifiers are well-formed,
+relationships and dependencies only reference elements of the document, and
+checksums are well-formed digests. The format is detected from the document;
+"-" reads it from stdin.
+
+Each issue is reported with its severity, "error" or "warning". The command
+fails if any error is found.`
+
+func init() {
+	addCommand("validate", "Validate an SBOM document", runValidate)
+}
+
+func runValidate(args []string) error {
+	fs := newFlagSet("validate", "<sbom-file>", validateDescription)
+	jsonOutput := fs.Bool("json", false, "write the report as JSON to stdout")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf("expected a single SBOM file")
+	}
+
+	data, err := readInput(positional[0])
+	if err != nil {
+		return err
+	}
+	report, err := validate.Check(data)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		e := json.NewEncoder(stdout)
+		e.SetIndent("", "  ")
+		if err := e.Encode(report); err != nil {
+			return err
+		}
+		if !report.Valid() {
+			return errSilent
+		}
+		return nil
+	}
+	for _, issue := range report.Issues {
+		fmt.Fprintln(stdout, issue)
+	}
+	if !report.Valid() {
+		fmt.Fprintf(stderr, "Found %d error(s) and %d warning(s) in the %s document.\n", report.Count(validate.Error), report.Count(validate.Warning), report.Format)
+		return errSilent
+	}
+	fmt.Fprintf(stdout, "%s: valid %s document\n", positional[0], report.Format)
+	return nil
+}
+
+// readInput reads the named file, or stdin if the name is "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(name)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/canonical/ssbom/sbom"
+)
+
+const validateManifestDescription = `Check that the entries of the chisel manifests of a chiselled rootfs can be
+read and agree with each other: every slice belongs to a known package, every
+path belongs to known slices and has matching content entries, and so on.
+The rootfs may be given in any of the forms accepted by "generate", or a
+manifest may be given directly with --manifest ("-" for stdin).`
+
+func init() {
+	addCommand("validate-manifest", "Check the consistency of the chisel manifest of a rootfs", runValidateManifest)
+}
+
+func runValidateManifest(args []string) error {
+	flags := newFlagSet("validate-manifest", "<path-to-chiselled-rootfs-or-image>", validateManifestDescription)
+	image := flags.String("image", "", "image to read from an archive holding several, by tag or reference name")
+	platform := flags.String("platform", "", "platform to read from a multi-platform image, as os/arch[/variant]")
+	manifestFile := flags.String("manifest", "", "chisel manifest file to check instead of those in the rootfs, or - for stdin")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	source := &sbom.Source{}
+	switch {
+	case len(positional) == 1 && *manifestFile == "":
+		source.Path = positional[0]
+	case len(positional) == 0 && *manifestFile == "-":
+		source.Manifest = stdin
+	case len(positional) == 0 && *manifestFile != "":
+		f, err := os.Open(*manifestFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		source.Manifest = f
+	default:
+		return usageErrorf("expected either a single path to a chiselled rootfs or --manifest")
+	}
+
+	report, err := sbom.ValidateManifest(context.Background(), source, &sbom.Options{
+		Image:    *image,
+		Platform: *platform,
+	})
+	if err != nil {
+		return err
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", warning)
+	}
+	for _, issue := range report.Issues {
+		fmt.Fprintln(stdout, issue)
+	}
+	if len(report.Issues) > 0 {
+		fmt.Fprintf(stderr, "Found %d issue(s) in the manifest.\n", len(report.Issues))
+		return errSilent
+	}
+	fmt.Fprintln(stderr, "The manifest is consistent.")
+	return nil
+}
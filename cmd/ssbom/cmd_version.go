@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/canonical/ssbom/internal/builder"
+)
+
+// version may be set at build time with
+// -ldflags "-X main.version=<version>"; otherwise it is read from the
+// module build information.
+var version = ""
+
+func init() {
+	addCommand("version", "Show the version of ssbom", runVersion)
+}
+
+func runVersion(args []string) error {
+	fs := newFlagSet("version", "", "Show the version of ssbom.")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageErrorf("unexpected arguments")
+	}
+	fmt.Fprintln(stdout, ssbomVersion())
+	return nil
+}
+
+func ssbomVersion() string {
+	if version != "" {
+		return version
+	}
+	return builder.ToolVersion()
+}
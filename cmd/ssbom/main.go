@@ -1,95 +1,165 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strings"
+)
 
-	"github.com/canonical/ssbom/internal/converter"
-	"github.com/go-ini/ini"
-	"github.com/klauspost/compress/zstd"
-	"github.com/spdx/tools-golang/json"
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
 )
 
-const manifestPath = "var/lib/chisel/manifest.wall"
-const osReleasePath = "etc/os-release"
+const progName = "ssbom"
 
-func main() {
-	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
-	}
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
 }
 
-func run() error {
-	args := os.Args
-	if len(args) != 2 && len(args) != 3 {
-		fmt.Printf("Usage: %v <path-to-chiselled-rootfs> [<spdx-file-out>]\n", args[0])
-		fmt.Printf("  Build an SPDX document with the chisel jsonwall manifest\n")
-		fmt.Printf("  and save it out as a json file to <spdx-file-out> if specified;\n")
-		fmt.Printf("  otherwise as manifest.spdx.json in the current working directory.\n")
-		return nil
-	}
+var commands []*command
+
+func addCommand(name, summary string, run func(args []string) error) {
+	commands = append(commands, &command{name: name, summary: summary, run: run})
+}
 
-	// get the command-line arguments
-	root := args[1]
-	var outPath string
-	var fileOut *os.File
-
-	if len(args) == 3 {
-		outPath = args[2]
-	} else {
-		if cwd, err := os.Getwd(); err != nil {
-			return err
-		} else {
-			outPath = filepath.Join(cwd, "manifest.spdx.json")
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
 		}
 	}
+	return nil
+}
 
-	fileReader, err := os.Open(filepath.Join(root, manifestPath))
-	if err != nil {
-		return err
-	}
-	defer fileReader.Close()
-	zstdReader, err := zstd.NewReader(fileReader)
-	if err != nil {
-		return err
-	}
-	defer zstdReader.Close()
-
-	osRelease, err := ReadOSRelease(filepath.Join(root, osReleasePath))
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("Warning: OS release file not found in the chiselled rootfs.")
-			fmt.Println("         The generated SBOM will be incomplete for vulnerability identification.")
-		} else {
-			return err
-		}
+// usageError is returned by commands when they are invoked with invalid
+// arguments. An empty message means the error was already reported.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// errSilent is returned by commands that already reported why they failed.
+var errSilent = errors.New("silent error")
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
 	}
 
-	doc, err := converter.Convert(zstdReader, osRelease)
-	if err != nil {
-		return err
+	name := args[0]
+	cmd := findCommand(name)
+	switch {
+	case name == "help" || name == "-h" || name == "--help":
+		printUsage(stdout)
+		return exitOK
+	case cmd != nil:
+		args = args[1:]
+	case !strings.HasPrefix(name, "-") && pathExists(name):
+		// Backwards compatibility: "ssbom <rootfs> [<out>]" is "generate".
+		// Names of missing paths are taken to be mistyped commands.
+		cmd = findCommand("generate")
+	default:
+		fmt.Fprintf(stderr, "error: unknown command %q\n", name)
+		printUsage(stderr)
+		return exitUsage
 	}
 
-	fileOut, err = os.Create(outPath)
-	if err != nil {
-		return err
+	err := cmd.run(args)
+	var uerr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &uerr):
+		if uerr.msg != "" {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			fmt.Fprintf(stderr, "Run '%s %s -h' for usage.\n", progName, cmd.name)
+		}
+		return exitUsage
+	case errors.Is(err, errSilent):
+		return exitError
+	default:
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
 	}
-	defer fileOut.Close()
+}
 
-	json.Write(doc, fileOut, json.EscapeHTML(false))
-	fmt.Printf("SPDX document created at %v\n", outPath)
-	return nil
+func pathExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
 }
 
-func ReadOSRelease(configfile string) (string, error) {
-	cfg, err := ini.Load(configfile)
-	if err != nil {
-		return "", err
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [<options>] [<args>]\n\n", progName)
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the options of each command.\n", progName)
+}
 
-	versionId := cfg.Section("").Key("VERSION_ID").String()
+// newFlagSet returns a flag set for a command which prints its usage to
+// stderr.
+func newFlagSet(name, args, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s %s [<options>] %s\n\n", progName, name, args)
+		fmt.Fprintf(stderr, "%s\n", description)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(stderr, "\nOptions:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
 
-	return versionId, nil
+// parseArgs parses the flags in args, allowing them to be interspersed with
+// positional arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			// The flag set already printed the error and the usage.
+			return nil, &usageError{}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
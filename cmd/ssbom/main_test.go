@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	. "gopkg.in/check.v1"
+)
+
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+var testManifest = strings.Join([]string{
+	`{"jsonwall":"1.0","schema":"1.0","count":5}`,
+	`{"kind":"content","slice":"hello_bins","path":"/usr/bin/hello"}`,
+	`{"kind":"package","name":"hello","version":"2.10-3build1","sha256":"` + emptySHA256 + `","arch":"amd64"}`,
+	`{"kind":"path","path":"/usr/bin/hello","mode":"0755","slices":["hello_bins"],"sha256":"` + emptySHA256 + `","size":0}`,
+	`{"kind":"slice","name":"hello_bins"}`,
+}, "\n")
+
+// runMain runs the command line with the given stdin and returns its exit
+// status and output.
+func runMain(input string, args ...string) (status int, out, errOut string) {
+	var outBuf, errBuf bytes.Buffer
+	oldStdin, oldStdout, oldStderr := stdin, stdout, stderr
+	stdin, stdout, stderr = strings.NewReader(input), &outBuf, &errBuf
+	defer func() {
+		stdin, stdout, stderr = oldStdin, oldStdout, oldStderr
+	}()
+	status = run(args)
+	return status, outBuf.String(), errBuf.String()
+}
+
+// makeRootfs writes a rootfs with the given manifest and returns its path.
+func makeRootfs(c *C, manifest string) string {
+	dir := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(dir, "var/lib/chisel"), 0755), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(dir, "etc"), 0755), IsNil)
+	// chisel writes zstd-compressed manifests.
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte(manifest))
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "var/lib/chisel/manifest.wall"), buf.Bytes(), 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "etc/os-release"), []byte("ID=ubuntu\nVERSION_ID=\"24.04\"\n"), 0644), IsNil)
+	return dir
+}
+
+var usageTests = []struct {
+	summary string
+	args    []string
+	stderr  string
+}{{
+	summary: "No command",
+	stderr:  `(?s)Usage: ssbom <command>.*`,
+}, {
+	summary: "Unknown command",
+	args:    []string{"--foo"},
+	stderr:  `(?s)error: unknown command "--foo"\nUsage: ssbom <command>.*`,
+}, {
+	summary: "Mistyped command",
+	args:    []string{"valdate", "sbom.json"},
+	stderr:  `(?s)error: unknown command "valdate"\nUsage: ssbom <command>.*`,
+}, {
+	summary: "Unknown flag",
+	args:    []string{"generate", "--foo", "rootfs"},
+	stderr:  `(?s)flag provided but not defined: -foo\nUsage: ssbom generate .*`,
+}, {
+	summary: "Invalid format",
+	args:    []string{"generate", "--format", "xml", "rootfs"},
+	stderr:  `(?s)error: .*xml.*\nRun 'ssbom generate -h' for usage.\n`,
+}, {
+	summary: "Missing rootfs",
+	args:    []string{"generate"},
+	stderr:  "error: expected a single path to a chiselled rootfs\nRun 'ssbom generate -h' for usage.\n",
+}, {
+	summary: "Missing SBOM",
+	args:    []string{"validate"},
+	stderr:  "error: expected a single SBOM file\nRun 'ssbom validate -h' for usage.\n",
+}, {
+	summary: "Missing second SBOM",
+	args:    []string{"diff", "a.json"},
+	stderr:  "error: expected two SBOM files\nRun 'ssbom diff -h' for usage.\n",
+}, {
+	summary: "Missing manifest",
+	args:    []string{"validate-manifest"},
+	stderr:  "error: expected either a single path to a chiselled rootfs or --manifest\nRun 'ssbom validate-manifest -h' for usage.\n",
+}, {
+	summary: "Unexpected version argument",
+	args:    []string{"version", "foo"},
+	stderr:  "error: unexpected arguments\nRun 'ssbom version -h' for usage.\n",
+}}
+
+func (s *S) TestUsageErrors(c *C) {
+	for _, test := range usageTests {
+		c.Logf("Running test: %s", test.summary)
+		status, out, errOut := runMain("", test.args...)
+		c.Assert(status, Equals, exitUsage)
+		c.Assert(out, Equals, "")
+		c.Assert(errOut, Matches, test.stderr)
+	}
+}
+
+func (s *S) TestHelp(c *C) {
+	status, out, _ := runMain("", "help")
+	c.Assert(status, Equals, exitOK)
+	c.Assert(out, Matches, `(?s)Usage: ssbom <command>.*  validate-manifest .*`)
+
+	status, _, errOut := runMain("", "generate", "-h")
+	c.Assert(status, Equals, exitOK)
+	c.Assert(errOut, Matches, `(?s)Usage: ssbom generate .*`)
+}
+
+func (s *S) TestVersion(c *C) {
+	status, out, _ := runMain("", "version")
+	c.Assert(status, Equals, exitOK)
+	c.Assert(out, Equals, ssbomVersion()+"\n")
+}
+
+func (s *S) TestGenerateStdout(c *C) {
+	rootfs := makeRootfs(c, testManifest)
+	status, out, errOut := runMain("", "generate", "--format", "cyclonedx", "-o", "-", rootfs)
+	c.Assert(status, Equals, exitOK, Commentf("%s", errOut))
+	c.Assert(out, Matches, `\{"bomFormat":"CycloneDX","specVersion":"1.6",.*\n`)
+
+	status, out, errOut = runMain(out, "validate", "-")
+	c.Assert(status, Equals, exitOK, Commentf("%s", errOut))
+	c.Assert(out, Equals, "-: valid cyclonedx document\n")
+}
+
+func (s *S) TestGenerateLegacy(c *C) {
+	rootfs := makeRootfs(c, testManifest)
+	output := filepath.Join(c.MkDir(), "sbom.json")
+
+	// "ssbom <rootfs> <output>" is "ssbom generate -o <output> <rootfs>".
+	status, _, errOut := runMain("", rootfs, output)
+	c.Assert(status, Equals, exitOK, Commentf("%s", errOut))
+	c.Assert(errOut, Equals, "SPDX document created at "+output+"\n")
+	data, err := os.ReadFile(output)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Matches, `\{"spdxVersion":"SPDX-2.3",.*\n`)
+}
+
+func (s *S) TestGenerateInvalid(c *C) {
+	manifest := strings.ReplaceAll(testManifest, emptySHA256, "aaaa")
+	output := filepath.Join(c.MkDir(), "sbom.json")
+	status, _, errOut := runMain(manifest, "generate", "--manifest", "-", "-o", output)
+	c.Assert(status, Equals, exitError)
+	c.Assert(errOut, Matches, `(?s).*Validation: error: SPDXRef-Package-hello: SHA256 checksum "aaaa" has 4 digits instead of 64\n.*`+
+		`SPDX document created at .*\nerror: generated SPDX document is invalid: 2 validation error\(s\) found\n`)
+
+	// The invalid document is still written to be inspected.
+	status, out, errOut := runMain("", "validate", output)
+	c.Assert(status, Equals, exitError)
+	c.Assert(out, Matches, `error: SPDXRef-Package-hello: .*\nerror: SPDXRef-File-usr-bin-hello-86a8fab9: .*\n`)
+	c.Assert(errOut, Equals, "Found 2 error(s) and 0 warning(s) in the spdx document.\n")
+
+	status, out, _ = runMain("", "validate", "--json", output)
+	c.Assert(status, Equals, exitError)
+	c.Assert(out, Matches, `(?s)\{\n  "format": "spdx",\n  "issues": \[\n    \{\n      "severity": "error",.*`)
+}
+
+func (s *S) TestValidateErrors(c *C) {
+	status, _, errOut := runMain("{}", "validate", "-")
+	c.Assert(status, Equals, exitError)
+	c.Assert(errOut, Matches, "error: .*\n")
+
+	status, _, errOut = runMain("", "validate", filepath.Join(c.MkDir(), "missing.json"))
+	c.Assert(status, Equals, exitError)
+	c.Assert(errOut, Matches, "error: open .*missing.json: no such file or directory\n")
+}
+
+func (s *S) TestValidateManifest(c *C) {
+	status, _, errOut := runMain(testManifest, "validate-manifest", "--manifest", "-")
+	c.Assert(status, Equals, exitOK)
+	c.Assert(errOut, Equals, "The manifest is consistent.\n")
+
+	manifest := strings.Replace(testManifest, `{"kind":"slice","name":"hello_bins"}`, `{"kind":"slice","name":"hello_libs"}`, 1)
+	status, out, errOut := runMain(manifest, "validate-manifest", "--manifest", "-")
+	c.Assert(status, Equals, exitError)
+	c.Assert(out, Not(Equals), "")
+	c.Assert(errOut, Matches, `Found \d+ issue\(s\) in the manifest.\n`)
+}
+
+func (s *S) TestDiff(c *C) {
+	dir := c.MkDir()
+	generate := func(name, manifest string) string {
+		path := filepath.Join(dir, name)
+		status, _, errOut := runMain("", "generate", "-o", path, makeRootfs(c, manifest))
+		c.Assert(status, Equals, exitOK, Commentf("%s", errOut))
+		return path
+	}
+	old := generate("old.json", testManifest)
+	same := generate("same.json", testManifest)
+	changed := generate("new.json", strings.ReplaceAll(testManifest, "2.10-3build1", "2.10-3build2"))
+
+	status, out, _ := runMain("", "diff", old, same)
+	c.Assert(status, Equals, exitOK)
+	c.Assert(out, Equals, "")
+
+	status, out, _ = runMain("", "diff", old, changed)
+	c.Assert(status, Equals, exitError)
+	c.Assert(out, Matches, `(?s).*2.10-3build1.*2.10-3build2.*`)
+}
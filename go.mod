@@ -2,14 +2,17 @@ module github.com/canonical/ssbom
 
 go 1.22
 
+require (
+	github.com/canonical/chisel v1.1.1-0.20250127163729-ad87b0bb6f96
+	github.com/go-ini/ini v1.67.0
+	github.com/klauspost/compress v1.17.11
+	github.com/spdx/tools-golang v0.5.5
+	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c
+)
+
 require (
 	github.com/anchore/go-struct-converter v0.0.0-20221118182256-c68fdcfa2092 // indirect
-	github.com/canonical/chisel v1.1.1-0.20250127163729-ad87b0bb6f96 // indirect
-	github.com/go-ini/ini v1.67.0 // indirect
 	github.com/h2non/filetype v1.1.3 // indirect
-	github.com/klauspost/compress v1.17.11 // indirect
 	github.com/kr/pretty v0.2.1 // indirect
 	github.com/kr/text v0.1.0 // indirect
-	github.com/spdx/tools-golang v0.5.5 // indirect
-	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
@@ -0,0 +1,244 @@
+// Package apt reads the Packages and Sources indexes of an apt archive, to
+// describe the packages of a rootfs beyond what chisel records.
+package apt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/canonical/ssbom/internal/deb822"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Package is a binary package of a Packages index.
+type Package struct {
+	Name         string
+	Version      string
+	Architecture string
+	// Source and SourceVersion identify the source package the package
+	// was built from. They default to the package name and version.
+	Source        string
+	SourceVersion string
+	Maintainer    string
+	// OriginalMaintainer is the maintainer of the package in Debian, for
+	// packages modified by Ubuntu.
+	OriginalMaintainer string
+	Homepage           string
+	// Summary is the first line of the description.
+	Summary     string
+	Description string
+	// Filename is the path of the deb in the archive.
+	Filename string
+	// Depends lists the names of the packages the package depends on,
+	// without versions or alternatives.
+	Depends []string
+}
+
+// Source is a source package of a Sources index.
+type Source struct {
+	Name       string
+	Version    string
+	Maintainer string
+	Homepage   string
+	// Directory is the path of the source package in the archive.
+	Directory string
+}
+
+// Index holds the packages of Packages and Sources indexes.
+type Index struct {
+	packages map[string][]*Package
+	sources  map[string][]*Source
+}
+
+func NewIndex() *Index {
+	return &Index{
+		packages: make(map[string][]*Package),
+		sources:  make(map[string][]*Source),
+	}
+}
+
+// Package returns the binary package with the given name, version and
+// architecture, or nil if the index does not have it. An empty architecture
+// matches any.
+func (idx *Index) Package(name, version, arch string) *Package {
+	for _, p := range idx.packages[name] {
+		if p.Version == version && (arch == "" || p.Architecture == arch || p.Architecture == "all") {
+			return p
+		}
+	}
+	return nil
+}
+
+// Source returns the source package with the given name and version, or nil
+// if the index does not have it.
+func (idx *Index) Source(name, version string) *Source {
+	for _, s := range idx.sources[name] {
+		if s.Version == version {
+			return s
+		}
+	}
+	return nil
+}
+
+// Len returns the number of binary and source packages of the index.
+func (idx *Index) Len() int {
+	n := 0
+	for _, ps := range idx.packages {
+		n += len(ps)
+	}
+	for _, ss := range idx.sources {
+		n += len(ss)
+	}
+	return n
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Read adds the packages of a Packages or Sources index, which may be gzip
+// or zstd compressed, to the index. A dpkg status file, which has the same
+// format as a Packages index, may be read too.
+func (idx *Index) Read(r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("cannot read apt index: %w", err)
+	}
+	r = br
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("cannot read apt index: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case bytes.Equal(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("cannot read apt index: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	paragraphs, err := deb822.Parse(r)
+	if err != nil {
+		return fmt.Errorf("cannot read apt index: %w", err)
+	}
+	for _, p := range paragraphs {
+		if p["Package"] == "" || p["Version"] == "" {
+			continue
+		}
+		if _, ok := p["Binary"]; ok {
+			idx.addSource(p)
+		} else {
+			idx.addPackage(p)
+		}
+	}
+	return nil
+}
+
+func (idx *Index) addPackage(p deb822.Paragraph) {
+	pkg := &Package{
+		Name:               p["Package"],
+		Version:            p["Version"],
+		Architecture:       p["Architecture"],
+		Source:             p["Package"],
+		SourceVersion:      p["Version"],
+		Maintainer:         p["Maintainer"],
+		OriginalMaintainer: p["Original-Maintainer"],
+		Homepage:           p["Homepage"],
+		Filename:           p["Filename"],
+		Depends:            parseDepends(p["Pre-Depends"], p["Depends"]),
+	}
+	pkg.Summary, pkg.Description, _ = strings.Cut(p["Description"], "\n")
+	if source := p["Source"]; source != "" {
+		// "Source: name (version)" when the versions differ.
+		name, version, ok := strings.Cut(source, " ")
+		pkg.Source = name
+		if ok {
+			pkg.SourceVersion = strings.Trim(strings.TrimSpace(version), "()")
+		}
+	}
+	idx.packages[pkg.Name] = append(idx.packages[pkg.Name], pkg)
+}
+
+func (idx *Index) addSource(p deb822.Paragraph) {
+	src := &Source{
+		Name:       p["Package"],
+		Version:    p["Version"],
+		Maintainer: p["Maintainer"],
+		Homepage:   p["Homepage"],
+		Directory:  p["Directory"],
+	}
+	idx.sources[src.Name] = append(idx.sources[src.Name], src)
+}
+
+// parseDepends returns the package names of dependency fields, keeping the
+// first of alternatives and dropping versions and architecture qualifiers.
+func parseDepends(fields ...string) []string {
+	var names []string
+	for _, field := range fields {
+		for _, dep := range strings.Split(field, ",") {
+			dep, _, _ = strings.Cut(dep, "|")
+			name := strings.Fields(dep)
+			if len(name) == 0 {
+				continue
+			}
+			n, _, _ := strings.Cut(name[0], ":")
+			n, _, _ = strings.Cut(n, "(")
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// ReadMirror adds the Packages and Sources indexes of a mirror of an apt
+// archive, as found in its dists directory, to the index. When an index is
+// available in several compressions, only one of them is read.
+func (idx *Index) ReadMirror(fsys fs.FS) error {
+	read := make(map[string]bool)
+	err := fs.WalkDir(fsys, "dists", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		base := path.Base(name)
+		stem, _, _ := strings.Cut(base, ".")
+		if stem != "Packages" && stem != "Sources" {
+			return nil
+		}
+		if base != stem && base != stem+".gz" && base != stem+".zst" {
+			return nil
+		}
+		key := path.Join(path.Dir(name), stem)
+		if read[key] {
+			return nil
+		}
+		read[key] = true
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := idx.Read(f); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read apt mirror: %w", err)
+	}
+	return nil
+}
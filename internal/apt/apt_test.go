@@ -0,0 +1,105 @@
+package apt_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing/fstest"
+
+	"github.com/canonical/ssbom/internal/apt"
+	. "gopkg.in/check.v1"
+)
+
+const samplePackages = `Package: hello
+Architecture: amd64
+Version: 2.10-3build1
+Priority: optional
+Section: devel
+Origin: Ubuntu
+Maintainer: Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>
+Original-Maintainer: Santiago Vila <sanvila@debian.org>
+Installed-Size: 112
+Pre-Depends: libc6 (>= 2.34)
+Depends: libfoo1:any (>= 1.0) | libbar1, libbaz1
+Filename: pool/main/h/hello/hello_2.10-3build1_amd64.deb
+Description: example package based on GNU hello
+ The GNU hello program produces a familiar, friendly greeting.
+
+Package: hello-doc
+Architecture: all
+Version: 2.10-3build1
+Source: hello (2.10-3)
+Maintainer: Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>
+Filename: pool/main/h/hello/hello-doc_2.10-3build1_all.deb
+Description: documentation of hello
+`
+
+const sampleSources = `Package: hello
+Binary: hello, hello-doc
+Version: 2.10-3
+Maintainer: Santiago Vila <sanvila@debian.org>
+Homepage: https://www.gnu.org/software/hello/
+Directory: pool/main/h/hello
+`
+
+func (s *S) TestRead(c *C) {
+	idx := apt.NewIndex()
+	c.Assert(idx.Read(strings.NewReader(samplePackages)), IsNil)
+	c.Assert(idx.Read(strings.NewReader(sampleSources)), IsNil)
+	c.Assert(idx.Len(), Equals, 3)
+
+	c.Assert(idx.Package("hello", "2.10-3build1", "amd64"), DeepEquals, &apt.Package{
+		Name:               "hello",
+		Version:            "2.10-3build1",
+		Architecture:       "amd64",
+		Source:             "hello",
+		SourceVersion:      "2.10-3build1",
+		Maintainer:         "Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>",
+		OriginalMaintainer: "Santiago Vila <sanvila@debian.org>",
+		Summary:            "example package based on GNU hello",
+		Description:        "The GNU hello program produces a familiar, friendly greeting.",
+		Filename:           "pool/main/h/hello/hello_2.10-3build1_amd64.deb",
+		Depends:            []string{"libc6", "libfoo1", "libbaz1"},
+	})
+	c.Assert(idx.Package("hello", "2.10-3build1", "arm64"), IsNil)
+	c.Assert(idx.Package("hello", "2.10-3", "amd64"), IsNil)
+
+	doc := idx.Package("hello-doc", "2.10-3build1", "arm64")
+	c.Assert(doc, NotNil)
+	c.Assert(doc.Source, Equals, "hello")
+	c.Assert(doc.SourceVersion, Equals, "2.10-3")
+
+	c.Assert(idx.Source("hello", "2.10-3"), DeepEquals, &apt.Source{
+		Name:       "hello",
+		Version:    "2.10-3",
+		Maintainer: "Santiago Vila <sanvila@debian.org>",
+		Homepage:   "https://www.gnu.org/software/hello/",
+		Directory:  "pool/main/h/hello",
+	})
+}
+
+func (s *S) TestReadMirror(c *C) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(samplePackages))
+	w.Close()
+
+	mirror := fstest.MapFS{
+		"dists/noble/main/binary-amd64/Packages.gz": &fstest.MapFile{Data: gz.Bytes()},
+		// The same index, uncompressed, is read only once.
+		"dists/noble/main/binary-amd64/Packages":     &fstest.MapFile{Data: []byte(samplePackages)},
+		"dists/noble/main/binary-amd64/Release":      &fstest.MapFile{Data: []byte("Archive: noble\n")},
+		"dists/noble/main/source/Sources":            &fstest.MapFile{Data: []byte(sampleSources)},
+		"dists/noble/main/source/Sources.diff/Index": &fstest.MapFile{Data: []byte("garbage")},
+		"pool/main/h/hello/Packages":                 &fstest.MapFile{Data: []byte("garbage")},
+	}
+	idx := apt.NewIndex()
+	c.Assert(idx.ReadMirror(mirror), IsNil)
+	c.Assert(idx.Len(), Equals, 3)
+	c.Assert(idx.Package("hello", "2.10-3build1", "amd64"), NotNil)
+	c.Assert(idx.Source("hello", "2.10-3"), NotNil)
+
+	mirror["dists/noble/main/binary-arm64/Packages"] = &fstest.MapFile{Data: []byte("Package: hello\nnot a field\n")}
+	err := apt.NewIndex().ReadMirror(mirror)
+	c.Assert(err, ErrorMatches, `cannot read apt mirror: dists/noble/main/binary-arm64/Packages: cannot read apt index: .*`)
+}
@@ -0,0 +1,113 @@
+package builder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// AnnotateSPDXDocument adds an annotation about the whole document.
+func AnnotateSPDXDocument(doc *spdx.Document, comment string) {
+	annotateSPDXElement(doc, string(doc.SPDXIdentifier), comment)
+}
+
+// annotateSPDXElement adds an annotation about an element of the document.
+func annotateSPDXElement(doc *spdx.Document, id string, comment string) {
+	doc.Annotations = append(doc.Annotations, &spdx.Annotation{
+		Annotator: common.Annotator{
+			Annotator:     spdxAnnotator(doc),
+			AnnotatorType: "Tool",
+		},
+		AnnotationDate:           spdxAnnotationDate(doc),
+		AnnotationType:           "OTHER",
+		AnnotationSPDXIdentifier: common.MakeDocElementID("", id),
+		AnnotationComment:        comment,
+	})
+}
+
+// spdxAnnotator returns the tool that created the document, as in
+// "ssbom-v1.2.3", or ToolName if it is not known yet.
+func spdxAnnotator(doc *spdx.Document) string {
+	if doc.CreationInfo != nil {
+		for _, c := range doc.CreationInfo.Creators {
+			if c.CreatorType == "Tool" {
+				return c.Creator
+			}
+		}
+	}
+	return ToolName
+}
+
+// spdxAnnotationDate returns the creation time of the document, if set, or
+// the current time.
+func spdxAnnotationDate(doc *spdx.Document) string {
+	if doc.CreationInfo != nil && doc.CreationInfo.Created != "" {
+		return doc.CreationInfo.Created
+	}
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// AnnotateSPDX3Document adds an annotation about the SpdxDocument element.
+func AnnotateSPDX3Document(doc *spdx3.Document, statement string) {
+	var spdxDoc *spdx3.SpdxDocument
+	annotations := 0
+	for _, element := range doc.Graph {
+		switch e := element.(type) {
+		case *spdx3.SpdxDocument:
+			spdxDoc = e
+		case *spdx3.Annotation:
+			annotations++
+		}
+	}
+	if spdxDoc == nil {
+		return
+	}
+	annotation := &spdx3.Annotation{
+		Element: spdx3.Element{
+			Type:         spdx3.TypeAnnotation,
+			SpdxID:       SPDX3Id(spdx3Namespace(doc), fmt.Sprintf("Annotation-%d", annotations+1)),
+			CreationInfo: spdx3CreationInfoId,
+		},
+		AnnotationType: spdx3.AnnotationOther,
+		Subject:        spdxDoc.SpdxID,
+		Statement:      statement,
+	}
+	spdxDoc.Elements = append(spdxDoc.Elements, annotation.SpdxID)
+	doc.Graph = append(doc.Graph, annotation)
+}
+
+// AnnotateCycloneDXDocument adds an annotation about the OS component and
+// every top-level component of the BOM.
+func AnnotateCycloneDXDocument(bom *cyclonedx.BOM, text string) {
+	var subjects []string
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		subjects = append(subjects, bom.Metadata.Component.BOMRef)
+	}
+	for _, comp := range bom.Components {
+		subjects = append(subjects, comp.BOMRef)
+	}
+	if len(subjects) == 0 {
+		// Annotations must have a subject.
+		return
+	}
+	tool := ChiselSbomCycloneDXTool
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	if bom.Metadata != nil {
+		if bom.Metadata.Tools != nil && len(bom.Metadata.Tools.Components) > 0 {
+			tool = bom.Metadata.Tools.Components[0]
+		}
+		if bom.Metadata.Timestamp != "" {
+			timestamp = bom.Metadata.Timestamp
+		}
+	}
+	bom.Annotations = append(bom.Annotations, &cyclonedx.Annotation{
+		Subjects:  subjects,
+		Annotator: &cyclonedx.Annotator{Component: tool},
+		Timestamp: timestamp,
+		Text:      text,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/canonical/ssbom/internal/osrelease"
 	"github.com/spdx/tools-golang/spdx"
 	"github.com/spdx/tools-golang/spdx/v2/common"
 )
@@ -16,7 +17,32 @@ type PackageInfo struct {
 	Version string
 	SHA256  string
 	Arch    string
-	Distro  string
+	// MultiArch tells that packages of other architectures have the same
+	// name. The architecture is then part of the identifier of the package.
+	MultiArch bool
+	// OS is the distribution of the rootfs, if known.
+	OS *osrelease.OSRelease
+	// License is the SPDX license expression declared by the package, if
+	// known.
+	License string
+	// ProPocket is the Ubuntu Pro pocket the package comes from, such as
+	// "esm-infra", if any, and RepositoryURL the URL of its archive.
+	ProPocket     string
+	RepositoryURL string
+	// CPEs lists the candidate CPE 2.3 names of the package, most likely
+	// first. CPE23Locator is used if it is empty.
+	CPEs []string
+
+	// The following fields come from the apt archive, if known.
+	SourceName         string
+	SourceVersion      string
+	Maintainer         string
+	OriginalMaintainer string
+	Homepage           string
+	Summary            string
+	DownloadLocation   string
+	// Depends lists the names of the packages the package depends on.
+	Depends []string
 }
 
 type PathInfo struct {
@@ -27,20 +53,34 @@ type PathInfo struct {
 	FinalSHA256 string
 	Link        string
 	Inode       uint64
+	// License and Copyright are the SPDX license expression and the
+	// copyright text of the file, if known.
+	License   string
+	Copyright string
+}
+
+// LicenseRefInfo describes a license that is not in the SPDX license list.
+type LicenseRefInfo struct {
+	ID   string
+	Name string
+	Text string
 }
 
 type SliceInfo struct {
 	Name string
+	// Arch is the architecture of the package of the slice, when packages
+	// of several architectures have its name.
+	Arch string
 }
 
 var ChiselSbomDocCreator = []common.Creator{
 	{
-		Creator:     "Chisel SBOM Exporter ()",
+		Creator:     ToolName,
 		CreatorType: "Tool",
 	},
 }
 
-func BuildSPDXDocument(distro string, sliceInfos *[]SliceInfo, packageInfos *[]PackageInfo, pathInfos *[]PathInfo) (*spdx.Document, error) {
+func BuildSPDXDocument(osRelease *osrelease.OSRelease, sliceInfos *[]SliceInfo, packageInfos *[]PackageInfo, pathInfos *[]PathInfo) (*spdx.Document, error) {
 	doc := &spdx.Document{
 		SPDXVersion:    spdx.Version,
 		DataLicense:    spdx.DataLicense,
@@ -51,20 +91,23 @@ func BuildSPDXDocument(distro string, sliceInfos *[]SliceInfo, packageInfos *[]P
 		},
 	}
 
-	if distro != "" {
+	if hasOSPackage(osRelease) {
 		osPackage := &spdx.Package{
-			PackageName:             "ubuntu",
-			PackageSPDXIdentifier:   common.ElementID(OSId(distro)),
+			PackageName:             osRelease.ID,
+			PackageSPDXIdentifier:   common.ElementID(OSId(osRelease)),
 			PackageDownloadLocation: "NOASSERTION",
+			PackageHomePage:         osRelease.HomeURL,
+			PackageSummary:          osRelease.PrettyName,
 			FilesAnalyzed:           false,
-			PackageComment:          "This package is the distribution of the rootfs.",
-			PackageVersion:          distro,
+			PackageComment:          osComment("package", *packageInfos),
+			PackageSupplier:         DistroSupplier(osRelease),
+			PackageVersion:          osRelease.Version(),
 			PrimaryPackagePurpose:   "OPERATING_SYSTEM",
 		}
 		doc.Packages = append(doc.Packages, osPackage)
 		doc.Relationships = append(doc.Relationships, &spdx.Relationship{
 			RefA:         common.MakeDocElementID("", "DOCUMENT"),
-			RefB:         common.MakeDocElementID("", OSId(distro)),
+			RefB:         common.MakeDocElementID("", OSId(osRelease)),
 			Relationship: "DESCRIBES",
 		})
 	}
@@ -77,6 +120,26 @@ func BuildSPDXDocument(distro string, sliceInfos *[]SliceInfo, packageInfos *[]P
 		}
 		doc.Packages = append(doc.Packages, pkg)
 		doc.Relationships = append(doc.Relationships, rln)
+		if p.ProPocket != "" {
+			annotateSPDXElement(doc, p.SPDXId(), p.proComment())
+		}
+	}
+	for _, dep := range packageDependencies(*packageInfos) {
+		doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+			RefA:         common.MakeDocElementID("", dep[0].SPDXId()),
+			RefB:         common.MakeDocElementID("", dep[1].SPDXId()),
+			Relationship: "DEPENDS_ON",
+		})
+	}
+	for _, src := range sourcePackages(*packageInfos) {
+		doc.Packages = append(doc.Packages, src.buildPackageSection())
+		for _, p := range src.Binaries {
+			doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+				RefA:         common.MakeDocElementID("", p.SPDXId()),
+				RefB:         common.MakeDocElementID("", src.SPDXId()),
+				Relationship: "GENERATED_FROM",
+			})
+		}
 	}
 
 	// Add slices
@@ -102,20 +165,33 @@ func BuildSPDXDocument(distro string, sliceInfos *[]SliceInfo, packageInfos *[]P
 	return doc, nil
 }
 
-func OSId(distro string) string {
-	return fmt.Sprintf("OperatingSystem-ubuntu-%s", distro)
-}
-
+// SPDXId returns the identifier of the package. The architecture of a
+// multi-arch package is joined to its name by ":", as with dpkg, so that
+// the identifier cannot be the one of another package, such as "libc6-amd64".
 func (p *PackageInfo) SPDXId() string {
-	return fmt.Sprintf("Package-%s", p.Name)
+	if p.MultiArch {
+		return spdxId("Package", p.Name+":"+p.Arch)
+	}
+	return spdxId("Package", p.Name)
 }
 
 func (s *SliceInfo) SPDXId() string {
-	return fmt.Sprintf("Slice-%s", s.Name)
+	return spdxId("Slice", s.Name)
 }
 
 func (p *PathInfo) SPDXId() string {
-	return fmt.Sprintf("File-%s", p.Path)
+	return spdxId("File", p.Path)
+}
+
+// packageName returns the name of the package the slice belongs to.
+func (s *SliceInfo) packageName() string {
+	name, _, _ := strings.Cut(s.Name, "_")
+	return name
+}
+
+// packageInfo returns the identity of the package the slice belongs to.
+func (s *SliceInfo) packageInfo() *PackageInfo {
+	return &PackageInfo{Name: s.packageName(), Arch: s.Arch, MultiArch: s.Arch != ""}
 }
 
 var UbuntuPackageSupplier = common.Supplier{
@@ -124,39 +200,209 @@ var UbuntuPackageSupplier = common.Supplier{
 }
 
 func (p *PackageInfo) CPE23Locator() string {
+	if len(p.CPEs) > 0 {
+		return p.CPEs[0]
+	}
 	return fmt.Sprintf("cpe:2.3:a:%s:%s:%s:*:*:*:*:*:*:*", p.Name, p.Name, p.Version)
 }
 
+// cpe23Locators returns every candidate CPE 2.3 name of the package.
+func (p *PackageInfo) cpe23Locators() []string {
+	if len(p.CPEs) > 0 {
+		return p.CPEs
+	}
+	return []string{p.CPE23Locator()}
+}
+
 func (p *PackageInfo) PurlLocator() string {
-	locator := fmt.Sprintf("pkg:deb/ubuntu/%s@%s?arch=%s", p.Name, p.Version, p.Arch)
-	if p.Distro != "" {
-		locator += fmt.Sprintf("&distro=ubuntu-%s", p.Distro)
+	locator := fmt.Sprintf("pkg:deb/%s/%s@%s?arch=%s", purlNamespace(p.OS), p.Name, p.Version, p.Arch)
+	locator += purlDistro(p.OS)
+	locator += p.repositoryURLQualifier()
+	if upstream := p.upstream(); upstream != "" {
+		locator += "&upstream=" + upstream
 	}
 	return locator
 }
 
-func (p *PackageInfo) buildPackageSection() (*spdx.Package, *spdx.Relationship, error) {
-	pkg := &spdx.Package{
-		PackageName:             p.Name,
-		PackageSPDXIdentifier:   common.ElementID(p.SPDXId()),
-		PackageVersion:          p.Version,
-		PackageChecksums:        []common.Checksum{{Algorithm: common.SHA256, Value: p.SHA256}},
+// upstream returns the value of the upstream purl qualifier: the name of the
+// source package, followed by its version if it differs from the version of
+// the package, as in "glibc%402.39-0ubuntu8".
+func (p *PackageInfo) upstream() string {
+	if p.SourceName == "" {
+		return ""
+	}
+	if p.SourceVersion == "" || p.SourceVersion == p.Version {
+		return p.SourceName
+	}
+	return p.SourceName + "%40" + p.SourceVersion
+}
+
+// sourcePackage is a source package the packages of the rootfs were built
+// from.
+type sourcePackage struct {
+	Name     string
+	Version  string
+	OS       *osrelease.OSRelease
+	Binaries []*PackageInfo
+}
+
+// SPDXId returns the identifier of the source package. Its name and version
+// are joined by "_", as in the names of Debian source files, so that they
+// cannot be told apart from the "-" of other names and versions.
+func (s *sourcePackage) SPDXId() string {
+	return spdxId("SourcePackage", s.Name+"_"+s.Version)
+}
+
+func (s *sourcePackage) PurlLocator() string {
+	return fmt.Sprintf("pkg:deb/%s/%s@%s?arch=source", purlNamespace(s.OS), s.Name, s.Version) + purlDistro(s.OS)
+}
+
+// sourcePackages returns the source packages of the packages whose source is
+// known, in order of first appearance.
+func sourcePackages(packageInfos []PackageInfo) []*sourcePackage {
+	var sources []*sourcePackage
+	byId := make(map[string]*sourcePackage)
+	for i := range packageInfos {
+		p := &packageInfos[i]
+		if p.SourceName == "" {
+			continue
+		}
+		version := p.SourceVersion
+		if version == "" {
+			version = p.Version
+		}
+		src := &sourcePackage{Name: p.SourceName, Version: version, OS: p.OS}
+		if existing, ok := byId[src.SPDXId()]; ok {
+			src = existing
+		} else {
+			byId[src.SPDXId()] = src
+			sources = append(sources, src)
+		}
+		src.Binaries = append(src.Binaries, p)
+	}
+	return sources
+}
+
+func (s *sourcePackage) buildPackageSection() *spdx.Package {
+	return &spdx.Package{
+		PackageName:             s.Name,
+		PackageSPDXIdentifier:   common.ElementID(s.SPDXId()),
+		PackageVersion:          s.Version,
 		PackageDownloadLocation: "NOASSERTION",
 		FilesAnalyzed:           false,
-		PackageComment:          "This package includes one or more slice(s); see Relationship information.",
-		PackageSupplier:         &UbuntuPackageSupplier,
+		PackageComment:          "This package is the source package of one or more packages; see Relationship information.",
+		PackageSupplier:         DistroSupplier(s.OS),
 		PackageExternalReferences: []*spdx.PackageExternalReference{
-			{
-				Category: "SECURITY",
-				RefType:  "cpe23Type",
-				Locator:  p.CPE23Locator(),
-			},
 			{
 				Category: "PACKAGE_MANAGER",
 				RefType:  "purl",
-				Locator:  p.PurlLocator(),
+				Locator:  s.PurlLocator(),
 			},
 		},
+		PrimaryPackagePurpose: "SOURCE",
+	}
+}
+
+// packageDependencies returns the pairs of packages where the first depends
+// on the second. Dependencies on packages that are not in the rootfs are
+// left out.
+func packageDependencies(packageInfos []PackageInfo) [][2]*PackageInfo {
+	byName := make(map[string][]*PackageInfo)
+	for i := range packageInfos {
+		byName[packageInfos[i].Name] = append(byName[packageInfos[i].Name], &packageInfos[i])
+	}
+	var deps [][2]*PackageInfo
+	for i := range packageInfos {
+		p := &packageInfos[i]
+		seen := make(map[string]bool)
+		for _, name := range p.Depends {
+			dep := dependency(p, byName[name])
+			if dep == nil || seen[name] || dep == p {
+				continue
+			}
+			seen[name] = true
+			deps = append(deps, [2]*PackageInfo{p, dep})
+		}
+	}
+	return deps
+}
+
+// dependency returns the package, among those with the name of a
+// dependency, that satisfies it: the one of the same architecture as the
+// dependent package, or of architecture "all", or otherwise the first.
+func dependency(p *PackageInfo, candidates []*PackageInfo) *PackageInfo {
+	if len(candidates) == 0 {
+		return nil
+	}
+	for _, arch := range []string{p.Arch, "all"} {
+		for _, dep := range candidates {
+			if dep.Arch == arch {
+				return dep
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// SourceInfo returns the SPDX source information of the package.
+func (p *PackageInfo) SourceInfo() string {
+	if p.SourceName == "" {
+		return ""
+	}
+	if p.SourceVersion == "" {
+		return "built package from: " + p.SourceName
+	}
+	return fmt.Sprintf("built package from: %s %s", p.SourceName, p.SourceVersion)
+}
+
+func (p *PackageInfo) supplier() *common.Supplier {
+	if p.ProPocket != "" {
+		return &UbuntuProPackageSupplier
+	}
+	if p.Maintainer == "" {
+		return DistroSupplier(p.OS)
+	}
+	return &common.Supplier{SupplierType: "Person", Supplier: p.Maintainer}
+}
+
+func (p *PackageInfo) buildPackageSection() (*spdx.Package, *spdx.Relationship, error) {
+	downloadLocation := p.DownloadLocation
+	if downloadLocation == "" {
+		downloadLocation = "NOASSERTION"
+	}
+	pkg := &spdx.Package{
+		PackageName:             p.Name,
+		PackageSPDXIdentifier:   common.ElementID(p.SPDXId()),
+		PackageVersion:          p.Version,
+		PackageDownloadLocation: downloadLocation,
+		PackageSourceInfo:       p.SourceInfo(),
+		PackageHomePage:         p.Homepage,
+		PackageSummary:          p.Summary,
+		FilesAnalyzed:           false,
+		PackageComment:          "This package includes one or more slice(s); see Relationship information.",
+		PackageSupplier:         p.supplier(),
+	}
+	if p.SHA256 != "" {
+		pkg.PackageChecksums = []common.Checksum{{Algorithm: common.SHA256, Value: p.SHA256}}
+	}
+	for _, cpe := range p.cpe23Locators() {
+		pkg.PackageExternalReferences = append(pkg.PackageExternalReferences, &spdx.PackageExternalReference{
+			Category: "SECURITY",
+			RefType:  "cpe23Type",
+			Locator:  cpe,
+		})
+	}
+	pkg.PackageExternalReferences = append(pkg.PackageExternalReferences, &spdx.PackageExternalReference{
+		Category: "PACKAGE_MANAGER",
+		RefType:  "purl",
+		Locator:  p.PurlLocator(),
+	})
+	if p.OriginalMaintainer != "" {
+		pkg.PackageOriginator = &common.Originator{OriginatorType: "Person", Originator: p.OriginalMaintainer}
+	}
+	if p.License != "" {
+		pkg.PackageLicenseDeclared = p.License
+		pkg.PackageLicenseConcluded = p.License
 	}
 
 	rln := &spdx.Relationship{
@@ -169,7 +415,7 @@ func (p *PackageInfo) buildPackageSection() (*spdx.Package, *spdx.Relationship,
 }
 
 func (s *SliceInfo) buildSliceSection() (*spdx.Package, *spdx.Relationship, error) {
-	packageName := strings.Split(s.Name, "_")[0]
+	packageName := s.packageName()
 
 	pkg := &spdx.Package{
 		PackageName:             s.Name,
@@ -179,11 +425,8 @@ func (s *SliceInfo) buildSliceSection() (*spdx.Package, *spdx.Relationship, erro
 		PackageComment:          fmt.Sprintf("This slice is a sub-package of the package %s; see Relationship information.", packageName),
 	}
 
-	packageInfo := PackageInfo{
-		Name: packageName,
-	}
 	rln := &spdx.Relationship{
-		RefA:         common.MakeDocElementID("", packageInfo.SPDXId()),
+		RefA:         common.MakeDocElementID("", s.packageInfo().SPDXId()),
 		RefB:         common.MakeDocElementID("", s.SPDXId()),
 		Relationship: "CONTAINS",
 	}
@@ -211,39 +454,24 @@ func (f *PathInfo) buildPathSection() (*spdx.File, []*spdx.Relationship, error)
 	if f.FinalSHA256 != "" {
 		sha256 = f.FinalSHA256
 	}
-	var fileType int
 	file := &spdx.File{
 		FileName:           f.Path,
 		FileSPDXIdentifier: common.ElementID(f.SPDXId()),
-		Checksums:          []common.Checksum{{Algorithm: common.SHA256, Value: sha256}},
 		FileCopyrightText:  "NOASSERTION",
 	}
+	if sha256 != "" {
+		file.Checksums = []common.Checksum{{Algorithm: common.SHA256, Value: sha256}}
+	}
+	if f.License != "" {
+		file.LicenseConcluded = f.License
+	}
+	if f.Copyright != "" {
+		file.FileCopyrightText = f.Copyright
+	}
 
-	// Determine the file type
-	// File type  |  Inode  |  Link  |  FinalSHA256  |       SHA256
-	// ------------------------------------------------------------------
-	// Regular    |    0    |   ""   |       ""      |       != ""
-	// Modified   |    0    |   ""   |      != ""    |     (omitted)
-	// Link       |    0    |  != "" |       ""      |       == ""
-	// Hard link  |   != 0  |   ""   |       ""      |     (omitted)
-	// ------------------------------------------------------------------
-	// Note: The rest of the cases are invalid
-	if f.FinalSHA256 == "" {
-		if f.Inode > 0 && f.Link != "" {
-			return nil, nil, fmt.Errorf("cannot build file section: invalid file type: file %s simultaneously has inode %d and link %s", f.Path, f.Inode, f.Link)
-		}
-		if f.Inode > 0 && f.Link == "" {
-			fileType = FileHlk
-		} else if f.Inode == 0 && f.Link != "" {
-			fileType = FileLnk
-		} else if f.Inode == 0 && f.Link == "" {
-			fileType = FileReg
-		}
-	} else {
-		if f.Inode > 0 || f.Link != "" {
-			return nil, nil, fmt.Errorf("cannot build file section: invalid link: link %s has a final sha256", f.Path)
-		}
-		fileType = FileMod
+	fileType, err := f.fileType()
+	if err != nil {
+		return nil, nil, err
 	}
 
 	slices := strings.Join(f.Slices, ", ")
@@ -268,6 +496,34 @@ func (f *PathInfo) buildPathSection() (*spdx.File, []*spdx.Relationship, error)
 	return file, rln, nil
 }
 
+// fileType determines the type of the file from the manifest fields.
+func (f *PathInfo) fileType() (int, error) {
+	// File type  |  Inode  |  Link  |  FinalSHA256  |       SHA256
+	// ------------------------------------------------------------------
+	// Regular    |    0    |   ""   |       ""      |       != ""
+	// Modified   |    0    |   ""   |      != ""    |     (omitted)
+	// Link       |    0    |  != "" |       ""      |       == ""
+	// Hard link  |   != 0  |   ""   |       ""      |     (omitted)
+	// ------------------------------------------------------------------
+	// Note: The rest of the cases are invalid
+	if f.FinalSHA256 != "" {
+		if f.Inode > 0 || f.Link != "" {
+			return 0, fmt.Errorf("cannot build file section: invalid link: link %s has a final sha256", f.Path)
+		}
+		return FileMod, nil
+	}
+	if f.Inode > 0 && f.Link != "" {
+		return 0, fmt.Errorf("cannot build file section: invalid file type: file %s simultaneously has inode %d and link %s", f.Path, f.Inode, f.Link)
+	}
+	if f.Inode > 0 {
+		return FileHlk, nil
+	}
+	if f.Link != "" {
+		return FileLnk, nil
+	}
+	return FileReg, nil
+}
+
 var fileRlnComments = map[string]string{
 	"CONTAINS":      "File %s is included in the slice %s.",
 	"FILE_MODIFIED": "File %s is mutated by the slice %s.",
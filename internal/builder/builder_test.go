@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/internal/osrelease"
 	"github.com/canonical/ssbom/internal/testutil"
 	"github.com/spdx/tools-golang/spdx"
 	"github.com/spdx/tools-golang/spdx/v2/common"
@@ -139,7 +140,7 @@ var builerTests = []BuilderTest{
 				&testutil.SPDXDocSampleSingleFileModified,
 				{
 					FileName:           "/test2",
-					FileSPDXIdentifier: spdx.ElementID("File-/test2"),
+					FileSPDXIdentifier: spdx.ElementID("File-test2-bdc482ad"),
 					Checksums: []spdx.Checksum{
 						{
 							Algorithm: spdx.SHA256,
@@ -155,8 +156,8 @@ var builerTests = []BuilderTest{
 				&testutil.SPDXRelSampleSinglePkgContainsSlice,
 				&testutil.SPDXRelSampleSingleFileModifiedBySlice,
 				{
-					RefA:                common.MakeDocElementID("", "Slice-test_slice"),
-					RefB:                common.MakeDocElementID("", "File-/test2"),
+					RefA:                common.MakeDocElementID("", "Slice-test-slice-1196cbd5"),
+					RefB:                common.MakeDocElementID("", "File-test2-bdc482ad"),
 					Relationship:        "CONTAINS",
 					RelationshipComment: "File /test2 is included in the slice test_slice.",
 				},
@@ -191,7 +192,7 @@ var builerTests = []BuilderTest{
 				&testutil.SPDXDocSampleSingleSlice,
 				{
 					PackageName:             "test_slice2",
-					PackageSPDXIdentifier:   spdx.ElementID("Slice-test_slice2"),
+					PackageSPDXIdentifier:   spdx.ElementID("Slice-test-slice2-f2209f73"),
 					FilesAnalyzed:           false,
 					PackageDownloadLocation: "NOASSERTION",
 					PackageComment:          "This slice is a sub-package of the package test; see Relationship information.",
@@ -200,7 +201,7 @@ var builerTests = []BuilderTest{
 			Files: []*spdx.File{
 				{
 					FileName:           "/test",
-					FileSPDXIdentifier: spdx.ElementID("File-/test"),
+					FileSPDXIdentifier: spdx.ElementID("File-test-b306d9ff"),
 					Checksums: []spdx.Checksum{
 						{
 							Algorithm: spdx.SHA256,
@@ -216,13 +217,13 @@ var builerTests = []BuilderTest{
 				&testutil.SPDXRelSampleSinglePkgContainsSlice,
 				{
 					RefA:         common.MakeDocElementID("", "Package-test"),
-					RefB:         common.MakeDocElementID("", "Slice-test_slice2"),
+					RefB:         common.MakeDocElementID("", "Slice-test-slice2-f2209f73"),
 					Relationship: "CONTAINS",
 				},
 				&testutil.SPDXRelSampleSingleSliceContainsFile,
 				{
-					RefA:                common.MakeDocElementID("", "Slice-test_slice2"),
-					RefB:                common.MakeDocElementID("", "File-/test"),
+					RefA:                common.MakeDocElementID("", "Slice-test-slice2-f2209f73"),
+					RefB:                common.MakeDocElementID("", "File-test-b306d9ff"),
 					Relationship:        "CONTAINS",
 					RelationshipComment: "File /test is included in the slice test_slice2.",
 				},
@@ -322,14 +323,14 @@ var builerTests = []BuilderTest{
 	},
 }
 
-func runTestBuilder(c *C, test []BuilderTest, distro string) {
+func runTestBuilder(c *C, test []BuilderTest, osRelease *osrelease.OSRelease) {
 	for _, test := range test {
-		if distro == "" {
+		if osRelease == nil {
 			c.Logf("Running test without distro: %s", test.summary)
 		} else {
-			c.Logf("Running test with distro: %s: %s", distro, test.summary)
+			c.Logf("Running test with distro: %s: %s", osRelease.Distro(), test.summary)
 		}
-		doc, err := builder.BuildSPDXDocument(distro, &test.sliceInfos, &test.packageInfos, &test.pathInfos)
+		doc, err := builder.BuildSPDXDocument(osRelease, &test.sliceInfos, &test.packageInfos, &test.pathInfos)
 		if test.error != "" {
 			c.Assert(err, ErrorMatches, test.error)
 			continue
@@ -340,7 +341,7 @@ func runTestBuilder(c *C, test []BuilderTest, distro string) {
 }
 
 func (s *S) TestBuilder(c *C) {
-	runTestBuilder(c, builerTests, "")
+	runTestBuilder(c, builerTests, nil)
 
 	// Testing builder with non-empty distro
 	// The package external reference is not appended with "&distro=ubuntu-24.04" because
@@ -355,5 +356,77 @@ func (s *S) TestBuilder(c *C) {
 			}, builerTests[i].spdxDocument.Relationships...)
 		}
 	}
-	runTestBuilder(c, builerTests, "24.04")
+	runTestBuilder(c, builerTests, testutil.SampleOSReleaseNoble)
+}
+
+func (s *S) TestBuilderDependencies(c *C) {
+	packageInfos := []builder.PackageInfo{
+		{Name: "hello", Version: "1.0", Arch: "amd64", Depends: []string{"libc6", "missing", "libc6"}},
+		{Name: "libc6", Version: "2.39", Arch: "amd64", Depends: []string{"libc6"}},
+	}
+	doc, err := builder.BuildSPDXDocument(testutil.SampleOSReleaseNoble, &[]builder.SliceInfo{}, &packageInfos, &[]builder.PathInfo{})
+	c.Assert(err, IsNil)
+	var deps []string
+	for _, rln := range doc.Relationships {
+		if rln.Relationship == "DEPENDS_ON" {
+			deps = append(deps, string(rln.RefA.ElementRefID)+" "+string(rln.RefB.ElementRefID))
+		}
+	}
+	c.Assert(deps, DeepEquals, []string{"Package-hello Package-libc6"})
+}
+
+func (s *S) TestSourcePackageIds(c *C) {
+	packageInfos := []builder.PackageInfo{
+		{Name: "foo", Version: "1-2", Arch: "amd64", SourceName: "foo", SourceVersion: "1-2"},
+		{Name: "foo-1", Version: "2", Arch: "amd64", SourceName: "foo-1", SourceVersion: "2"},
+	}
+	doc, err := builder.BuildSPDXDocument(nil, &[]builder.SliceInfo{}, &packageInfos, &[]builder.PathInfo{})
+	c.Assert(err, IsNil)
+	var ids []string
+	for _, p := range doc.Packages {
+		if p.PrimaryPackagePurpose == "SOURCE" {
+			ids = append(ids, string(p.PackageSPDXIdentifier))
+		}
+	}
+	c.Assert(ids, HasLen, 2)
+	c.Assert(ids[0], Not(Equals), ids[1])
+	for _, id := range ids {
+		c.Assert(id, Matches, `SourcePackage-foo-1-2-[0-9a-f]{8}`)
+	}
+}
+
+func (s *S) TestSourceInfo(c *C) {
+	for _, test := range []struct {
+		info     builder.PackageInfo
+		expected string
+	}{
+		{builder.PackageInfo{Name: "foo"}, ""},
+		{builder.PackageInfo{Name: "foo", SourceName: "foo-src"}, "built package from: foo-src"},
+		{builder.PackageInfo{Name: "foo", SourceName: "foo-src", SourceVersion: "1-2"}, "built package from: foo-src 1-2"},
+	} {
+		c.Assert(test.info.SourceInfo(), Equals, test.expected)
+	}
+}
+
+func (s *S) TestSPDXIds(c *C) {
+	for _, test := range []struct{ obtained, expected string }{
+		{(&builder.PackageInfo{Name: "base-files"}).SPDXId(), "Package-base-files"},
+		{(&builder.PackageInfo{Name: "libstdc++6"}).SPDXId(), "Package-libstdc-6-ee0af3d5"},
+		{(&builder.SliceInfo{Name: "base-files_base"}).SPDXId(), "Slice-base-files-base-58eca119"},
+		{(&builder.PathInfo{Path: "/usr/bin/hello"}).SPDXId(), "File-usr-bin-hello-86a8fab9"},
+		{(&builder.PathInfo{Path: "/usr/bin-hello"}).SPDXId(), "File-usr-bin-hello-c1b273c5"},
+		{(&builder.PathInfo{Path: "/"}).SPDXId(), "File-8a5edab2"},
+		{(&builder.GoModuleInfo{Path: "golang.org/x/net", Version: "v0.1.0"}).SPDXId(), "GoModule-golang.org-x-net-v0.1.0-0858e87a"},
+	} {
+		c.Assert(test.obtained, Equals, test.expected)
+		c.Assert(test.obtained, Matches, `[A-Za-z0-9.-]+`)
+	}
+
+	// Paths that only differ by invalid characters have different ids.
+	seen := make(map[string]string)
+	for _, path := range []string{"/usr/lib/a_b", "/usr/lib/a/b", "/usr/lib/a:b", "/usr/lib/a-b", "/usr/lib/a~b", "/usr/lib/a+b"} {
+		id := (&builder.PathInfo{Path: path}).SPDXId()
+		c.Assert(seen[id], Equals, "", Commentf("%s and %s have the same id %s", path, seen[id], id))
+		seen[id] = path
+	}
 }
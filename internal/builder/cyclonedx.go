@@ -0,0 +1,232 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/ssbom/internal/osrelease"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+)
+
+// ChiselSbomCycloneDXTool is the tool that creates BOMs. Its version is set
+// by SetCycloneDXDocumentInfo.
+var ChiselSbomCycloneDXTool = &cyclonedx.Component{
+	Type: cyclonedx.TypeApplication,
+	Name: ToolName,
+}
+
+var UbuntuCycloneDXSupplier = &cyclonedx.OrganizationalEntity{
+	Name: "Ubuntu Developers",
+	Contact: []*cyclonedx.OrganizationalContact{
+		{
+			Name:  "Ubuntu Developers",
+			Email: "ubuntu-devel-discuss@lists.ubuntu.com",
+		},
+	},
+}
+
+// BuildCycloneDXDocument builds a CycloneDX BOM from the same inputs as
+// BuildSPDXDocument. Packages are top-level components, slices are
+// sub-components of their package and files are sub-components of the first
+// slice that includes them.
+func BuildCycloneDXDocument(osRelease *osrelease.OSRelease, sliceInfos *[]SliceInfo, packageInfos *[]PackageInfo, pathInfos *[]PathInfo) (*cyclonedx.BOM, error) {
+	bom := &cyclonedx.BOM{
+		BOMFormat:   cyclonedx.BOMFormat,
+		SpecVersion: cyclonedx.SpecVersion,
+		Version:     1,
+		Metadata: &cyclonedx.Metadata{
+			Tools: &cyclonedx.Tools{
+				Components: []*cyclonedx.Component{ChiselSbomCycloneDXTool},
+			},
+		},
+	}
+
+	if hasOSPackage(osRelease) {
+		bom.Metadata.Component = &cyclonedx.Component{
+			Type:        cyclonedx.TypeOperatingSystem,
+			BOMRef:      OSId(osRelease),
+			Supplier:    distroCycloneDXSupplier(osRelease),
+			Name:        osRelease.ID,
+			Version:     osRelease.Version(),
+			Description: osComment("component", *packageInfos),
+		}
+		if osRelease.HomeURL != "" {
+			bom.Metadata.Component.ExternalReferences = []cyclonedx.ExternalReference{{Type: cyclonedx.ReferenceWebsite, URL: osRelease.HomeURL}}
+		}
+	}
+
+	packages := make(map[string]*cyclonedx.Component)
+	for _, p := range *packageInfos {
+		comp := p.buildPackageComponent()
+		packages[p.SPDXId()] = comp
+		bom.Components = append(bom.Components, comp)
+		if p.ProPocket != "" {
+			bom.Annotations = append(bom.Annotations, &cyclonedx.Annotation{
+				Subjects:  []string{comp.BOMRef},
+				Annotator: &cyclonedx.Annotator{Component: ChiselSbomCycloneDXTool},
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+				Text:      p.proComment(),
+			})
+		}
+	}
+
+	slices := make(map[string]*cyclonedx.Component)
+	for _, s := range *sliceInfos {
+		comp := s.buildSliceComponent()
+		slices[s.Name] = comp
+		pkg, ok := packages[s.packageInfo().SPDXId()]
+		if !ok {
+			bom.Components = append(bom.Components, comp)
+			continue
+		}
+		pkg.Components = append(pkg.Components, comp)
+	}
+
+	for _, p := range *pathInfos {
+		comp, err := p.buildPathComponent()
+		if err != nil {
+			return nil, err
+		}
+		var slice *cyclonedx.Component
+		if len(p.Slices) > 0 {
+			slice = slices[p.Slices[0]]
+		}
+		if slice == nil {
+			bom.Components = append(bom.Components, comp)
+			continue
+		}
+		slice.Components = append(slice.Components, comp)
+	}
+
+	var deps map[string]*cyclonedx.Dependency
+	for _, dep := range packageDependencies(*packageInfos) {
+		if deps == nil {
+			deps = make(map[string]*cyclonedx.Dependency)
+		}
+		d, ok := deps[dep[0].SPDXId()]
+		if !ok {
+			d = &cyclonedx.Dependency{Ref: dep[0].SPDXId()}
+			deps[d.Ref] = d
+			bom.Dependencies = append(bom.Dependencies, d)
+		}
+		d.DependsOn = append(d.DependsOn, dep[1].SPDXId())
+	}
+
+	return bom, nil
+}
+
+// cycloneDXSupplier returns the supplier of the package: its maintainer if
+// known, or its distribution.
+func (p *PackageInfo) cycloneDXSupplier() *cyclonedx.OrganizationalEntity {
+	if p.ProPocket != "" {
+		return UbuntuProCycloneDXSupplier
+	}
+	if p.Maintainer == "" {
+		return distroCycloneDXSupplier(p.OS)
+	}
+	name, email, _ := strings.Cut(p.Maintainer, "<")
+	name = strings.TrimSpace(name)
+	email = strings.TrimSuffix(strings.TrimSpace(email), ">")
+	return &cyclonedx.OrganizationalEntity{
+		Name:    name,
+		Contact: []*cyclonedx.OrganizationalContact{{Name: name, Email: email}},
+	}
+}
+
+func (p *PackageInfo) buildPackageComponent() *cyclonedx.Component {
+	comp := &cyclonedx.Component{
+		Type:        cyclonedx.TypeLibrary,
+		BOMRef:      p.SPDXId(),
+		Supplier:    p.cycloneDXSupplier(),
+		Name:        p.Name,
+		Version:     p.Version,
+		Description: p.Summary,
+		CPE:         p.CPE23Locator(),
+		PURL:        p.PurlLocator(),
+	}
+	for _, cpe := range p.cpe23Locators()[1:] {
+		// CycloneDX has a single CPE per component.
+		comp.Properties = append(comp.Properties, cyclonedx.Property{Name: "ssbom:cpe23", Value: cpe})
+	}
+	if p.Homepage != "" {
+		comp.ExternalReferences = append(comp.ExternalReferences, cyclonedx.ExternalReference{Type: cyclonedx.ReferenceWebsite, URL: p.Homepage})
+	}
+	if p.DownloadLocation != "" {
+		comp.ExternalReferences = append(comp.ExternalReferences, cyclonedx.ExternalReference{Type: cyclonedx.ReferenceDistribution, URL: p.DownloadLocation})
+	}
+	if p.SHA256 != "" {
+		comp.Hashes = []cyclonedx.Hash{{Algorithm: cyclonedx.SHA256, Content: p.SHA256}}
+	}
+	if p.License != "" {
+		comp.Licenses = []cyclonedx.LicenseChoice{{Expression: p.License}}
+	}
+	return comp
+}
+
+func (s *SliceInfo) buildSliceComponent() *cyclonedx.Component {
+	return &cyclonedx.Component{
+		Type:        cyclonedx.TypeLibrary,
+		BOMRef:      s.SPDXId(),
+		Name:        s.Name,
+		Description: fmt.Sprintf("This slice is a sub-package of the package %s.", s.packageName()),
+	}
+}
+
+var fileComponentDescriptions = map[int]string{
+	FileReg: "This file is included in the slice(s) %s.",
+	FileMod: "This file is mutated by the slice(s) %s.",
+	FileLnk: fileComments[FileLnk],
+	FileHlk: fileComments[FileHlk],
+}
+
+func (f *PathInfo) buildPathComponent() (*cyclonedx.Component, error) {
+	fileType, err := f.fileType()
+	if err != nil {
+		return nil, err
+	}
+
+	comp := &cyclonedx.Component{
+		Type:   cyclonedx.TypeFile,
+		BOMRef: f.SPDXId(),
+		Name:   f.Path,
+	}
+	sha256 := f.SHA256
+	if f.FinalSHA256 != "" {
+		sha256 = f.FinalSHA256
+	}
+	if sha256 != "" {
+		comp.Hashes = []cyclonedx.Hash{{Algorithm: cyclonedx.SHA256, Content: sha256}}
+	}
+	if f.License != "" {
+		comp.Licenses = []cyclonedx.LicenseChoice{{Expression: f.License}}
+	}
+	comp.Copyright = f.Copyright
+
+	switch fileType {
+	case FileReg, FileMod:
+		comp.Description = fmt.Sprintf(fileComponentDescriptions[fileType], strings.Join(f.Slices, ", "))
+	case FileLnk:
+		comp.Description = fmt.Sprintf(fileComponentDescriptions[fileType], f.Link)
+	case FileHlk:
+		comp.Description = fmt.Sprintf(fileComponentDescriptions[fileType], f.Inode)
+	}
+
+	if f.Mode != "" {
+		comp.Properties = append(comp.Properties, cyclonedx.Property{Name: "chisel:mode", Value: f.Mode})
+	}
+	for _, s := range f.Slices {
+		name := "chisel:slice"
+		if fileType == FileMod {
+			name = "chisel:mutated-by"
+		}
+		comp.Properties = append(comp.Properties, cyclonedx.Property{Name: name, Value: s})
+	}
+	if f.Link != "" {
+		comp.Properties = append(comp.Properties, cyclonedx.Property{Name: "chisel:link", Value: f.Link})
+	}
+	if f.Inode != 0 {
+		comp.Properties = append(comp.Properties, cyclonedx.Property{Name: "chisel:inode", Value: fmt.Sprint(f.Inode)})
+	}
+	return comp, nil
+}
@@ -0,0 +1,126 @@
+package builder_test
+
+import (
+	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/internal/osrelease"
+	"github.com/canonical/ssbom/internal/testutil"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	. "gopkg.in/check.v1"
+)
+
+type CycloneDXBuilderTest struct {
+	summary      string
+	osRelease    *osrelease.OSRelease
+	packageInfos []builder.PackageInfo
+	pathInfos    []builder.PathInfo
+	sliceInfos   []builder.SliceInfo
+	components   []*cyclonedx.Component
+	metadata     *cyclonedx.Component
+	error        string
+}
+
+var cycloneDXBuilderTests = []CycloneDXBuilderTest{
+	{
+		summary:      "Builds package component",
+		packageInfos: testutil.SampleSinglePackage,
+		components: []*cyclonedx.Component{
+			testutil.CycloneDXSamplePackage(),
+		},
+	}, {
+		summary:      "Builds slice as sub-component of its package",
+		packageInfos: testutil.SampleSinglePackage,
+		sliceInfos:   testutil.SampleSingleSlice,
+		components: []*cyclonedx.Component{
+			testutil.CycloneDXSamplePackage(testutil.CycloneDXSampleSlice()),
+		},
+	}, {
+		summary:      "Builds file as sub-component of its slice",
+		packageInfos: testutil.SampleSinglePackage,
+		sliceInfos:   testutil.SampleSingleSlice,
+		pathInfos:    testutil.SampleSinglePathNoFinalSHA256,
+		components: []*cyclonedx.Component{
+			testutil.CycloneDXSamplePackage(testutil.CycloneDXSampleSlice(&testutil.CycloneDXSampleFile)),
+		},
+	}, {
+		summary:      "Builds modified file",
+		packageInfos: testutil.SampleSinglePackage,
+		sliceInfos:   testutil.SampleSingleSlice,
+		pathInfos:    testutil.SampleSinglePathModified,
+		components: []*cyclonedx.Component{
+			testutil.CycloneDXSamplePackage(testutil.CycloneDXSampleSlice(&cyclonedx.Component{
+				Type:        cyclonedx.TypeFile,
+				BOMRef:      "File-test-b306d9ff",
+				Name:        "/test",
+				Description: "This file is mutated by the slice(s) test_slice.",
+				Hashes:      []cyclonedx.Hash{{Algorithm: cyclonedx.SHA256, Content: "final_sha256"}},
+				Properties: []cyclonedx.Property{
+					{Name: "chisel:mode", Value: "0644"},
+					{Name: "chisel:mutated-by", Value: "test_slice"},
+				},
+			})),
+		},
+	}, {
+		summary:    "Builds orphan slice and file at the top level",
+		sliceInfos: testutil.SampleSingleSlice,
+		pathInfos: []builder.PathInfo{{
+			Path:   "/test",
+			Mode:   "0644",
+			Slices: []string{"other_slice"},
+			Link:   "/file",
+		}},
+		components: []*cyclonedx.Component{
+			testutil.CycloneDXSampleSlice(),
+			{
+				Type:        cyclonedx.TypeFile,
+				BOMRef:      "File-test-b306d9ff",
+				Name:        "/test",
+				Description: "This file is a symlink to the file /file.",
+				Properties: []cyclonedx.Property{
+					{Name: "chisel:mode", Value: "0644"},
+					{Name: "chisel:slice", Value: "other_slice"},
+					{Name: "chisel:link", Value: "/file"},
+				},
+			},
+		},
+	}, {
+		summary:      "Builds operating system metadata component",
+		osRelease:    testutil.SampleOSReleaseNoble,
+		packageInfos: testutil.SampleSinglePackage,
+		components: []*cyclonedx.Component{
+			testutil.CycloneDXSamplePackage(),
+		},
+		metadata: &cyclonedx.Component{
+			Type:        cyclonedx.TypeOperatingSystem,
+			BOMRef:      "OperatingSystem-ubuntu-24.04",
+			Supplier:    builder.UbuntuCycloneDXSupplier,
+			Name:        "ubuntu",
+			Version:     "24.04",
+			Description: "This component is the distribution of the rootfs.",
+		},
+	}, {
+		summary: "Cannot build component for invalid link",
+		pathInfos: []builder.PathInfo{{
+			Path:  "/test",
+			Link:  "/file",
+			Inode: 1,
+		}},
+		error: "cannot build file section: invalid file type: file /test simultaneously has inode 1 and link /file",
+	},
+}
+
+func (s *S) TestCycloneDXBuilder(c *C) {
+	for _, test := range cycloneDXBuilderTests {
+		c.Logf("Running test: %s", test.summary)
+		bom, err := builder.BuildCycloneDXDocument(test.osRelease, &test.sliceInfos, &test.packageInfos, &test.pathInfos)
+		if test.error != "" {
+			c.Assert(err, ErrorMatches, test.error)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Assert(bom.BOMFormat, Equals, cyclonedx.BOMFormat)
+		c.Assert(bom.SpecVersion, Equals, cyclonedx.SpecVersion)
+		c.Assert(bom.Metadata.Tools.Components, DeepEquals, []*cyclonedx.Component{builder.ChiselSbomCycloneDXTool})
+		c.Assert(bom.Metadata.Component, DeepEquals, test.metadata)
+		c.Assert(bom.Components, DeepEquals, test.components)
+	}
+}
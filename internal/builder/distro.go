@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"github.com/canonical/ssbom/internal/osrelease"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// OSId returns the identifier of the package of the distribution.
+func OSId(osRelease *osrelease.OSRelease) string {
+	return spdxId("OperatingSystem", osRelease.ID+"-"+osRelease.Version())
+}
+
+// hasOSPackage reports whether the document has a package for the
+// distribution, which is only known with its version.
+func hasOSPackage(osRelease *osrelease.OSRelease) bool {
+	return osRelease != nil && osRelease.Version() != ""
+}
+
+// purlNamespace returns the namespace of the package URLs of the packages
+// of the distribution, which defaults to Ubuntu. The packages of derivatives
+// of Ubuntu and Debian are in the namespace of the distribution they derive
+// from.
+func purlNamespace(osRelease *osrelease.OSRelease) string {
+	if osRelease == nil {
+		return osrelease.DefaultID
+	}
+	return osRelease.Family()
+}
+
+// purlDistro returns the distro qualifier of the package URLs of the
+// packages of the distribution, if known.
+func purlDistro(osRelease *osrelease.OSRelease) string {
+	if osRelease == nil || osRelease.Distro() == "" {
+		return ""
+	}
+	return "&distro=" + osRelease.Distro()
+}
+
+func isUbuntu(osRelease *osrelease.OSRelease) bool {
+	return osRelease == nil || osRelease.Family() == "ubuntu"
+}
+
+// distroName returns the name of the distribution, for its supplier.
+func distroName(osRelease *osrelease.OSRelease) string {
+	if osRelease.Name != "" {
+		return osRelease.Name
+	}
+	return osRelease.ID
+}
+
+// DistroSupplier returns the supplier of the packages of the distribution
+// that have no known maintainer.
+func DistroSupplier(osRelease *osrelease.OSRelease) *common.Supplier {
+	if isUbuntu(osRelease) {
+		return &UbuntuPackageSupplier
+	}
+	return &common.Supplier{SupplierType: "Organization", Supplier: distroName(osRelease)}
+}
+
+func distroCycloneDXSupplier(osRelease *osrelease.OSRelease) *cyclonedx.OrganizationalEntity {
+	if isUbuntu(osRelease) {
+		return UbuntuCycloneDXSupplier
+	}
+	return &cyclonedx.OrganizationalEntity{Name: distroName(osRelease)}
+}
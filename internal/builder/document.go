@@ -0,0 +1,237 @@
+package builder
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// DefaultNamespacePrefix is the URI prefix of the namespaces of SPDX
+// documents, as recommended by the SPDX specification.
+const DefaultNamespacePrefix = "https://spdx.org/spdxdocs"
+
+// ToolName is the name ssbom is known as in the creators of documents.
+const ToolName = "ssbom"
+
+const modulePath = "github.com/canonical/ssbom"
+
+// ToolVersion returns the version of the ssbom module the program was built
+// with, or "devel" if it is not known.
+func ToolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return "devel"
+	}
+	return version
+}
+
+// DocumentInfo describes a document and how it was created. Its zero value
+// gives the defaults.
+type DocumentInfo struct {
+	// Name is the name of the document. It defaults to DocumentName.
+	Name string
+	// NamespacePrefix is the URI prefix of the namespace of SPDX documents.
+	// It defaults to DefaultNamespacePrefix.
+	NamespacePrefix string
+	// Namespace is the namespace of SPDX documents, under which the
+	// elements of SPDX 3.0 documents are identified. It defaults to the
+	// prefix followed by the name of the document and the digest, or a
+	// random UUID.
+	Namespace string
+	// Digest is the hex-encoded sha256 digest of the content described by
+	// the document. When set, the namespace and the serial number of
+	// CycloneDX BOMs are derived from it rather than random.
+	Digest string
+	// Organization is the organization that created the document, if any.
+	Organization string
+	// ToolVersion is the version of ssbom. It defaults to ToolVersion().
+	ToolVersion string
+	// Created is the time the document was created. It defaults to now.
+	Created time.Time
+}
+
+// complete returns a copy of the information with the defaults filled in.
+func (info DocumentInfo) complete() DocumentInfo {
+	if info.Name == "" {
+		info.Name = DocumentName
+	}
+	if info.NamespacePrefix == "" {
+		info.NamespacePrefix = DefaultNamespacePrefix
+	}
+	if info.Namespace == "" {
+		id := info.Digest
+		if id == "" {
+			id = newUUID()
+		}
+		info.Namespace = fmt.Sprintf("%s/%s-%s", strings.TrimSuffix(info.NamespacePrefix, "/"), slug(info.Name), id)
+	}
+	if info.ToolVersion == "" {
+		info.ToolVersion = ToolVersion()
+	}
+	if info.Created.IsZero() {
+		info.Created = time.Now()
+	}
+	return info
+}
+
+// DocumentNamespace returns the namespace of the document: Namespace if set,
+// or a new one otherwise.
+func (info DocumentInfo) DocumentNamespace() string {
+	return info.complete().Namespace
+}
+
+func (info *DocumentInfo) created() string {
+	return info.Created.UTC().Format(time.RFC3339)
+}
+
+func (info *DocumentInfo) tool() string {
+	return ToolName + "-" + info.ToolVersion
+}
+
+// slug returns the name in lower case with runs of characters other than
+// letters and digits replaced by "-", as in "chiselled-ubuntu-rootfs".
+func slug(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	if len(fields) == 0 {
+		return "document"
+	}
+	return strings.Join(fields, "-")
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("internal error: cannot read random bytes: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+// digestNamespace is the namespace of the name-based UUIDs derived from
+// digests: the URL namespace of RFC 9562.
+var digestNamespace = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// digestUUID returns a name-based (version 5) UUID derived from a hex-encoded
+// digest.
+func digestUUID(digest string) string {
+	var b [16]byte
+	h := sha1.New()
+	h.Write(digestNamespace[:])
+	h.Write([]byte("urn:sha256:" + digest))
+	copy(b[:], h.Sum(nil))
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// SetSPDXDocumentInfo sets the name, namespace and creation information of
+// the document. The annotations made while building the document are dated
+// with its creation time and credited to the version of the tool.
+func SetSPDXDocumentInfo(doc *spdx.Document, info DocumentInfo) {
+	info = info.complete()
+	doc.DocumentName = info.Name
+	doc.DocumentNamespace = info.Namespace
+	creators := []common.Creator{{Creator: info.tool(), CreatorType: "Tool"}}
+	if info.Organization != "" {
+		creators = append(creators, common.Creator{Creator: info.Organization, CreatorType: "Organization"})
+	}
+	doc.CreationInfo = &spdx.CreationInfo{
+		Creators: creators,
+		Created:  info.created(),
+	}
+	for _, a := range doc.Annotations {
+		if a.Annotator.AnnotatorType == "Tool" {
+			a.Annotator.Annotator = info.tool()
+			a.AnnotationDate = info.created()
+		}
+	}
+}
+
+// SetSPDX3DocumentInfo sets the name and creation information of the
+// document and the version of the tool that created it.
+func SetSPDX3DocumentInfo(doc *spdx3.Document, info DocumentInfo) {
+	info = info.complete()
+	namespace := spdx3Namespace(doc)
+	var creationInfo *spdx3.CreationInfo
+	for _, element := range doc.Graph {
+		switch e := element.(type) {
+		case *spdx3.CreationInfo:
+			creationInfo = e
+		case *spdx3.SpdxDocument:
+			e.Name = info.Name
+		case *spdx3.Agent:
+			if e.SpdxID == SPDX3Id(namespace, "Tool-ssbom") || e.SpdxID == SPDX3Id(namespace, "SoftwareAgent-ssbom") {
+				e.Name = info.tool()
+			}
+		}
+	}
+	if creationInfo == nil {
+		return
+	}
+	creationInfo.Created = info.created()
+	if info.Organization != "" {
+		if b := extendSPDX3(doc); b != nil {
+			creationInfo.CreatedBy = append(creationInfo.CreatedBy, b.organization(info.Organization))
+			b.flush(doc)
+		}
+	}
+}
+
+// SetCycloneDXDocumentInfo sets the serial number and timestamp of the BOM
+// and the version of the tool that created it. The annotations made while
+// building the BOM are dated with its timestamp.
+func SetCycloneDXDocumentInfo(bom *cyclonedx.BOM, info DocumentInfo) {
+	info = info.complete()
+	if info.Digest != "" {
+		bom.SerialNumber = "urn:uuid:" + digestUUID(info.Digest)
+	} else {
+		bom.SerialNumber = "urn:uuid:" + newUUID()
+	}
+	if bom.Metadata == nil {
+		bom.Metadata = &cyclonedx.Metadata{}
+	}
+	bom.Metadata.Timestamp = info.created()
+	tool := *ChiselSbomCycloneDXTool
+	tool.Version = info.ToolVersion
+	if bom.Metadata.Tools != nil {
+		for i, c := range bom.Metadata.Tools.Components {
+			if c == ChiselSbomCycloneDXTool {
+				bom.Metadata.Tools.Components[i] = &tool
+			}
+		}
+	}
+	for _, a := range bom.Annotations {
+		if a.Annotator != nil && a.Annotator.Component == ChiselSbomCycloneDXTool {
+			a.Annotator.Component = &tool
+			a.Timestamp = info.created()
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// EcosystemPackageInfo describes a package of a language ecosystem, such as
+// a Python distribution, found in the rootfs.
+type EcosystemPackageInfo struct {
+	// Type is the package URL type of the ecosystem, such as "pypi".
+	Type      string
+	Namespace string
+	Name      string
+	Version   string
+	PURL      string
+	// Location is the path of the file that describes the package.
+	Location string
+}
+
+func (p *EcosystemPackageInfo) SPDXId() string {
+	name := p.Name
+	if p.Namespace != "" {
+		name = p.Namespace + "/" + p.Name
+	}
+	return spdxId("Package", fmt.Sprintf("%s-%s@%s", p.Type, name, p.Version))
+}
+
+// ecosystemPackage is a package found at one or more locations.
+type ecosystemPackage struct {
+	EcosystemPackageInfo
+	locations []string
+}
+
+func (p *ecosystemPackage) comment() string {
+	return fmt.Sprintf("This %s package was found at %s.", p.Type, strings.Join(p.locations, ", "))
+}
+
+// groupEcosystemPackages merges the packages with the same identifier, which
+// are installed at several locations.
+func groupEcosystemPackages(packageInfos []EcosystemPackageInfo) []*ecosystemPackage {
+	var packages []*ecosystemPackage
+	byId := make(map[string]*ecosystemPackage)
+	for _, p := range packageInfos {
+		pkg, ok := byId[p.SPDXId()]
+		if !ok {
+			pkg = &ecosystemPackage{EcosystemPackageInfo: p}
+			byId[p.SPDXId()] = pkg
+			packages = append(packages, pkg)
+		}
+		pkg.locations = append(pkg.locations, p.Location)
+	}
+	return packages
+}
+
+// AddEcosystemSPDXPackages adds the packages of language ecosystems to the
+// document, next to the deb packages.
+func AddEcosystemSPDXPackages(doc *spdx.Document, packageInfos []EcosystemPackageInfo) {
+	for _, p := range groupEcosystemPackages(packageInfos) {
+		doc.Packages = append(doc.Packages, &spdx.Package{
+			PackageName:             p.Name,
+			PackageSPDXIdentifier:   common.ElementID(p.SPDXId()),
+			PackageVersion:          p.Version,
+			PackageFileName:         p.locations[0],
+			PackageDownloadLocation: "NOASSERTION",
+			FilesAnalyzed:           false,
+			PackageComment:          p.comment(),
+			PackageExternalReferences: []*spdx.PackageExternalReference{
+				{
+					Category: "PACKAGE_MANAGER",
+					RefType:  "purl",
+					Locator:  p.PURL,
+				},
+			},
+			PrimaryPackagePurpose: "LIBRARY",
+		})
+		doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+			RefA:         common.MakeDocElementID("", "DOCUMENT"),
+			RefB:         common.MakeDocElementID("", p.SPDXId()),
+			Relationship: "DESCRIBES",
+		})
+	}
+}
+
+// AddEcosystemCycloneDXComponents adds the packages of language ecosystems
+// to the BOM as top-level library components, with their locations as
+// evidence.
+func AddEcosystemCycloneDXComponents(bom *cyclonedx.BOM, packageInfos []EcosystemPackageInfo) {
+	for _, p := range groupEcosystemPackages(packageInfos) {
+		comp := &cyclonedx.Component{
+			Type:     cyclonedx.TypeLibrary,
+			BOMRef:   p.SPDXId(),
+			Group:    p.Namespace,
+			Name:     p.Name,
+			Version:  p.Version,
+			PURL:     p.PURL,
+			Evidence: &cyclonedx.Evidence{},
+		}
+		for _, location := range p.locations {
+			comp.Evidence.Occurrences = append(comp.Evidence.Occurrences, cyclonedx.Occurrence{Location: location})
+		}
+		bom.Components = append(bom.Components, comp)
+	}
+}
+
+// AddEcosystemSPDX3Packages adds the packages of language ecosystems to the
+// document as root elements, next to the deb packages.
+func AddEcosystemSPDX3Packages(doc *spdx3.Document, packageInfos []EcosystemPackageInfo) {
+	b := extendSPDX3(doc)
+	if b == nil {
+		return
+	}
+	for _, p := range groupEcosystemPackages(packageInfos) {
+		id := b.id(p.SPDXId())
+		b.add(id, &spdx3.Package{
+			Element: spdx3.Element{
+				Type:         spdx3.TypePackage,
+				SpdxID:       id,
+				CreationInfo: spdx3CreationInfoId,
+				Name:         p.Name,
+				Comment:      p.comment(),
+			},
+			PrimaryPurpose: spdx3.PurposeLibrary,
+			PackageVersion: p.Version,
+			PackageURL:     p.PURL,
+		})
+		b.doc.RootElement = append(b.doc.RootElement, id)
+	}
+	b.flush(doc)
+}
@@ -0,0 +1,196 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// GoBinaryInfo describes a Go executable of the rootfs and the modules
+// linked into it.
+type GoBinaryInfo struct {
+	Path    string
+	SHA256  string
+	Modules []GoModuleInfo
+}
+
+type GoModuleInfo struct {
+	Path    string
+	Version string
+}
+
+const (
+	goBinaryComment = "This file is a Go executable."
+	goModuleComment = "This package is a Go module linked into one or more executables; see Relationship information."
+)
+
+func (b *GoBinaryInfo) SPDXId() string {
+	return spdxId("File", b.Path)
+}
+
+func (m *GoModuleInfo) SPDXId() string {
+	if m.Version == "" {
+		return spdxId("GoModule", m.Path)
+	}
+	return spdxId("GoModule", m.Path+"@"+m.Version)
+}
+
+func (m *GoModuleInfo) PurlLocator() string {
+	if m.Version == "" {
+		return fmt.Sprintf("pkg:golang/%s", m.Path)
+	}
+	return fmt.Sprintf("pkg:golang/%s@%s", m.Path, strings.ReplaceAll(m.Version, "+", "%2B"))
+}
+
+// AddGoModulesSPDX adds a package for every module of the Go executables to
+// the document, contained in the file of each executable that links it.
+// Executables that are not in the document yet are added as files.
+func AddGoModulesSPDX(doc *spdx.Document, binaryInfos []GoBinaryInfo) {
+	ids := make(map[string]bool)
+	for _, f := range doc.Files {
+		ids[string(f.FileSPDXIdentifier)] = true
+	}
+	for _, p := range doc.Packages {
+		ids[string(p.PackageSPDXIdentifier)] = true
+	}
+
+	for _, b := range binaryInfos {
+		if !ids[b.SPDXId()] {
+			ids[b.SPDXId()] = true
+			doc.Files = append(doc.Files, &spdx.File{
+				FileName:           b.Path,
+				FileSPDXIdentifier: common.ElementID(b.SPDXId()),
+				Checksums:          []common.Checksum{{Algorithm: common.SHA256, Value: b.SHA256}},
+				FileCopyrightText:  "NOASSERTION",
+				FileComment:        goBinaryComment,
+			})
+		}
+		for _, m := range b.Modules {
+			if !ids[m.SPDXId()] {
+				ids[m.SPDXId()] = true
+				doc.Packages = append(doc.Packages, &spdx.Package{
+					PackageName:             m.Path,
+					PackageSPDXIdentifier:   common.ElementID(m.SPDXId()),
+					PackageVersion:          m.Version,
+					PackageDownloadLocation: "NOASSERTION",
+					FilesAnalyzed:           false,
+					PackageComment:          goModuleComment,
+					PackageExternalReferences: []*spdx.PackageExternalReference{
+						{
+							Category: "PACKAGE_MANAGER",
+							RefType:  "purl",
+							Locator:  m.PurlLocator(),
+						},
+					},
+					PrimaryPackagePurpose: "LIBRARY",
+				})
+			}
+			doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+				RefA:                common.MakeDocElementID("", b.SPDXId()),
+				RefB:                common.MakeDocElementID("", m.SPDXId()),
+				Relationship:        "CONTAINS",
+				RelationshipComment: fmt.Sprintf("File %s links the Go module %s.", b.Path, m.Path),
+			})
+		}
+	}
+}
+
+// AddGoModulesCycloneDX adds a library component for every module of the Go
+// executables to the BOM, and a dependency of each executable on the modules
+// it links. Executables that are not in the BOM yet are added as components.
+func AddGoModulesCycloneDX(bom *cyclonedx.BOM, binaryInfos []GoBinaryInfo) {
+	refs := make(map[string]bool)
+	var collect func(comps []*cyclonedx.Component)
+	collect = func(comps []*cyclonedx.Component) {
+		for _, comp := range comps {
+			refs[comp.BOMRef] = true
+			collect(comp.Components)
+		}
+	}
+	collect(bom.Components)
+
+	for _, b := range binaryInfos {
+		if !refs[b.SPDXId()] {
+			refs[b.SPDXId()] = true
+			bom.Components = append(bom.Components, &cyclonedx.Component{
+				Type:        cyclonedx.TypeFile,
+				BOMRef:      b.SPDXId(),
+				Name:        b.Path,
+				Description: goBinaryComment,
+				Hashes:      []cyclonedx.Hash{{Algorithm: cyclonedx.SHA256, Content: b.SHA256}},
+			})
+		}
+		dep := &cyclonedx.Dependency{Ref: b.SPDXId()}
+		for _, m := range b.Modules {
+			if !refs[m.SPDXId()] {
+				refs[m.SPDXId()] = true
+				bom.Components = append(bom.Components, &cyclonedx.Component{
+					Type:        cyclonedx.TypeLibrary,
+					BOMRef:      m.SPDXId(),
+					Name:        m.Path,
+					Version:     m.Version,
+					Description: goModuleComment,
+					PURL:        m.PurlLocator(),
+				})
+			}
+			dep.DependsOn = append(dep.DependsOn, m.SPDXId())
+		}
+		bom.Dependencies = append(bom.Dependencies, dep)
+	}
+}
+
+// AddGoModulesSPDX3 adds a package for every module of the Go executables to
+// the document, contained in the file of each executable that links it.
+// Executables that are not in the document yet are added as files.
+func AddGoModulesSPDX3(doc *spdx3.Document, binaryInfos []GoBinaryInfo) {
+	b := extendSPDX3(doc)
+	if b == nil {
+		return
+	}
+	ids := make(map[string]bool)
+	for _, id := range b.doc.Elements {
+		ids[id] = true
+	}
+
+	for _, bin := range binaryInfos {
+		fileId := b.id(bin.SPDXId())
+		if !ids[fileId] {
+			ids[fileId] = true
+			b.add(fileId, &spdx3.File{
+				Element: spdx3.Element{
+					Type:         spdx3.TypeFile,
+					SpdxID:       fileId,
+					CreationInfo: spdx3CreationInfoId,
+					Name:         bin.Path,
+					Comment:      goBinaryComment,
+				},
+				VerifiedUsing:  []spdx3.Hash{spdx3.SHA256(bin.SHA256)},
+				PrimaryPurpose: spdx3.PurposeFile,
+			})
+		}
+		for _, m := range bin.Modules {
+			id := b.id(m.SPDXId())
+			if !ids[id] {
+				ids[id] = true
+				b.add(id, &spdx3.Package{
+					Element: spdx3.Element{
+						Type:         spdx3.TypePackage,
+						SpdxID:       id,
+						CreationInfo: spdx3CreationInfoId,
+						Name:         m.Path,
+						Comment:      goModuleComment,
+					},
+					PrimaryPurpose: spdx3.PurposeLibrary,
+					PackageVersion: m.Version,
+					PackageURL:     m.PurlLocator(),
+				})
+			}
+			b.relate(bin.SPDXId(), spdx3.RelationshipContains, m.SPDXId())
+		}
+	}
+	b.flush(doc)
+}
@@ -0,0 +1,29 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+var invalidIdChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// spdxId returns the identifier of an element of the given kind, such as
+// "Package", and name. Only letters, digits, "." and "-" are allowed in SPDX
+// identifiers: the runs of other characters of the name are replaced by "-"
+// and the name is then followed by the start of its sha256 digest, as in
+// "File-usr-bin-hello-86a8fab9", so that different names still have
+// different identifiers.
+func spdxId(kind, name string) string {
+	safe := invalidIdChars.ReplaceAllString(name, "-")
+	if safe == name && name != "" {
+		return kind + "-" + name
+	}
+	sum := sha256.Sum256([]byte(name))
+	digest := hex.EncodeToString(sum[:4])
+	if safe = strings.Trim(safe, "-"); safe == "" {
+		return kind + "-" + digest
+	}
+	return kind + "-" + safe + "-" + digest
+}
@@ -0,0 +1,23 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/spdx/tools-golang/spdx"
+)
+
+// AddSPDXLicenseRefs adds the licenses that are not in the SPDX license list,
+// and are used in the license expressions of the document, to it.
+func AddSPDXLicenseRefs(doc *spdx.Document, refInfos []LicenseRefInfo) {
+	for _, ref := range refInfos {
+		text := ref.Text
+		if text == "" {
+			text = fmt.Sprintf("The text of the license %s is not in the copyright file.", ref.Name)
+		}
+		doc.OtherLicenses = append(doc.OtherLicenses, &spdx.OtherLicense{
+			LicenseIdentifier: ref.ID,
+			LicenseName:       ref.Name,
+			ExtractedText:     text,
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package builder
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// UbuntuProPackageSupplier is the supplier of the packages of the Ubuntu
+// Pro archives.
+var UbuntuProPackageSupplier = common.Supplier{
+	SupplierType: "Organization",
+	Supplier:     "Canonical Ltd.",
+}
+
+var UbuntuProCycloneDXSupplier = &cyclonedx.OrganizationalEntity{
+	Name: "Canonical Ltd.",
+}
+
+// proComment returns the comment that tells which Ubuntu Pro pocket the
+// package comes from.
+func (p *PackageInfo) proComment() string {
+	return fmt.Sprintf("This package comes from the Ubuntu Pro %s pocket.", p.ProPocket)
+}
+
+func (p *PackageInfo) repositoryURLQualifier() string {
+	if p.RepositoryURL == "" {
+		return ""
+	}
+	return "&repository_url=" + url.QueryEscape(p.RepositoryURL)
+}
+
+// osComment returns the comment of the OS package, which tells the Ubuntu
+// Pro pockets of its packages, if any.
+func osComment(kind string, packageInfos []PackageInfo) string {
+	comment := fmt.Sprintf("This %s is the distribution of the rootfs.", kind)
+	seen := make(map[string]bool)
+	var pockets []string
+	for _, p := range packageInfos {
+		if p.ProPocket != "" && !seen[p.ProPocket] {
+			seen[p.ProPocket] = true
+			pockets = append(pockets, p.ProPocket)
+		}
+	}
+	if len(pockets) == 0 {
+		return comment
+	}
+	sort.Strings(pockets)
+	return fmt.Sprintf("This %s is the distribution of the rootfs, with packages from the Ubuntu Pro %s pockets.", kind, strings.Join(pockets, ", "))
+}
@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"sort"
+
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// SortSPDXDocument sorts the elements of the document so that the same
+// content is always written the same way. The operating system packages come
+// first and the other packages follow by identifier, files are sorted by
+// name and identifier, relationships by their first element, type and second
+// element, and annotations by subject and comment.
+func SortSPDXDocument(doc *spdx.Document) {
+	sort.SliceStable(doc.Packages, func(i, j int) bool {
+		a, b := doc.Packages[i], doc.Packages[j]
+		aOS, bOS := a.PrimaryPackagePurpose == "OPERATING_SYSTEM", b.PrimaryPackagePurpose == "OPERATING_SYSTEM"
+		if aOS != bOS {
+			return aOS
+		}
+		return a.PackageSPDXIdentifier < b.PackageSPDXIdentifier
+	})
+	sort.SliceStable(doc.Files, func(i, j int) bool {
+		a, b := doc.Files[i], doc.Files[j]
+		if a.FileName != b.FileName {
+			return a.FileName < b.FileName
+		}
+		return a.FileSPDXIdentifier < b.FileSPDXIdentifier
+	})
+	sort.SliceStable(doc.Relationships, func(i, j int) bool {
+		a, b := doc.Relationships[i], doc.Relationships[j]
+		if ka, kb := docElementKey(a.RefA), docElementKey(b.RefA); ka != kb {
+			return ka < kb
+		}
+		if a.Relationship != b.Relationship {
+			return a.Relationship < b.Relationship
+		}
+		return docElementKey(a.RefB) < docElementKey(b.RefB)
+	})
+	sort.SliceStable(doc.Annotations, func(i, j int) bool {
+		a, b := doc.Annotations[i], doc.Annotations[j]
+		if ka, kb := docElementKey(a.AnnotationSPDXIdentifier), docElementKey(b.AnnotationSPDXIdentifier); ka != kb {
+			return ka < kb
+		}
+		return a.AnnotationComment < b.AnnotationComment
+	})
+	sort.SliceStable(doc.OtherLicenses, func(i, j int) bool {
+		return doc.OtherLicenses[i].LicenseIdentifier < doc.OtherLicenses[j].LicenseIdentifier
+	})
+}
+
+func docElementKey(id common.DocElementID) string {
+	if id.SpecialID != "" {
+		return id.SpecialID
+	}
+	return id.DocumentRefID + ":" + string(id.ElementRefID)
+}
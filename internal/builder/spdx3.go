@@ -0,0 +1,387 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/ssbom/internal/osrelease"
+	"github.com/canonical/ssbom/sbom/spdx3"
+)
+
+const spdx3CreationInfoId = "_:creationinfo"
+
+// SPDX3Id returns the SPDX 3.0 identifier of an element of the document with
+// the given namespace (see DocumentInfo.DocumentNamespace), given its local
+// (SPDX 2.3) identifier.
+func SPDX3Id(namespace, localId string) string {
+	return fmt.Sprintf("%s#%s", namespace, localId)
+}
+
+// spdx3Namespace returns the namespace of the identifiers of the elements of
+// the document, from the identifier of its SpdxDocument element.
+func spdx3Namespace(doc *spdx3.Document) string {
+	for _, element := range doc.Graph {
+		if e, ok := element.(*spdx3.SpdxDocument); ok {
+			return strings.TrimSuffix(e.SpdxID, "#DOCUMENT")
+		}
+	}
+	return ""
+}
+
+// spdx3Builder accumulates the elements of an SPDX 3.0 graph.
+type spdx3Builder struct {
+	namespace string
+	doc       *spdx3.SpdxDocument
+	elements  []any
+	rlns      map[string]*spdx3.Relationship
+	licenses  map[string]string
+	// agents holds the identifiers of the persons and organizations, by
+	// type and name.
+	agents      map[string]string
+	annotations int
+}
+
+// id returns the identifier of an element given its local identifier.
+func (b *spdx3Builder) id(localId string) string {
+	return SPDX3Id(b.namespace, localId)
+}
+
+func (b *spdx3Builder) add(id string, element any) {
+	b.doc.Elements = append(b.doc.Elements, id)
+	b.elements = append(b.elements, element)
+}
+
+// relate records a relationship, merging every relationship with the same
+// source and type into a single element.
+func (b *spdx3Builder) relate(from, rel, to string) {
+	key := fmt.Sprintf("Relationship-%s-%s", from, rel)
+	if r, ok := b.rlns[key]; ok {
+		r.To = append(r.To, b.id(to))
+		return
+	}
+	r := &spdx3.Relationship{
+		Element: spdx3.Element{
+			Type:         spdx3.TypeRelationship,
+			SpdxID:       b.id(key),
+			CreationInfo: spdx3CreationInfoId,
+		},
+		From:             b.id(from),
+		To:               []string{b.id(to)},
+		RelationshipType: rel,
+	}
+	b.rlns[key] = r
+	b.add(r.SpdxID, r)
+}
+
+// license returns the local identifier of the element for the license
+// expression, adding it on first use.
+func (b *spdx3Builder) license(expression string) string {
+	if id, ok := b.licenses[expression]; ok {
+		return id
+	}
+	if b.licenses == nil {
+		b.licenses = make(map[string]string)
+	}
+	id := fmt.Sprintf("LicenseExpression-%d", len(b.licenses)+1)
+	b.licenses[expression] = id
+	b.add(b.id(id), &spdx3.LicenseExpression{
+		Element: spdx3.Element{
+			Type:         spdx3.TypeLicenseExpression,
+			SpdxID:       b.id(id),
+			CreationInfo: spdx3CreationInfoId,
+		},
+		LicenseExpression: expression,
+	})
+	if len(b.licenses) == 1 {
+		b.doc.ProfileConformance = append(b.doc.ProfileConformance, spdx3.ProfileSimpleLicensing)
+	}
+	return id
+}
+
+// person returns the identifier of the element for the person, such as a
+// package maintainer, adding it on first use.
+func (b *spdx3Builder) person(name string) string {
+	return b.agent(spdx3.TypePerson, name)
+}
+
+// organization returns the identifier of the element for the organization,
+// adding it on first use.
+func (b *spdx3Builder) organization(name string) string {
+	return b.agent(spdx3.TypeOrganization, name)
+}
+
+func (b *spdx3Builder) agent(agentType, name string) string {
+	key := agentType + "/" + name
+	if id, ok := b.agents[key]; ok {
+		return id
+	}
+	if b.agents == nil {
+		b.agents = make(map[string]string)
+	}
+	id := b.id(fmt.Sprintf("%s-%d", agentType, len(b.agents)+1))
+	b.agents[key] = id
+	b.add(id, &spdx3.Agent{Element: spdx3.Element{
+		Type:         agentType,
+		SpdxID:       id,
+		CreationInfo: spdx3CreationInfoId,
+		Name:         name,
+	}})
+	return id
+}
+
+// annotate adds an annotation about an element.
+func (b *spdx3Builder) annotate(subject, statement string) {
+	b.annotations++
+	id := b.id(fmt.Sprintf("Annotation-%d", b.annotations))
+	b.add(id, &spdx3.Annotation{
+		Element: spdx3.Element{
+			Type:         spdx3.TypeAnnotation,
+			SpdxID:       id,
+			CreationInfo: spdx3CreationInfoId,
+		},
+		AnnotationType: spdx3.AnnotationOther,
+		Subject:        subject,
+		Statement:      statement,
+	})
+}
+
+// extendSPDX3 returns a builder that adds elements and relationships to an
+// existing document, or nil if the document has no SpdxDocument element.
+// The added elements are only in the graph after flush.
+func extendSPDX3(doc *spdx3.Document) *spdx3Builder {
+	b := &spdx3Builder{namespace: spdx3Namespace(doc), rlns: make(map[string]*spdx3.Relationship)}
+	for _, element := range doc.Graph {
+		switch e := element.(type) {
+		case *spdx3.SpdxDocument:
+			b.doc = e
+		case *spdx3.Relationship:
+			b.rlns[strings.TrimPrefix(e.SpdxID, b.id(""))] = e
+		case *spdx3.Annotation:
+			b.annotations++
+		case *spdx3.Agent:
+			if e.Type == spdx3.TypePerson || e.Type == spdx3.TypeOrganization {
+				if b.agents == nil {
+					b.agents = make(map[string]string)
+				}
+				b.agents[e.Type+"/"+e.Name] = e.SpdxID
+			}
+		}
+	}
+	if b.doc == nil {
+		return nil
+	}
+	return b
+}
+
+// flush appends the added elements to the graph of the document.
+func (b *spdx3Builder) flush(doc *spdx3.Document) {
+	doc.Graph = append(doc.Graph, b.elements...)
+	b.elements = nil
+}
+
+// BuildSPDX3Document builds an SPDX 3.0 document from the same inputs as
+// BuildSPDXDocument, with the identifiers of its elements in the namespace.
+// The OS and deb packages are the root elements of the document, slices are
+// packages contained in their deb package and files are contained in (or
+// modified by) their slices.
+func BuildSPDX3Document(namespace string, osRelease *osrelease.OSRelease, sliceInfos *[]SliceInfo, packageInfos *[]PackageInfo, pathInfos *[]PathInfo) (*spdx3.Document, error) {
+	b := &spdx3Builder{
+		namespace: namespace,
+		rlns:      make(map[string]*spdx3.Relationship),
+	}
+	creatorId := b.id("SoftwareAgent-ssbom")
+	toolId := b.id("Tool-ssbom")
+	supplierId := b.id("Organization-" + purlNamespace(osRelease))
+
+	creationInfo := &spdx3.CreationInfo{
+		Type:         spdx3.TypeCreationInfo,
+		ID:           spdx3CreationInfoId,
+		SpecVersion:  spdx3.SpecVersion,
+		Created:      time.Now().UTC().Format(time.RFC3339),
+		CreatedBy:    []string{creatorId},
+		CreatedUsing: []string{toolId},
+	}
+	b.doc = &spdx3.SpdxDocument{
+		Element: spdx3.Element{
+			Type:         spdx3.TypeSpdxDocument,
+			SpdxID:       b.id("DOCUMENT"),
+			CreationInfo: spdx3CreationInfoId,
+			Name:         DocumentName,
+		},
+		DataLicense:        spdx3.DataLicense,
+		ProfileConformance: []string{spdx3.ProfileCore, spdx3.ProfileSoftware},
+	}
+
+	b.add(creatorId, &spdx3.Agent{Element: spdx3.Element{
+		Type:         spdx3.TypeSoftwareAgent,
+		SpdxID:       creatorId,
+		CreationInfo: spdx3CreationInfoId,
+		Name:         ToolName,
+	}})
+	b.add(toolId, &spdx3.Agent{Element: spdx3.Element{
+		Type:         spdx3.TypeTool,
+		SpdxID:       toolId,
+		CreationInfo: spdx3CreationInfoId,
+		Name:         ToolName,
+	}})
+	b.add(supplierId, &spdx3.Agent{Element: spdx3.Element{
+		Type:         spdx3.TypeOrganization,
+		SpdxID:       supplierId,
+		CreationInfo: spdx3CreationInfoId,
+		Name:         DistroSupplier(osRelease).Supplier,
+	}})
+
+	if hasOSPackage(osRelease) {
+		osId := b.id(OSId(osRelease))
+		b.add(osId, &spdx3.Package{
+			Element: spdx3.Element{
+				Type:         spdx3.TypePackage,
+				SpdxID:       osId,
+				CreationInfo: spdx3CreationInfoId,
+				Name:         osRelease.ID,
+				Summary:      osRelease.PrettyName,
+				Comment:      osComment("package", *packageInfos),
+			},
+			SuppliedBy:     supplierId,
+			PrimaryPurpose: spdx3.PurposeOperatingSystem,
+			PackageVersion: osRelease.Version(),
+			HomePage:       osRelease.HomeURL,
+		})
+		b.doc.RootElement = append(b.doc.RootElement, osId)
+	}
+
+	for _, p := range *packageInfos {
+		id := b.id(p.SPDXId())
+		pkg := &spdx3.Package{
+			Element: spdx3.Element{
+				Type:         spdx3.TypePackage,
+				SpdxID:       id,
+				CreationInfo: spdx3CreationInfoId,
+				Name:         p.Name,
+				Summary:      p.Summary,
+				Comment:      "This package includes one or more slice(s); see Relationship information.",
+			},
+			SuppliedBy:       supplierId,
+			PrimaryPurpose:   spdx3.PurposeLibrary,
+			PackageVersion:   p.Version,
+			PackageURL:       p.PurlLocator(),
+			DownloadLocation: p.DownloadLocation,
+			HomePage:         p.Homepage,
+			SourceInfo:       p.SourceInfo(),
+		}
+		for _, cpe := range p.cpe23Locators() {
+			pkg.ExternalIdentifier = append(pkg.ExternalIdentifier, spdx3.NewExternalIdentifier(spdx3.ExternalIdentifierCPE23, cpe))
+		}
+		if p.ProPocket != "" {
+			pkg.SuppliedBy = b.organization(UbuntuProPackageSupplier.Supplier)
+		} else if p.Maintainer != "" {
+			pkg.SuppliedBy = b.person(p.Maintainer)
+		}
+		if p.OriginalMaintainer != "" {
+			pkg.OriginatedBy = []string{b.person(p.OriginalMaintainer)}
+		}
+		if p.SHA256 != "" {
+			pkg.VerifiedUsing = []spdx3.Hash{spdx3.SHA256(p.SHA256)}
+		}
+		b.add(id, pkg)
+		b.doc.RootElement = append(b.doc.RootElement, id)
+		if p.ProPocket != "" {
+			b.annotate(id, p.proComment())
+		}
+		if p.License != "" {
+			license := b.license(p.License)
+			b.relate(p.SPDXId(), spdx3.RelationshipHasDeclaredLicense, license)
+			b.relate(p.SPDXId(), spdx3.RelationshipHasConcludedLicense, license)
+		}
+	}
+
+	for _, dep := range packageDependencies(*packageInfos) {
+		b.relate(dep[0].SPDXId(), spdx3.RelationshipDependsOn, dep[1].SPDXId())
+	}
+
+	for _, src := range sourcePackages(*packageInfos) {
+		id := b.id(src.SPDXId())
+		b.add(id, &spdx3.Package{
+			Element: spdx3.Element{
+				Type:         spdx3.TypePackage,
+				SpdxID:       id,
+				CreationInfo: spdx3CreationInfoId,
+				Name:         src.Name,
+				Comment:      "This package is the source package of one or more packages; see Relationship information.",
+			},
+			SuppliedBy:     supplierId,
+			PrimaryPurpose: spdx3.PurposeSource,
+			PackageVersion: src.Version,
+			PackageURL:     src.PurlLocator(),
+		})
+		for _, p := range src.Binaries {
+			b.relate(src.SPDXId(), spdx3.RelationshipGenerates, p.SPDXId())
+		}
+	}
+
+	for _, s := range *sliceInfos {
+		id := b.id(s.SPDXId())
+		b.add(id, &spdx3.Package{
+			Element: spdx3.Element{
+				Type:         spdx3.TypePackage,
+				SpdxID:       id,
+				CreationInfo: spdx3CreationInfoId,
+				Name:         s.Name,
+				Comment:      fmt.Sprintf("This slice is a sub-package of the package %s; see Relationship information.", s.packageName()),
+			},
+			PrimaryPurpose: spdx3.PurposeLibrary,
+		})
+		b.relate(s.packageInfo().SPDXId(), spdx3.RelationshipContains, s.SPDXId())
+	}
+
+	for _, p := range *pathInfos {
+		fileType, err := p.fileType()
+		if err != nil {
+			return nil, err
+		}
+		id := b.id(p.SPDXId())
+		file := &spdx3.File{
+			Element: spdx3.Element{
+				Type:         spdx3.TypeFile,
+				SpdxID:       id,
+				CreationInfo: spdx3CreationInfoId,
+				Name:         p.Path,
+			},
+			PrimaryPurpose: spdx3.PurposeFile,
+			CopyrightText:  p.Copyright,
+		}
+		sha256 := p.SHA256
+		if p.FinalSHA256 != "" {
+			sha256 = p.FinalSHA256
+		}
+		if sha256 != "" {
+			file.VerifiedUsing = []spdx3.Hash{spdx3.SHA256(sha256)}
+		}
+		switch fileType {
+		case FileLnk:
+			file.Comment = fmt.Sprintf(fileComments[fileType], p.Link)
+		case FileHlk:
+			file.Comment = fmt.Sprintf(fileComments[fileType], p.Inode)
+		}
+		b.add(id, file)
+		if p.License != "" {
+			b.relate(p.SPDXId(), spdx3.RelationshipHasConcludedLicense, b.license(p.License))
+		}
+
+		for _, s := range p.Slices {
+			slice := SliceInfo{Name: s}
+			if fileType == FileMod {
+				b.relate(p.SPDXId(), spdx3.RelationshipModifiedBy, slice.SPDXId())
+			} else {
+				b.relate(slice.SPDXId(), spdx3.RelationshipContains, p.SPDXId())
+			}
+		}
+	}
+
+	doc := &spdx3.Document{
+		Context: spdx3.Context,
+		Graph:   append([]any{creationInfo, b.doc}, b.elements...),
+	}
+	return doc, nil
+}
@@ -0,0 +1,205 @@
+package builder_test
+
+import (
+	"time"
+
+	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/internal/osrelease"
+	"github.com/canonical/ssbom/internal/testutil"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	. "gopkg.in/check.v1"
+)
+
+const spdx3TestNamespace = "https://example.com/spdxdocs/test"
+
+func spdx3Id(localId string) string {
+	return builder.SPDX3Id(spdx3TestNamespace, localId)
+}
+
+type SPDX3BuilderTest struct {
+	summary      string
+	osRelease    *osrelease.OSRelease
+	packageInfos []builder.PackageInfo
+	pathInfos    []builder.PathInfo
+	sliceInfos   []builder.SliceInfo
+	rootElements []string
+	elements     []any
+	error        string
+}
+
+var spdx3SamplePackage = &spdx3.Package{
+	Element: spdx3.Element{
+		Type:         spdx3.TypePackage,
+		SpdxID:       spdx3Id("Package-test"),
+		CreationInfo: "_:creationinfo",
+		Name:         "test",
+		Comment:      "This package includes one or more slice(s); see Relationship information.",
+	},
+	SuppliedBy:    spdx3Id("Organization-ubuntu"),
+	VerifiedUsing: []spdx3.Hash{spdx3.SHA256("sha256")},
+	ExternalIdentifier: []spdx3.ExternalIdentifier{
+		spdx3.NewExternalIdentifier(spdx3.ExternalIdentifierCPE23, "cpe:2.3:a:test:test:1.0:*:*:*:*:*:*:*"),
+	},
+	PrimaryPurpose: spdx3.PurposeLibrary,
+	PackageVersion: "1.0",
+	PackageURL:     "pkg:deb/ubuntu/test@1.0?arch=amd64",
+}
+
+var spdx3SampleSlice = &spdx3.Package{
+	Element: spdx3.Element{
+		Type:         spdx3.TypePackage,
+		SpdxID:       spdx3Id("Slice-test-slice-1196cbd5"),
+		CreationInfo: "_:creationinfo",
+		Name:         "test_slice",
+		Comment:      "This slice is a sub-package of the package test; see Relationship information.",
+	},
+	PrimaryPurpose: spdx3.PurposeLibrary,
+}
+
+func spdx3SampleRelationship(from, rel string, to ...string) *spdx3.Relationship {
+	r := &spdx3.Relationship{
+		Element: spdx3.Element{
+			Type:         spdx3.TypeRelationship,
+			SpdxID:       spdx3Id("Relationship-" + from + "-" + rel),
+			CreationInfo: "_:creationinfo",
+		},
+		From:             spdx3Id(from),
+		RelationshipType: rel,
+	}
+	for _, t := range to {
+		r.To = append(r.To, spdx3Id(t))
+	}
+	return r
+}
+
+var spdx3BuilderTests = []SPDX3BuilderTest{
+	{
+		summary:      "Builds package element",
+		packageInfos: testutil.SampleSinglePackage,
+		rootElements: []string{spdx3Id("Package-test")},
+		elements:     []any{spdx3SamplePackage},
+	}, {
+		summary:      "Builds slice element contained in its package",
+		packageInfos: testutil.SampleSinglePackage,
+		sliceInfos:   testutil.SampleSingleSlice,
+		rootElements: []string{spdx3Id("Package-test")},
+		elements: []any{
+			spdx3SamplePackage,
+			spdx3SampleSlice,
+			spdx3SampleRelationship("Package-test", "contains", "Slice-test-slice-1196cbd5"),
+		},
+	}, {
+		summary:      "Merges relationships with the same source and type",
+		packageInfos: testutil.SampleSinglePackage,
+		sliceInfos:   testutil.SampleSingleSlice,
+		pathInfos: append(testutil.SampleSinglePathNoFinalSHA256, builder.PathInfo{
+			Path:   "/test2",
+			Mode:   "0644",
+			Slices: []string{"test_slice"},
+			Link:   "/test",
+		}),
+		rootElements: []string{spdx3Id("Package-test")},
+		elements: []any{
+			spdx3SamplePackage,
+			spdx3SampleSlice,
+			spdx3SampleRelationship("Package-test", "contains", "Slice-test-slice-1196cbd5"),
+			&spdx3.File{
+				Element: spdx3.Element{
+					Type:         spdx3.TypeFile,
+					SpdxID:       spdx3Id("File-test-b306d9ff"),
+					CreationInfo: "_:creationinfo",
+					Name:         "/test",
+				},
+				VerifiedUsing:  []spdx3.Hash{spdx3.SHA256("sha256")},
+				PrimaryPurpose: spdx3.PurposeFile,
+			},
+			spdx3SampleRelationship("Slice-test-slice-1196cbd5", "contains", "File-test-b306d9ff", "File-test2-bdc482ad"),
+			&spdx3.File{
+				Element: spdx3.Element{
+					Type:         spdx3.TypeFile,
+					SpdxID:       spdx3Id("File-test2-bdc482ad"),
+					CreationInfo: "_:creationinfo",
+					Name:         "/test2",
+					Comment:      "This file is a symlink to the file /test.",
+				},
+				PrimaryPurpose: spdx3.PurposeFile,
+			},
+		},
+	}, {
+		summary:    "Builds modified file",
+		sliceInfos: testutil.SampleSingleSlice,
+		pathInfos:  testutil.SampleSinglePathModified,
+		elements: []any{
+			spdx3SampleSlice,
+			spdx3SampleRelationship("Package-test", "contains", "Slice-test-slice-1196cbd5"),
+			&spdx3.File{
+				Element: spdx3.Element{
+					Type:         spdx3.TypeFile,
+					SpdxID:       spdx3Id("File-test-b306d9ff"),
+					CreationInfo: "_:creationinfo",
+					Name:         "/test",
+				},
+				VerifiedUsing:  []spdx3.Hash{spdx3.SHA256("final_sha256")},
+				PrimaryPurpose: spdx3.PurposeFile,
+			},
+			spdx3SampleRelationship("File-test-b306d9ff", "modifiedBy", "Slice-test-slice-1196cbd5"),
+		},
+	}, {
+		summary:      "Builds operating system element",
+		osRelease:    testutil.SampleOSReleaseNoble,
+		rootElements: []string{spdx3Id("OperatingSystem-ubuntu-24.04")},
+		elements: []any{
+			&spdx3.Package{
+				Element: spdx3.Element{
+					Type:         spdx3.TypePackage,
+					SpdxID:       spdx3Id("OperatingSystem-ubuntu-24.04"),
+					CreationInfo: "_:creationinfo",
+					Name:         "ubuntu",
+					Comment:      "This package is the distribution of the rootfs.",
+				},
+				SuppliedBy:     spdx3Id("Organization-ubuntu"),
+				PrimaryPurpose: spdx3.PurposeOperatingSystem,
+				PackageVersion: "24.04",
+			},
+		},
+	}, {
+		summary: "Cannot build element for mutated symlink",
+		pathInfos: []builder.PathInfo{{
+			Path:        "/test",
+			FinalSHA256: "final_sha256",
+			Link:        "/file",
+		}},
+		error: "cannot build file section: invalid link: link /test has a final sha256",
+	},
+}
+
+func (s *S) TestSPDX3Builder(c *C) {
+	for _, test := range spdx3BuilderTests {
+		c.Logf("Running test: %s", test.summary)
+		doc, err := builder.BuildSPDX3Document(spdx3TestNamespace, test.osRelease, &test.sliceInfos, &test.packageInfos, &test.pathInfos)
+		if test.error != "" {
+			c.Assert(err, ErrorMatches, test.error)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Assert(doc.Context, Equals, spdx3.Context)
+
+		// The graph starts with the creation info, the document and the
+		// three agents, followed by the elements under test.
+		creationInfo := doc.Graph[0].(*spdx3.CreationInfo)
+		_, err = time.Parse(time.RFC3339, creationInfo.Created)
+		c.Assert(err, IsNil)
+		c.Assert(creationInfo.SpecVersion, Equals, spdx3.SpecVersion)
+		c.Assert(creationInfo.CreatedBy, DeepEquals, []string{spdx3Id("SoftwareAgent-ssbom")})
+
+		spdxDoc := doc.Graph[1].(*spdx3.SpdxDocument)
+		c.Assert(spdxDoc.SpdxID, Equals, spdx3Id("DOCUMENT"))
+		c.Assert(spdxDoc.RootElement, DeepEquals, test.rootElements)
+		c.Assert(spdxDoc.Elements, HasLen, len(doc.Graph)-2)
+
+		c.Assert(doc.Graph[5:], HasLen, len(test.elements))
+		for i, element := range test.elements {
+			c.Assert(doc.Graph[5+i], DeepEquals, element)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// UnmanagedFileInfo describes a file of the rootfs that is not in the chisel
+// manifest.
+type UnmanagedFileInfo struct {
+	Path   string
+	Link   string
+	SHA1   string
+	SHA256 string
+}
+
+// UnmanagedContentId is the identifier of the synthetic package that contains
+// the unmanaged files.
+const UnmanagedContentId = "UnmanagedContent"
+
+const (
+	unmanagedContentName    = "unmanaged-content"
+	unmanagedContentComment = "This package groups the files of the rootfs that are not in the chisel manifest."
+	unmanagedFileComment    = "This file is not in the chisel manifest."
+)
+
+func (f *UnmanagedFileInfo) SPDXId() string {
+	return spdxId("File", f.Path)
+}
+
+func (f *UnmanagedFileInfo) comment() string {
+	if f.Link != "" {
+		return fmt.Sprintf(fileComments[FileLnk], f.Link)
+	}
+	return unmanagedFileComment
+}
+
+// AddUnmanagedSPDXFiles adds the unmanaged files to the document, contained
+// in a synthetic package described by the document.
+func AddUnmanagedSPDXFiles(doc *spdx.Document, fileInfos []UnmanagedFileInfo) {
+	if len(fileInfos) == 0 {
+		return
+	}
+	doc.Packages = append(doc.Packages, &spdx.Package{
+		PackageName:             unmanagedContentName,
+		PackageSPDXIdentifier:   common.ElementID(UnmanagedContentId),
+		PackageDownloadLocation: "NOASSERTION",
+		FilesAnalyzed:           false,
+		PackageComment:          unmanagedContentComment,
+	})
+	doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+		RefA:         common.MakeDocElementID("", "DOCUMENT"),
+		RefB:         common.MakeDocElementID("", UnmanagedContentId),
+		Relationship: "DESCRIBES",
+	})
+	for _, f := range fileInfos {
+		file := &spdx.File{
+			FileName:           f.Path,
+			FileSPDXIdentifier: common.ElementID(f.SPDXId()),
+			FileCopyrightText:  "NOASSERTION",
+			FileComment:        f.comment(),
+		}
+		if f.SHA256 != "" {
+			file.Checksums = []common.Checksum{
+				{Algorithm: common.SHA1, Value: f.SHA1},
+				{Algorithm: common.SHA256, Value: f.SHA256},
+			}
+		}
+		doc.Files = append(doc.Files, file)
+		doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+			RefA:                common.MakeDocElementID("", UnmanagedContentId),
+			RefB:                common.MakeDocElementID("", f.SPDXId()),
+			Relationship:        "CONTAINS",
+			RelationshipComment: fmt.Sprintf("File %s is not in the chisel manifest.", f.Path),
+		})
+	}
+}
+
+// AddUnmanagedCycloneDXFiles adds the unmanaged files to the BOM, as
+// sub-components of a synthetic top-level component.
+func AddUnmanagedCycloneDXFiles(bom *cyclonedx.BOM, fileInfos []UnmanagedFileInfo) {
+	if len(fileInfos) == 0 {
+		return
+	}
+	group := &cyclonedx.Component{
+		Type:        cyclonedx.TypeApplication,
+		BOMRef:      UnmanagedContentId,
+		Name:        unmanagedContentName,
+		Description: unmanagedContentComment,
+	}
+	for _, f := range fileInfos {
+		comp := &cyclonedx.Component{
+			Type:        cyclonedx.TypeFile,
+			BOMRef:      f.SPDXId(),
+			Name:        f.Path,
+			Description: f.comment(),
+		}
+		if f.SHA256 != "" {
+			comp.Hashes = []cyclonedx.Hash{
+				{Algorithm: cyclonedx.SHA1, Content: f.SHA1},
+				{Algorithm: cyclonedx.SHA256, Content: f.SHA256},
+			}
+		}
+		group.Components = append(group.Components, comp)
+	}
+	bom.Components = append(bom.Components, group)
+}
+
+// AddUnmanagedSPDX3Files adds the unmanaged files to the document, contained
+// in a synthetic root package.
+func AddUnmanagedSPDX3Files(doc *spdx3.Document, fileInfos []UnmanagedFileInfo) {
+	if len(fileInfos) == 0 {
+		return
+	}
+	b := extendSPDX3(doc)
+	if b == nil {
+		return
+	}
+	groupId := b.id(UnmanagedContentId)
+	b.add(groupId, &spdx3.Package{
+		Element: spdx3.Element{
+			Type:         spdx3.TypePackage,
+			SpdxID:       groupId,
+			CreationInfo: spdx3CreationInfoId,
+			Name:         unmanagedContentName,
+			Comment:      unmanagedContentComment,
+		},
+		PrimaryPurpose: spdx3.PurposeOther,
+	})
+	b.doc.RootElement = append(b.doc.RootElement, groupId)
+
+	for _, f := range fileInfos {
+		id := b.id(f.SPDXId())
+		file := &spdx3.File{
+			Element: spdx3.Element{
+				Type:         spdx3.TypeFile,
+				SpdxID:       id,
+				CreationInfo: spdx3CreationInfoId,
+				Name:         f.Path,
+				Comment:      f.comment(),
+			},
+			PrimaryPurpose: spdx3.PurposeFile,
+		}
+		if f.SHA256 != "" {
+			file.VerifiedUsing = []spdx3.Hash{spdx3.SHA1(f.SHA1), spdx3.SHA256(f.SHA256)}
+		}
+		b.add(id, file)
+		b.relate(UnmanagedContentId, spdx3.RelationshipContains, f.SPDXId())
+	}
+	b.flush(doc)
+}
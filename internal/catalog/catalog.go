@@ -0,0 +1,164 @@
+// Package catalog finds the packages of language ecosystems, such as Python
+// distributions, npm packages and Java archives, in a rootfs.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Package is a package of a language ecosystem found in a rootfs.
+type Package struct {
+	// Type is the package URL type of the ecosystem, such as "pypi".
+	Type string
+	// Namespace is the package URL namespace, such as the group of a Maven
+	// artifact or the scope of an npm package, if any.
+	Namespace string
+	Name      string
+	Version   string
+	// Location is the absolute path of the file that describes the package.
+	Location string
+}
+
+// Package URL types.
+const (
+	TypePyPI  = "pypi"
+	TypeNPM   = "npm"
+	TypeMaven = "maven"
+)
+
+// PURL returns the package URL of the package.
+// See https://github.com/package-url/purl-spec
+func (p *Package) PURL() string {
+	name := p.Name
+	if p.Type == TypePyPI {
+		name = normalizePythonName(name)
+	}
+	purl := "pkg:" + p.Type + "/"
+	if p.Namespace != "" {
+		purl += escapePURL(p.Namespace) + "/"
+	}
+	purl += escapePURL(name)
+	if p.Version != "" {
+		purl += "@" + escapePURL(p.Version)
+	}
+	return purl
+}
+
+// Cataloguer finds the packages of an ecosystem.
+type Cataloguer interface {
+	// Name identifies the cataloguer, as in "python".
+	Name() string
+	// Match reports whether the file at name, relative to the root of the
+	// rootfs, may describe packages.
+	Match(name string) bool
+	// Catalog returns the packages described by the content of the file at
+	// name.
+	Catalog(name string, data []byte) ([]*Package, error)
+}
+
+// Cataloguers returns every available cataloguer.
+func Cataloguers() []Cataloguer {
+	return []Cataloguer{Python{}, Node{}, Java{}}
+}
+
+// Lookup returns the available cataloguer with the given name.
+func Lookup(name string) (Cataloguer, error) {
+	for _, c := range Cataloguers() {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cataloguer %q", name)
+}
+
+// Match reports whether any of the cataloguers matches the file at name.
+func Match(cataloguers []Cataloguer, name string) bool {
+	for _, c := range cataloguers {
+		if c.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run walks fsys and returns the packages found by the cataloguers, sorted
+// by location, type and name. Files that cannot be catalogued are reported
+// as warnings rather than errors, as they do not prevent the others from
+// being catalogued.
+func Run(ctx context.Context, fsys fs.FS, cataloguers []Cataloguer) (packages []*Package, warnings []string, err error) {
+	err = fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		var data []byte
+		for _, c := range cataloguers {
+			if !c.Match(name) {
+				continue
+			}
+			if data == nil {
+				if data, err = fs.ReadFile(fsys, name); err != nil {
+					return err
+				}
+			}
+			found, err := c.Catalog(name, data)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("cannot catalog %s package at /%s: %v", c.Name(), name, err))
+				continue
+			}
+			for _, p := range found {
+				p.Location = "/" + name
+			}
+			packages = append(packages, found...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot catalog packages: %w", err)
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		a, b := packages[i], packages[j]
+		if a.Location != b.Location {
+			return a.Location < b.Location
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	return packages, warnings, nil
+}
+
+var pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePythonName normalizes a distribution name as in PEP 503.
+func normalizePythonName(name string) string {
+	return strings.ToLower(pythonNameSeparators.ReplaceAllString(name, "-"))
+}
+
+// escapePURL percent-encodes the characters of a package URL component that
+// are not unreserved. Slashes separate the segments of namespaces.
+func escapePURL(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package catalog_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing/fstest"
+
+	"github.com/canonical/ssbom/internal/catalog"
+	. "gopkg.in/check.v1"
+)
+
+func makeJar(c *C, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		c.Assert(err, IsNil)
+		_, err = f.Write([]byte(data))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(w.Close(), IsNil)
+	return buf.Bytes()
+}
+
+const pythonMetadata = `Metadata-Version: 2.1
+Name: PyYAML
+Version: 6.0.1
+Summary: YAML parser and emitter for Python
+
+Name: not-a-header
+`
+
+const jarManifest = "Manifest-Version: 1.0\r\nImplementation-Title: commons-\r\n lang\r\nImplementation-Version: 2.6\r\nImplementation-Vendor-Id: commons-lang\r\n\r\n"
+
+func (s *S) TestRun(c *C) {
+	fsys := fstest.MapFS{
+		"usr/lib/python3/dist-packages/PyYAML-6.0.1.dist-info/METADATA": {Data: []byte(pythonMetadata)},
+		"usr/lib/python3/dist-packages/broken-1.0.dist-info/METADATA":   {Data: []byte("Metadata-Version: 2.1\n")},
+		"usr/lib/python3/dist-packages/yaml/METADATA":                   {Data: []byte(pythonMetadata)},
+		"app/node_modules/@types/node/package.json":                     {Data: []byte(`{"name": "@types/node", "version": "20.1.0"}`)},
+		"app/node_modules/left-pad/package.json":                        {Data: []byte(`{"name": "left-pad", "version": "1.3.0"}`)},
+		"app/node_modules/left-pad/esm/package.json":                    {Data: []byte(`{"type": "module"}`)},
+		"app/node_modules/broken/package.json":                          {Data: []byte(`{`)},
+		"opt/app/lib/shaded.jar": {Data: makeJar(c, map[string]string{
+			"META-INF/MANIFEST.MF":                                 jarManifest,
+			"META-INF/maven/com.google.guava/guava/pom.properties": "# Generated\ngroupId=com.google.guava\nartifactId=guava\nversion=33.0.0-jre\n",
+			"META-INF/maven/org.slf4j/slf4j-api/pom.properties":    "groupId=org.slf4j\nartifactId=slf4j-api\nversion=2.0.9\n",
+			"META-INF/maven/org.example/incomplete/pom.properties": "groupId=org.example\n",
+		})},
+		"opt/app/lib/commons-lang.jar": {Data: makeJar(c, map[string]string{"META-INF/MANIFEST.MF": jarManifest})},
+		"opt/app/lib/broken.jar":       {Data: []byte("not a zip")},
+	}
+
+	packages, warnings, err := catalog.Run(context.Background(), fsys, catalog.Cataloguers())
+	c.Assert(err, IsNil)
+	var purls, locations []string
+	for _, p := range packages {
+		purls = append(purls, p.PURL())
+		locations = append(locations, p.Location)
+	}
+	c.Assert(purls, DeepEquals, []string{
+		"pkg:npm/%40types/node@20.1.0",
+		"pkg:npm/left-pad@1.3.0",
+		"pkg:maven/commons-lang/commons-lang@2.6",
+		"pkg:maven/com.google.guava/guava@33.0.0-jre",
+		"pkg:maven/org.slf4j/slf4j-api@2.0.9",
+		"pkg:pypi/pyyaml@6.0.1",
+	})
+	c.Assert(locations, DeepEquals, []string{
+		"/app/node_modules/@types/node/package.json",
+		"/app/node_modules/left-pad/package.json",
+		"/opt/app/lib/commons-lang.jar",
+		"/opt/app/lib/shaded.jar",
+		"/opt/app/lib/shaded.jar",
+		"/usr/lib/python3/dist-packages/PyYAML-6.0.1.dist-info/METADATA",
+	})
+	c.Assert(warnings, DeepEquals, []string{
+		"cannot catalog node package at /app/node_modules/broken/package.json: unexpected end of JSON input",
+		"cannot catalog java package at /opt/app/lib/broken.jar: zip: not a valid zip file",
+		"cannot catalog python package at /usr/lib/python3/dist-packages/broken-1.0.dist-info/METADATA: no Name or Version in metadata",
+	})
+
+	python, err := catalog.Lookup("python")
+	c.Assert(err, IsNil)
+	packages, _, err = catalog.Run(context.Background(), fsys, []catalog.Cataloguer{python})
+	c.Assert(err, IsNil)
+	c.Assert(packages, HasLen, 1)
+	c.Assert(*packages[0], DeepEquals, catalog.Package{
+		Type:     catalog.TypePyPI,
+		Name:     "PyYAML",
+		Version:  "6.0.1",
+		Location: "/usr/lib/python3/dist-packages/PyYAML-6.0.1.dist-info/METADATA",
+	})
+
+	_, err = catalog.Lookup("rust")
+	c.Assert(err, ErrorMatches, `unknown cataloguer "rust"`)
+}
+
+func (s *S) TestPURL(c *C) {
+	for _, test := range []struct {
+		pkg  catalog.Package
+		purl string
+	}{
+		{catalog.Package{Type: catalog.TypePyPI, Name: "Foo_Bar.baz", Version: "1.0"}, "pkg:pypi/foo-bar-baz@1.0"},
+		{catalog.Package{Type: catalog.TypeNPM, Namespace: "@angular", Name: "core", Version: "17.0.0+build"}, "pkg:npm/%40angular/core@17.0.0%2Bbuild"},
+		{catalog.Package{Type: catalog.TypeMaven, Name: "plain"}, "pkg:maven/plain"},
+	} {
+		c.Assert(test.pkg.PURL(), Equals, test.purl)
+	}
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Java finds the Maven artifacts of Java archives. Every pom.properties file
+// of an archive describes an artifact, as shaded archives hold several. The
+// manifest of archives without any describes the archive itself.
+type Java struct{}
+
+func (Java) Name() string { return "java" }
+
+func (Java) Match(name string) bool {
+	switch path.Ext(name) {
+	case ".jar", ".war", ".ear":
+		return true
+	}
+	return false
+}
+
+func (Java) Catalog(name string, data []byte) ([]*Package, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var packages []*Package
+	var manifest []byte
+	for _, f := range r.File {
+		switch {
+		case f.Name == "META-INF/MANIFEST.MF":
+			if manifest, err = readZipFile(f); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(f.Name, "META-INF/maven/") && path.Base(f.Name) == "pom.properties":
+			props, err := readZipFile(f)
+			if err != nil {
+				return nil, err
+			}
+			if p := parsePomProperties(props); p != nil {
+				packages = append(packages, p)
+			}
+		}
+	}
+	if len(packages) > 0 {
+		return packages, nil
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("no pom.properties or manifest in archive")
+	}
+
+	headers := readHeaders(manifest)
+	p := &Package{
+		Type:      TypeMaven,
+		Namespace: headers["Implementation-Vendor-Id"],
+		Name:      headers["Implementation-Title"],
+		Version:   headers["Implementation-Version"],
+	}
+	if p.Name == "" {
+		p.Name, _, _ = strings.Cut(headers["Bundle-SymbolicName"], ";")
+	}
+	if p.Version == "" {
+		p.Version = headers["Bundle-Version"]
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	if p.Version == "" {
+		return nil, fmt.Errorf("no version in archive manifest")
+	}
+	return []*Package{p}, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// parsePomProperties returns the artifact described by a pom.properties
+// file, or nil if it is incomplete.
+func parsePomProperties(data []byte) *Package {
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if props["artifactId"] == "" || props["version"] == "" {
+		return nil
+	}
+	return &Package{
+		Type:      TypeMaven,
+		Namespace: props["groupId"],
+		Name:      props["artifactId"],
+		Version:   props["version"],
+	}
+}
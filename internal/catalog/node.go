@@ -0,0 +1,37 @@
+package catalog
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// Node finds the npm packages described by package.json files, such as the
+// ones installed in node_modules directories.
+type Node struct{}
+
+func (Node) Name() string { return "node" }
+
+func (Node) Match(name string) bool {
+	return path.Base(name) == "package.json"
+}
+
+func (Node) Catalog(name string, data []byte) ([]*Package, error) {
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, err
+	}
+	if pkg.Name == "" || pkg.Version == "" {
+		// Files that only configure a directory, such as the ones setting
+		// "type": "module" in a package, are not packages.
+		return nil, nil
+	}
+	p := &Package{Type: TypeNPM, Name: pkg.Name, Version: pkg.Version}
+	if scope, name, ok := strings.Cut(pkg.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+		p.Namespace, p.Name = scope, name
+	}
+	return []*Package{p}, nil
+}
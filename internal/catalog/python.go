@@ -0,0 +1,61 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Python finds the Python distributions installed in site-packages or
+// dist-packages directories, described by their .dist-info/METADATA files.
+type Python struct{}
+
+func (Python) Name() string { return "python" }
+
+func (Python) Match(name string) bool {
+	return path.Base(name) == "METADATA" && strings.HasSuffix(path.Dir(name), ".dist-info")
+}
+
+func (Python) Catalog(name string, data []byte) ([]*Package, error) {
+	headers := readHeaders(data)
+	if headers["Name"] == "" || headers["Version"] == "" {
+		return nil, fmt.Errorf("no Name or Version in metadata")
+	}
+	return []*Package{{Type: TypePyPI, Name: headers["Name"], Version: headers["Version"]}}, nil
+}
+
+// readHeaders reads the headers of an RFC 822 style document, such as Python
+// core metadata or a jar manifest, up to the first empty line. Continuation
+// lines start with a space. Only the first value of repeated headers is kept.
+func readHeaders(data []byte) map[string]string {
+	headers := make(map[string]string)
+	var last string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if last != "" {
+				headers[last] += line[1:]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			last = ""
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, ok := headers[key]; ok {
+			last = ""
+			continue
+		}
+		headers[key] = strings.TrimSpace(value)
+		last = key
+	}
+	return headers
+}
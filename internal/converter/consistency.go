@@ -0,0 +1,159 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Inconsistency is a disagreement between the entries of a manifest.
+type Inconsistency struct {
+	// Kind and Name identify the offending entry. Content entries are named
+	// after their slice and path.
+	Kind    string
+	Name    string
+	Message string
+}
+
+func (i *Inconsistency) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Kind, i.Name, i.Message)
+}
+
+// CheckConsistency checks that the entries of the manifest agree with each
+// other, as chisel guarantees when it writes them, and returns every
+// inconsistency found.
+func (md *ManifestData) CheckConsistency() []*Inconsistency {
+	var found []*Inconsistency
+	report := func(kind, name, format string, args ...any) {
+		found = append(found, &Inconsistency{Kind: kind, Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Packages of different architectures may have the same name.
+	packages := make(map[string]bool)
+	for _, p := range md.Packages {
+		if packages[p.Name+":"+p.Arch] {
+			report("package", p.Name, "duplicated entry")
+		}
+		packages[p.Name+":"+p.Arch] = true
+	}
+
+	archs := md.packageArchs()
+	sliceNames := make(map[string]bool)
+	packagesWithSlices := make(map[string]bool)
+	for _, s := range md.Slices {
+		if sliceNames[s.Name] {
+			report("slice", s.Name, "duplicated entry")
+		}
+		sliceNames[s.Name] = true
+		if pkg, _, ok := strings.Cut(s.Name, "_"); !ok || pkg == "" {
+			report("slice", s.Name, "name is not in the <package>_<slice> form")
+			continue
+		}
+		pkg, arch, ambiguous := archs.slicePackage(s.Name)
+		if ambiguous {
+			// The slice may belong to any of the packages with the name.
+			for _, arch := range archs.byName[pkg] {
+				packagesWithSlices[pkg+":"+arch] = true
+			}
+			continue
+		}
+		packagesWithSlices[pkg+":"+arch] = true
+		if !packages[pkg+":"+arch] {
+			report("slice", s.Name, "package %s has no package entry", qualifiedName(pkg, arch))
+		}
+	}
+	for _, p := range md.Packages {
+		if !packagesWithSlices[p.Name+":"+p.Arch] {
+			name := p.Name
+			if len(archs.byName[p.Name]) > 1 {
+				name = qualifiedName(p.Name, p.Arch)
+			}
+			report("package", name, "no slice entry for this package")
+		}
+	}
+
+	type contentKey struct{ slice, path string }
+	content := make(map[contentKey]bool)
+	for _, c := range md.Content {
+		key := contentKey{c.Slice, c.Path}
+		name := c.Slice + ":" + c.Path
+		if content[key] {
+			report("content", name, "duplicated entry")
+		}
+		content[key] = true
+	}
+
+	paths := make(map[string][]string)
+	hardLinks := make(map[uint64][]int)
+	for i, p := range md.Paths {
+		if _, ok := paths[p.Path]; ok {
+			report("path", p.Path, "duplicated entry")
+		}
+		paths[p.Path] = p.Slices
+		if len(p.Slices) == 0 {
+			report("path", p.Path, "no slices")
+		}
+		for _, s := range p.Slices {
+			if !sliceNames[s] {
+				report("path", p.Path, "slice %s has no slice entry", s)
+			}
+			if !content[contentKey{s, p.Path}] {
+				report("path", p.Path, "no content entry for slice %s", s)
+			}
+		}
+		isDir := strings.HasSuffix(p.Path, "/")
+		switch {
+		case isDir && (p.SHA256 != "" || p.FinalSHA256 != "" || p.Size != 0 || p.Link != ""):
+			report("path", p.Path, "directory has a link, a digest or a size")
+		case p.Link != "" && (p.SHA256 != "" || p.FinalSHA256 != "" || p.Size != 0):
+			report("path", p.Path, "symlink has a digest or a size")
+		}
+		if p.Inode != 0 {
+			hardLinks[p.Inode] = append(hardLinks[p.Inode], i)
+		}
+	}
+
+	inodes := make([]uint64, 0, len(hardLinks))
+	for inode := range hardLinks {
+		inodes = append(inodes, inode)
+	}
+	slices.Sort(inodes)
+	for _, inode := range inodes {
+		group := hardLinks[inode]
+		first := &md.Paths[group[0]]
+		if len(group) == 1 {
+			report("path", first.Path, "only path of hard link group %d", inode)
+			continue
+		}
+		for _, i := range group[1:] {
+			p := &md.Paths[i]
+			if p.Mode != first.Mode || p.SHA256 != first.SHA256 || p.FinalSHA256 != first.FinalSHA256 || p.Size != first.Size || p.Link != first.Link {
+				report("path", p.Path, "content differs from %s in hard link group %d", first.Path, inode)
+			}
+		}
+	}
+
+	for _, c := range md.Content {
+		name := c.Slice + ":" + c.Path
+		if !sliceNames[c.Slice] {
+			report("content", name, "slice %s has no slice entry", c.Slice)
+		}
+		pathSlices, ok := paths[c.Path]
+		switch {
+		case !ok:
+			report("content", name, "path %s has no path entry", c.Path)
+		case !slices.Contains(pathSlices, c.Slice):
+			report("content", name, "path %s is not in slice %s", c.Path, c.Slice)
+		}
+	}
+	return found
+}
+
+// qualifiedName returns the name of a package qualified with its
+// architecture, as in "libc6:i386", if known.
+func qualifiedName(name, arch string) string {
+	if arch == "" {
+		return name
+	}
+	return name + ":" + arch
+}
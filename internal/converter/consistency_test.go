@@ -0,0 +1,132 @@
+package converter_test
+
+import (
+	"github.com/canonical/chisel/public/manifest"
+	"github.com/canonical/ssbom/internal/converter"
+	. "gopkg.in/check.v1"
+)
+
+var consistencyTests = []struct {
+	summary      string
+	manifestData converter.ManifestData
+	issues       []string
+}{{
+	summary: "Consistent manifest",
+	manifestData: converter.ManifestData{
+		Packages: []manifest.Package{{Kind: "package", Name: "pkg", Version: "1.0", Digest: "aaaa", Arch: "amd64"}},
+		Slices:   []manifest.Slice{{Kind: "slice", Name: "pkg_bins"}},
+		Paths: []manifest.Path{
+			{Kind: "path", Path: "/bin/", Mode: "0755", Slices: []string{"pkg_bins"}},
+			{Kind: "path", Path: "/bin/a", Mode: "0755", Slices: []string{"pkg_bins"}, SHA256: "bbbb", Size: 1, Inode: 1},
+			{Kind: "path", Path: "/bin/b", Mode: "0755", Slices: []string{"pkg_bins"}, SHA256: "bbbb", Size: 1, Inode: 1},
+			{Kind: "path", Path: "/bin/c", Mode: "0777", Slices: []string{"pkg_bins"}, Link: "a"},
+		},
+		Content: []manifest.Content{
+			{Kind: "content", Slice: "pkg_bins", Path: "/bin/"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/bin/a"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/bin/b"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/bin/c"},
+		},
+	},
+}, {
+	summary: "Packages of several architectures",
+	manifestData: converter.ManifestData{
+		Packages: []manifest.Package{
+			{Kind: "package", Name: "libc6", Version: "2.39", Digest: "aaaa", Arch: "amd64"},
+			{Kind: "package", Name: "libc6", Version: "2.39", Digest: "bbbb", Arch: "i386"},
+		},
+		Slices: []manifest.Slice{{Kind: "slice", Name: "libc6_libs"}},
+		Paths: []manifest.Path{
+			{Kind: "path", Path: "/usr/lib/x86_64-linux-gnu/libc.so.6", Mode: "0644", Slices: []string{"libc6_libs"}, SHA256: "cccc", Size: 1},
+		},
+		Content: []manifest.Content{
+			{Kind: "content", Slice: "libc6_libs", Path: "/usr/lib/x86_64-linux-gnu/libc.so.6"},
+		},
+	},
+	issues: []string{
+		"package libc6:i386: no slice entry for this package",
+	},
+}, {
+	summary: "Missing packages, slices and paths",
+	manifestData: converter.ManifestData{
+		Packages: []manifest.Package{{Kind: "package", Name: "unused"}},
+		Slices:   []manifest.Slice{{Kind: "slice", Name: "pkg_bins"}, {Kind: "slice", Name: "invalid"}},
+		Paths: []manifest.Path{
+			{Kind: "path", Path: "/bin/a", Slices: []string{"pkg_bins", "pkg_libs"}},
+			{Kind: "path", Path: "/bin/b"},
+		},
+		Content: []manifest.Content{
+			{Kind: "content", Slice: "pkg_bins", Path: "/bin/a"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/bin/c"},
+			{Kind: "content", Slice: "other_bins", Path: "/bin/b"},
+		},
+	},
+	issues: []string{
+		"slice pkg_bins: package pkg has no package entry",
+		"slice invalid: name is not in the <package>_<slice> form",
+		"package unused: no slice entry for this package",
+		"path /bin/a: slice pkg_libs has no slice entry",
+		"path /bin/a: no content entry for slice pkg_libs",
+		"path /bin/b: no slices",
+		"content pkg_bins:/bin/c: path /bin/c has no path entry",
+		"content other_bins:/bin/b: slice other_bins has no slice entry",
+		"content other_bins:/bin/b: path /bin/b is not in slice other_bins",
+	},
+}, {
+	summary: "Duplicated entries",
+	manifestData: converter.ManifestData{
+		Packages: []manifest.Package{{Kind: "package", Name: "pkg"}, {Kind: "package", Name: "pkg"}},
+		Slices:   []manifest.Slice{{Kind: "slice", Name: "pkg_bins"}, {Kind: "slice", Name: "pkg_bins"}},
+		Paths: []manifest.Path{
+			{Kind: "path", Path: "/a", Slices: []string{"pkg_bins"}},
+			{Kind: "path", Path: "/a", Slices: []string{"pkg_bins"}},
+		},
+		Content: []manifest.Content{
+			{Kind: "content", Slice: "pkg_bins", Path: "/a"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/a"},
+		},
+	},
+	issues: []string{
+		"package pkg: duplicated entry",
+		"slice pkg_bins: duplicated entry",
+		"content pkg_bins:/a: duplicated entry",
+		"path /a: duplicated entry",
+	},
+}, {
+	summary: "Invalid file types and hard links",
+	manifestData: converter.ManifestData{
+		Packages: []manifest.Package{{Kind: "package", Name: "pkg"}},
+		Slices:   []manifest.Slice{{Kind: "slice", Name: "pkg_bins"}},
+		Paths: []manifest.Path{
+			{Kind: "path", Path: "/a", Mode: "0644", Slices: []string{"pkg_bins"}, SHA256: "aaaa", Inode: 1},
+			{Kind: "path", Path: "/b", Mode: "0644", Slices: []string{"pkg_bins"}, SHA256: "bbbb", Inode: 1},
+			{Kind: "path", Path: "/c", Mode: "0644", Slices: []string{"pkg_bins"}, Inode: 2},
+			{Kind: "path", Path: "/d/", Mode: "0755", Slices: []string{"pkg_bins"}, Size: 1},
+			{Kind: "path", Path: "/e", Mode: "0777", Slices: []string{"pkg_bins"}, Link: "a", SHA256: "aaaa"},
+		},
+		Content: []manifest.Content{
+			{Kind: "content", Slice: "pkg_bins", Path: "/a"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/b"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/c"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/d/"},
+			{Kind: "content", Slice: "pkg_bins", Path: "/e"},
+		},
+	},
+	issues: []string{
+		"path /d/: directory has a link, a digest or a size",
+		"path /e: symlink has a digest or a size",
+		"path /b: content differs from /a in hard link group 1",
+		"path /c: only path of hard link group 2",
+	},
+}}
+
+func (s *S) TestCheckConsistency(c *C) {
+	for _, test := range consistencyTests {
+		c.Logf("Running test: %s", test.summary)
+		var issues []string
+		for _, inconsistency := range test.manifestData.CheckConsistency() {
+			issues = append(issues, inconsistency.String())
+		}
+		c.Assert(issues, DeepEquals, test.issues)
+	}
+}
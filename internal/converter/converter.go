@@ -1,38 +1,108 @@
 package converter
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/canonical/chisel/public/jsonwall"
 	"github.com/canonical/chisel/public/manifest"
+	"github.com/canonical/ssbom/internal/apt"
 	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/internal/copyright"
+	"github.com/canonical/ssbom/internal/cpe"
+	"github.com/canonical/ssbom/internal/osrelease"
+	"github.com/canonical/ssbom/internal/pro"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/klauspost/compress/zstd"
 	"github.com/spdx/tools-golang/spdx"
 )
 
-// Convert converts a JSONWall to an SPDX document.
-func Convert(reader io.Reader, distro string) (*spdx.Document, error) {
-	db, err := jsonwall.ReadDB(reader)
+// Convert converts a JSONWall to an SPDX document. The JSONWall may be zstd
+// compressed. It fails if any entry of the JSONWall cannot be read. The
+// os-release of the rootfs, if known, describes its distribution.
+func Convert(reader io.Reader, osRelease *osrelease.OSRelease) (*spdx.Document, error) {
+	manifestData, err := readStrict(reader, osRelease)
 	if err != nil {
-		return nil, fmt.Errorf("cannot read manifest: %s", err)
+		return nil, err
 	}
+	return manifestData.BuildSPDX()
+}
 
-	manifestData := &ManifestData{Distro: distro}
-	for _, fn := range updateFunctions {
-		fn(db, manifestData)
+// ConvertCycloneDX converts a JSONWall to a CycloneDX BOM.
+func ConvertCycloneDX(reader io.Reader, osRelease *osrelease.OSRelease) (*cyclonedx.BOM, error) {
+	manifestData, err := readStrict(reader, osRelease)
+	if err != nil {
+		return nil, err
 	}
+	return manifestData.BuildCycloneDX()
+}
 
-	sliceInfos := manifestData.ProcessSlices()
-	packageInfos := manifestData.ProcessPackages()
-	pathInfos := manifestData.ProcessPaths()
+// ConvertSPDX3 converts a JSONWall to an SPDX 3.0 document.
+func ConvertSPDX3(reader io.Reader, osRelease *osrelease.OSRelease) (*spdx3.Document, error) {
+	manifestData, err := readStrict(reader, osRelease)
+	if err != nil {
+		return nil, err
+	}
+	return manifestData.BuildSPDX3()
+}
+
+// ReadManifest reads a JSONWall manifest, which may be zstd compressed.
+// Entries that cannot be read are skipped and recorded in ReadErrors.
+func ReadManifest(reader io.Reader) (*ManifestData, error) {
+	return readManifestData(reader, nil)
+}
 
-	doc, err := builder.BuildSPDXDocument(manifestData.Distro, &sliceInfos, &packageInfos, &pathInfos)
+func readStrict(reader io.Reader, osRelease *osrelease.OSRelease) (*ManifestData, error) {
+	manifestData, err := readManifestData(reader, osRelease)
 	if err != nil {
 		return nil, err
 	}
+	if err := manifestData.Err(); err != nil {
+		return nil, err
+	}
+	return manifestData, nil
+}
 
-	return doc, nil
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// readManifestData reads a jsonwall manifest, which may be zstd compressed
+// as chisel writes it, or not compressed.
+func readManifestData(reader io.Reader, osRelease *osrelease.OSRelease) (*ManifestData, error) {
+	br := bufio.NewReader(reader)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("cannot read manifest: %s", err)
+	}
+	reader = br
+	if bytes.Equal(magic, zstdMagic) {
+		zstdReader, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read manifest: %s", err)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	}
+
+	db, err := jsonwall.ReadDB(reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read manifest: %s", err)
+	}
+
+	manifestData := &ManifestData{OSRelease: osRelease}
+	for _, fn := range updateFunctions {
+		if err := fn(db, manifestData); err != nil {
+			return nil, err
+		}
+	}
+	return manifestData, nil
 }
 
 type ManifestData struct {
@@ -40,33 +110,362 @@ type ManifestData struct {
 	Slices   []manifest.Slice
 	Paths    []manifest.Path
 	Content  []manifest.Content
-	Distro   string
+	// OSRelease describes the distribution of the rootfs, if known.
+	OSRelease *osrelease.OSRelease
+	// Copyrights holds the machine-readable copyright files of the
+	// packages, by package name.
+	Copyrights map[string]*copyright.Copyright
+	// Archive holds the Packages and Sources indexes of the apt archive the
+	// packages come from, if known.
+	Archive *apt.Index
+	// ArchiveURL is the URL of the apt archive, used to build the download
+	// location of the packages. It defaults to the Debian archive for
+	// Debian, and to the Ubuntu archive of the architecture of each package
+	// otherwise.
+	ArchiveURL string
+	// CPEMap overrides the entries of the bundled table of the CPE
+	// vendors and products of packages.
+	CPEMap cpe.Table
+	// ProLabel is the Ubuntu Pro pocket the ESM or FIPS packages, as told
+	// by their versions, come from. See pro.Detect.
+	ProLabel pro.Pocket
+	// EcosystemPackages, Unmanaged and GoBinaries describe the content of
+	// the rootfs found besides the manifest: the packages of other
+	// ecosystems, the files no slice installed and the Go executables.
+	EcosystemPackages []builder.EcosystemPackageInfo
+	Unmanaged         []builder.UnmanagedFileInfo
+	GoBinaries        []builder.GoBinaryInfo
+	// Document describes the generated document and how it was created.
+	Document builder.DocumentInfo
+	// ReadErrors lists the entries of the manifest that could not be read.
+	ReadErrors []*EntryError
+}
+
+// EntryError records an entry of a manifest that could not be read.
+type EntryError struct {
+	// Manifest is the path of the manifest, if known.
+	Manifest string
+	// Kind is the kind of the entry: package, slice, path or content.
+	Kind string
+	// Position is the position of the entry among those of its kind,
+	// starting at 1.
+	Position int
+	Err      error
+}
+
+func (e *EntryError) Error() string {
+	if e.Manifest != "" {
+		return fmt.Sprintf("cannot read %s entry %d of %s: %v", e.Kind, e.Position, e.Manifest, e.Err)
+	}
+	return fmt.Sprintf("cannot read %s entry %d: %v", e.Kind, e.Position, e.Err)
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// Err returns an error listing the entries that could not be read, or nil if
+// there are none.
+func (md *ManifestData) Err() error {
+	switch len(md.ReadErrors) {
+	case 0:
+		return nil
+	case 1:
+		return md.ReadErrors[0]
+	}
+	msgs := make([]string, len(md.ReadErrors))
+	for i, err := range md.ReadErrors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Errorf("cannot read %d manifest entries:\n- %s", len(md.ReadErrors), strings.Join(msgs, "\n- "))
+}
+
+// readErrorsAnnotation returns the annotation recording the entries that could
+// not be read and are thus missing from documents.
+func (md *ManifestData) readErrorsAnnotation() string {
+	msgs := make([]string, len(md.ReadErrors))
+	for i, err := range md.ReadErrors {
+		msgs[i] = err.Error()
+	}
+	return "This document is incomplete: the following manifest entries could not be read: " + strings.Join(msgs, "; ")
+}
+
+// Digest returns the hex-encoded sha256 digest of the entries of the
+// manifest and of the os-release, which identifies the content described by
+// the documents built from the manifest data.
+func (md *ManifestData) Digest() string {
+	h := sha256.New()
+	e := json.NewEncoder(h)
+	for _, v := range []any{md.Packages, md.Slices, md.Paths, md.Content, md.OSRelease} {
+		// The values only hold strings, numbers and slices of those.
+		if err := e.Encode(v); err != nil {
+			panic(fmt.Sprintf("internal error: cannot encode manifest data: %v", err))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// BuildSPDX builds an SPDX document from the manifest data. If some entries
+// could not be read, the document is annotated with them. Its elements are
+// sorted.
+func (md *ManifestData) BuildSPDX() (*spdx.Document, error) {
+	sliceInfos := md.ProcessSlices()
+	packageInfos := md.ProcessPackages()
+	pathInfos := md.ProcessPaths()
+	doc, err := builder.BuildSPDXDocument(md.OSRelease, &sliceInfos, &packageInfos, &pathInfos)
+	if err != nil {
+		return nil, err
+	}
+	builder.AddSPDXLicenseRefs(doc, md.licenseRefs())
+	builder.AddEcosystemSPDXPackages(doc, md.EcosystemPackages)
+	builder.AddUnmanagedSPDXFiles(doc, md.Unmanaged)
+	builder.AddGoModulesSPDX(doc, md.GoBinaries)
+	builder.SetSPDXDocumentInfo(doc, md.Document)
+	if len(md.ReadErrors) > 0 {
+		builder.AnnotateSPDXDocument(doc, md.readErrorsAnnotation())
+	}
+	builder.SortSPDXDocument(doc)
+	return doc, nil
+}
+
+// BuildCycloneDX builds a CycloneDX BOM from the manifest data. If some
+// entries could not be read, the BOM is annotated with them.
+func (md *ManifestData) BuildCycloneDX() (*cyclonedx.BOM, error) {
+	sliceInfos := md.ProcessSlices()
+	packageInfos := md.ProcessPackages()
+	pathInfos := md.ProcessPaths()
+	bom, err := builder.BuildCycloneDXDocument(md.OSRelease, &sliceInfos, &packageInfos, &pathInfos)
+	if err != nil {
+		return nil, err
+	}
+	builder.AddEcosystemCycloneDXComponents(bom, md.EcosystemPackages)
+	builder.AddUnmanagedCycloneDXFiles(bom, md.Unmanaged)
+	builder.AddGoModulesCycloneDX(bom, md.GoBinaries)
+	builder.SetCycloneDXDocumentInfo(bom, md.Document)
+	if len(md.ReadErrors) > 0 {
+		builder.AnnotateCycloneDXDocument(bom, md.readErrorsAnnotation())
+	}
+	return bom, nil
+}
+
+// BuildSPDX3 builds an SPDX 3.0 document from the manifest data. If some
+// entries could not be read, the document is annotated with them.
+func (md *ManifestData) BuildSPDX3() (*spdx3.Document, error) {
+	sliceInfos := md.ProcessSlices()
+	packageInfos := md.ProcessPackages()
+	pathInfos := md.ProcessPaths()
+	info := md.Document
+	info.Namespace = info.DocumentNamespace()
+	doc, err := builder.BuildSPDX3Document(info.Namespace, md.OSRelease, &sliceInfos, &packageInfos, &pathInfos)
+	if err != nil {
+		return nil, err
+	}
+	builder.AddEcosystemSPDX3Packages(doc, md.EcosystemPackages)
+	builder.AddUnmanagedSPDX3Files(doc, md.Unmanaged)
+	builder.AddGoModulesSPDX3(doc, md.GoBinaries)
+	builder.SetSPDX3DocumentInfo(doc, info)
+	if len(md.ReadErrors) > 0 {
+		builder.AnnotateSPDX3Document(doc, md.readErrorsAnnotation())
+	}
+	return doc, nil
 }
 
 func (md *ManifestData) ProcessSlices() []builder.SliceInfo {
 	var sliceInfos []builder.SliceInfo
+	archs := md.packageArchs()
 	for _, s := range md.Slices {
 		var sliceInfo builder.SliceInfo
 		sliceInfo.Name = s.Name
+		if name, arch, _ := archs.slicePackage(s.Name); len(archs.byName[name]) > 1 {
+			sliceInfo.Arch = arch
+		}
 		sliceInfos = append(sliceInfos, sliceInfo)
 	}
 	return sliceInfos
 }
 
+// archIndex holds the architectures of the packages, by name, the
+// architectures of the multiarch directories the slices have content in, by
+// slice, and the native architecture of the rootfs.
+type archIndex struct {
+	byName  map[string][]string
+	bySlice map[string][]string
+	native  string
+}
+
+// multiarchTuples maps the multiarch tuples found in paths, as in
+// /usr/lib/x86_64-linux-gnu, to their Debian architectures.
+var multiarchTuples = map[string]string{
+	"x86_64-linux-gnu":      "amd64",
+	"i386-linux-gnu":        "i386",
+	"aarch64-linux-gnu":     "arm64",
+	"arm-linux-gnueabihf":   "armhf",
+	"arm-linux-gnueabi":     "armel",
+	"powerpc64le-linux-gnu": "ppc64el",
+	"riscv64-linux-gnu":     "riscv64",
+	"s390x-linux-gnu":       "s390x",
+}
+
+// pathArch returns the architecture of the multiarch directory of the path,
+// if any.
+func pathArch(path string) string {
+	for _, dir := range strings.Split(path, "/") {
+		if arch, ok := multiarchTuples[dir]; ok {
+			return arch
+		}
+	}
+	return ""
+}
+
+// packageArchs returns the architectures of the packages and slices. The
+// native architecture is the one of most packages, other than "all".
+func (md *ManifestData) packageArchs() *archIndex {
+	archs := &archIndex{
+		byName:  make(map[string][]string),
+		bySlice: make(map[string][]string),
+	}
+	for _, c := range md.Content {
+		if arch := pathArch(c.Path); arch != "" && !slices.Contains(archs.bySlice[c.Slice], arch) {
+			archs.bySlice[c.Slice] = append(archs.bySlice[c.Slice], arch)
+		}
+	}
+	count := make(map[string]int)
+	for _, p := range md.Packages {
+		if !slices.Contains(archs.byName[p.Name], p.Arch) {
+			archs.byName[p.Name] = append(archs.byName[p.Name], p.Arch)
+		}
+		if p.Arch == "all" {
+			continue
+		}
+		count[p.Arch]++
+		if n := count[p.Arch]; archs.native == "" || n > count[archs.native] || n == count[archs.native] && p.Arch < archs.native {
+			archs.native = p.Arch
+		}
+	}
+	return archs
+}
+
+// slicePackage returns the name and architecture of the package of a slice.
+// Slices are named after their package only, as in "libc6_libs", so when
+// packages of several architectures have the name, the slice belongs to the
+// one of the multiarch directories it has content in, as in
+// /usr/lib/i386-linux-gnu. If that is not a single one of them, ambiguous is
+// true and the slice is taken to belong to the package of the native
+// architecture, if there is one, or to the first package with the name.
+func (a *archIndex) slicePackage(slice string) (name, arch string, ambiguous bool) {
+	name, _, _ = strings.Cut(slice, "_")
+	archs := a.byName[name]
+	switch len(archs) {
+	case 0:
+		return name, "", false
+	case 1:
+		return name, archs[0], false
+	}
+	if sliceArchs := a.bySlice[slice]; len(sliceArchs) == 1 && slices.Contains(archs, sliceArchs[0]) {
+		return name, sliceArchs[0], false
+	}
+	if slices.Contains(archs, a.native) {
+		return name, a.native, true
+	}
+	return name, archs[0], true
+}
+
 func (md *ManifestData) ProcessPackages() []builder.PackageInfo {
 	var packageInfos []builder.PackageInfo
+	cpeTable := cpe.Bundled().With(md.CPEMap)
+	archs := md.packageArchs()
 	for _, p := range md.Packages {
 		var packageInfo builder.PackageInfo
 		packageInfo.Name = p.Name
 		packageInfo.Version = p.Version
 		packageInfo.SHA256 = p.Digest
 		packageInfo.Arch = p.Arch
-		packageInfo.Distro = md.Distro
+		packageInfo.MultiArch = len(archs.byName[p.Name]) > 1
+		packageInfo.OS = md.OSRelease
+		if c, ok := md.Copyrights[p.Name]; ok {
+			packageInfo.License, _ = c.SPDXLicense()
+		}
+		md.fillArchiveInfo(&packageInfo)
+		if pocket := md.proPocket(&packageInfo); pocket != "" {
+			packageInfo.ProPocket = string(pocket)
+			packageInfo.RepositoryURL = pocket.RepositoryURL()
+		}
+		packageInfo.CPEs = cpeTable.CPEs(p.Name, packageInfo.SourceName, p.Version)
 		packageInfos = append(packageInfos, packageInfo)
 	}
 	return packageInfos
 }
 
+// Default archives of Ubuntu and Debian packages.
+const (
+	UbuntuArchiveURL = "http://archive.ubuntu.com/ubuntu"
+	UbuntuPortsURL   = "http://ports.ubuntu.com/ubuntu-ports"
+	DebianArchiveURL = "http://deb.debian.org/debian"
+)
+
+// fillArchiveInfo fills the package information that comes from the apt
+// archive, if the archive indexes have the package.
+func (md *ManifestData) fillArchiveInfo(info *builder.PackageInfo) {
+	if md.Archive == nil {
+		return
+	}
+	p := md.Archive.Package(info.Name, info.Version, info.Arch)
+	if p == nil {
+		return
+	}
+	info.SourceName = p.Source
+	info.SourceVersion = p.SourceVersion
+	info.Maintainer = p.Maintainer
+	info.OriginalMaintainer = p.OriginalMaintainer
+	info.Homepage = p.Homepage
+	info.Summary = p.Summary
+	info.Depends = p.Depends
+	if info.Homepage == "" {
+		if src := md.Archive.Source(p.Source, p.SourceVersion); src != nil {
+			info.Homepage = src.Homepage
+		}
+	}
+	if p.Filename != "" {
+		archiveURL := md.ArchiveURL
+		switch {
+		case archiveURL != "":
+		case md.OSRelease != nil && md.OSRelease.Family() == "debian":
+			archiveURL = DebianArchiveURL
+		case info.Arch == "amd64" || info.Arch == "i386":
+			archiveURL = UbuntuArchiveURL
+		default:
+			archiveURL = UbuntuPortsURL
+		}
+		info.DownloadLocation = strings.TrimSuffix(archiveURL, "/") + "/" + p.Filename
+	}
+}
+
+// proPocket returns the Ubuntu Pro pocket the package comes from, if any.
+func (md *ManifestData) proPocket(info *builder.PackageInfo) pro.Pocket {
+	if md.OSRelease != nil && md.OSRelease.Family() != "ubuntu" {
+		return ""
+	}
+	return pro.Detect(info.Version, md.component(info), md.ProLabel)
+}
+
+// component returns the archive component of the package, such as "main",
+// if the archive indexes have the package.
+func (md *ManifestData) component(info *builder.PackageInfo) string {
+	if md.Archive == nil {
+		return ""
+	}
+	p := md.Archive.Package(info.Name, info.Version, info.Arch)
+	if p == nil {
+		return ""
+	}
+	// Debs are in pool/<component>/.
+	parts := strings.Split(p.Filename, "/")
+	if len(parts) < 2 || parts[0] != "pool" {
+		return ""
+	}
+	return parts[1]
+}
+
 func (md *ManifestData) ProcessPaths() []builder.PathInfo {
 	var pathInfos []builder.PathInfo
 	for _, p := range md.Paths {
@@ -81,25 +480,72 @@ func (md *ManifestData) ProcessPaths() []builder.PathInfo {
 		pathInfo.FinalSHA256 = p.FinalSHA256
 		pathInfo.Link = p.Link
 		pathInfo.Inode = p.Inode
+		pathInfo.License, pathInfo.Copyright = md.pathLicense(&p)
 		pathInfos = append(pathInfos, pathInfo)
 	}
 	return pathInfos
 }
 
+// pathLicense returns the SPDX license expression and the copyright of the
+// path, from the copyright file of the package of its first slice. The
+// package name of the slice may be qualified with the architecture.
+func (md *ManifestData) pathLicense(p *manifest.Path) (license, copyrightText string) {
+	if len(p.Slices) == 0 {
+		return "", ""
+	}
+	pkg, _, _ := strings.Cut(p.Slices[0], "_")
+	pkg, _, _ = strings.Cut(pkg, ":")
+	c, ok := md.Copyrights[pkg]
+	if !ok {
+		return "", ""
+	}
+	files := c.Match(p.Path)
+	if files == nil {
+		return "", ""
+	}
+	license, _ = copyright.SPDXExpression(files.License.Expression)
+	return license, files.Copyright
+}
+
+// licenseRefs returns the licenses of the packages that are not in the
+// SPDX license list, with their text if the copyright files have it.
+func (md *ManifestData) licenseRefs() []builder.LicenseRefInfo {
+	var refs []builder.LicenseRefInfo
+	seen := make(map[string]bool)
+	for _, p := range md.Packages {
+		c, ok := md.Copyrights[p.Name]
+		if !ok {
+			continue
+		}
+		_, pkgRefs := c.SPDXLicense()
+		for _, ref := range pkgRefs {
+			if seen[ref.ID] {
+				continue
+			}
+			seen[ref.ID] = true
+			refs = append(refs, builder.LicenseRefInfo{ID: ref.ID, Name: ref.Name, Text: c.LicenseText(ref.Name)})
+		}
+	}
+	return refs
+}
+
 type prefixable interface {
 	manifest.Path | manifest.Content | manifest.Package | manifest.Slice
 }
 
-func iteratePrefix[T prefixable](db *jsonwall.DB, prefix *T, store *[]T) error {
+// iteratePrefix appends the entries of the given kind to store. Entries that
+// cannot be read are skipped and recorded in data.ReadErrors.
+func iteratePrefix[T prefixable](db *jsonwall.DB, kind string, prefix *T, store *[]T, data *ManifestData) error {
 	iter, err := db.IteratePrefix(prefix)
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot read manifest: cannot iterate %s entries: %s", kind, err)
 	}
-	for iter.Next() {
+	for position := 1; iter.Next(); position++ {
 		var val T
 		err := iter.Get(&val)
 		if err != nil {
-			return fmt.Errorf("cannot read manifest: %s", err)
+			data.ReadErrors = append(data.ReadErrors, &EntryError{Kind: kind, Position: position, Err: err})
+			continue
 		}
 		*store = append(*store, val)
 	}
@@ -110,15 +556,15 @@ type updFnType func(db *jsonwall.DB, data *ManifestData) error
 
 var updateFunctions = []updFnType{
 	func(db *jsonwall.DB, data *ManifestData) error {
-		return iteratePrefix(db, &manifest.Package{Kind: "package"}, &data.Packages)
+		return iteratePrefix(db, "package", &manifest.Package{Kind: "package"}, &data.Packages, data)
 	},
 	func(db *jsonwall.DB, data *ManifestData) error {
-		return iteratePrefix(db, &manifest.Slice{Kind: "slice"}, &data.Slices)
+		return iteratePrefix(db, "slice", &manifest.Slice{Kind: "slice"}, &data.Slices, data)
 	},
 	func(db *jsonwall.DB, data *ManifestData) error {
-		return iteratePrefix(db, &manifest.Path{Kind: "path"}, &data.Paths)
+		return iteratePrefix(db, "path", &manifest.Path{Kind: "path"}, &data.Paths, data)
 	},
 	func(db *jsonwall.DB, data *ManifestData) error {
-		return iteratePrefix(db, &manifest.Content{Kind: "content"}, &data.Content)
+		return iteratePrefix(db, "content", &manifest.Content{Kind: "content"}, &data.Content, data)
 	},
 }
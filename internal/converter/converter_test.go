@@ -1,14 +1,23 @@
 package converter_test
 
 import (
+	"bytes"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/canonical/chisel/public/manifest"
 	"github.com/canonical/ssbom/internal/builder"
 	"github.com/canonical/ssbom/internal/converter"
+	"github.com/canonical/ssbom/internal/copyright"
+	"github.com/canonical/ssbom/internal/osrelease"
 	"github.com/canonical/ssbom/internal/testutil"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/klauspost/compress/zstd"
 	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
 	. "gopkg.in/check.v1"
 )
 
@@ -40,6 +49,7 @@ var processTests = []ProcessTest{
 				Version: "1.0",
 				SHA256:  "sha256",
 				Arch:    "amd64",
+				CPEs:    []string{"cpe:2.3:a:test:test:1.0:*:*:*:*:*:*:*"},
 			},
 		},
 	}, {
@@ -122,7 +132,7 @@ var converterTests = []ConverterTest{
 				&testutil.SPDXRelSampleSingleSliceContainsFile,
 			},
 			CreationInfo: &spdx.CreationInfo{
-				Creators: builder.ChiselSbomDocCreator,
+				Creators: []spdx.Creator{{Creator: "ssbom-" + builder.ToolVersion(), CreatorType: "Tool"}},
 			},
 		},
 	}, {
@@ -147,11 +157,11 @@ var converterTests = []ConverterTest{
 			},
 			Relationships: []*spdx.Relationship{
 				&testutil.SPDXRelSampleSingleDocDescribesPkg,
-				&testutil.SPDXRelSampleSinglePkgContainsSlice,
 				&testutil.SPDXRelSampleSingleFileModifiedBySlice,
+				&testutil.SPDXRelSampleSinglePkgContainsSlice,
 			},
 			CreationInfo: &spdx.CreationInfo{
-				Creators: builder.ChiselSbomDocCreator,
+				Creators: []spdx.Creator{{Creator: "ssbom-" + builder.ToolVersion(), CreatorType: "Tool"}},
 			},
 		},
 	},
@@ -169,12 +179,12 @@ func (s *S) TestProcessManifestData(c *C) {
 	}
 }
 
-func runTestConvert(c *C, tests []ConverterTest, distro string) {
+func runTestConvert(c *C, tests []ConverterTest, osRelease *osrelease.OSRelease) {
 	for _, test := range tests {
-		if distro == "" {
+		if osRelease == nil {
 			c.Logf("Running test without distro: %s", test.summary)
 		} else {
-			c.Logf("Running test with distro: %s: %s", distro, test.summary)
+			c.Logf("Running test with distro: %s: %s", osRelease.Distro(), test.summary)
 		}
 		lines := strings.Split(strings.TrimSpace(test.jsonwall), "\n")
 		trimmedLines := make([]string, 0, len(lines))
@@ -183,14 +193,25 @@ func runTestConvert(c *C, tests []ConverterTest, distro string) {
 		}
 		test.jsonwall = strings.Join(trimmedLines, "\n")
 		var reader io.Reader = strings.NewReader(test.jsonwall)
-		doc, err := converter.Convert(reader, distro)
+		doc, err := converter.Convert(reader, osRelease)
 		c.Assert(err, IsNil)
+		checkDocumentInfo(c, doc)
 		c.Assert(doc, DeepEquals, &test.spdxDocument)
 	}
 }
 
+// checkDocumentInfo checks the namespace and creation time of the document,
+// which change on every run, and clears them.
+func checkDocumentInfo(c *C, doc *spdx.Document) {
+	c.Assert(doc.DocumentNamespace, Matches, `https://spdx.org/spdxdocs/chiselled-ubuntu-rootfs-[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}`)
+	_, err := time.Parse(time.RFC3339, doc.CreationInfo.Created)
+	c.Assert(err, IsNil)
+	doc.DocumentNamespace = ""
+	doc.CreationInfo.Created = ""
+}
+
 func (s *S) TestConvert(c *C) {
-	runTestConvert(c, converterTests, "")
+	runTestConvert(c, converterTests, nil)
 
 	// Test with distro
 	for i := range testutil.SPDXDocSamplePackages {
@@ -204,5 +225,220 @@ func (s *S) TestConvert(c *C) {
 			&testutil.SPDXRelSampleUbuntuNoble,
 		}, converterTests[i].spdxDocument.Relationships...)
 	}
-	runTestConvert(c, converterTests, "24.04")
+	runTestConvert(c, converterTests, testutil.SampleOSReleaseNoble)
+}
+
+func (s *S) TestConvertCycloneDX(c *C) {
+	reader := strings.NewReader(strings.Join([]string{
+		`{"jsonwall":"1.0","schema":"1.0","count":3}`,
+		`{"kind":"package","name":"test","version":"1.0","sha256":"sha256","arch":"amd64"}`,
+		`{"kind":"path","path":"/test","mode":"0644","slices":["test_slice"],"sha256":"sha256","size":1024}`,
+		`{"kind":"slice","name":"test_slice"}`,
+	}, "\n"))
+	bom, err := converter.ConvertCycloneDX(reader, nil)
+	c.Assert(err, IsNil)
+	c.Assert(bom.Components, DeepEquals, []*cyclonedx.Component{
+		testutil.CycloneDXSamplePackage(testutil.CycloneDXSampleSlice(&testutil.CycloneDXSampleFile)),
+	})
+}
+
+func (s *S) TestConvertCompressed(c *C) {
+	jsonwall := strings.Join([]string{
+		`{"jsonwall":"1.0","schema":"1.0","count":3}`,
+		`{"kind":"package","name":"test","version":"1.0","sha256":"sha256","arch":"amd64"}`,
+		`{"kind":"path","path":"/test","mode":"0644","slices":["test_slice"],"sha256":"sha256","size":1024}`,
+		`{"kind":"slice","name":"test_slice"}`,
+	}, "\n")
+	encoder, err := zstd.NewWriter(nil)
+	c.Assert(err, IsNil)
+	compressed := encoder.EncodeAll([]byte(jsonwall), nil)
+
+	plainDoc, err := converter.Convert(strings.NewReader(jsonwall), nil)
+	c.Assert(err, IsNil)
+	compressedDoc, err := converter.Convert(bytes.NewReader(compressed), nil)
+	c.Assert(err, IsNil)
+	c.Assert(compressedDoc.DocumentNamespace, Not(Equals), plainDoc.DocumentNamespace)
+	checkDocumentInfo(c, plainDoc)
+	checkDocumentInfo(c, compressedDoc)
+	c.Assert(compressedDoc, DeepEquals, plainDoc)
+	c.Assert(compressedDoc.Packages, HasLen, 2)
+}
+
+var corruptJSONWall = strings.Join([]string{
+	`{"jsonwall":"1.0","schema":"1.0","count":5}`,
+	`{"kind":"package","name":"test","version":"1.0","sha256":"sha256","arch":"amd64"}`,
+	`{"kind":"path","path":"/other","mode":"0644","slices":["test_slice"],"size":"big"}`,
+	`{"kind":"path","path":"/test","mode":"0644","slices":["test_slice"],"sha256":"sha256","size":1024}`,
+	`{"kind":"slice","name":"test_slice"}`,
+	`{"kind":"slice","name":["test_other"]}`,
+}, "\n")
+
+func (s *S) TestConvertReadErrors(c *C) {
+	_, err := converter.Convert(strings.NewReader(corruptJSONWall), nil)
+	c.Assert(err, ErrorMatches, `cannot read 2 manifest entries:
+- cannot read slice entry 2: json: cannot unmarshal array .*
+- cannot read path entry 1: json: cannot unmarshal string .*`)
+
+	manifestData, err := converter.ReadManifest(strings.NewReader(corruptJSONWall))
+	c.Assert(err, IsNil)
+	c.Assert(manifestData.Packages, HasLen, 1)
+	c.Assert(manifestData.Slices, HasLen, 1)
+	c.Assert(manifestData.Paths, HasLen, 1)
+	c.Assert(manifestData.Paths[0].Path, Equals, "/test")
+	c.Assert(manifestData.ReadErrors, HasLen, 2)
+	c.Assert(manifestData.ReadErrors[0].Kind, Equals, "slice")
+	c.Assert(manifestData.ReadErrors[0].Position, Equals, 2)
+	c.Assert(manifestData.ReadErrors[1].Kind, Equals, "path")
+	c.Assert(manifestData.ReadErrors[1].Position, Equals, 1)
+
+	doc, err := manifestData.BuildSPDX()
+	c.Assert(err, IsNil)
+	c.Assert(doc.Annotations, HasLen, 1)
+	c.Assert(doc.Annotations[0].AnnotationType, Equals, "OTHER")
+	c.Assert(doc.Annotations[0].AnnotationComment, Matches,
+		`This document is incomplete: the following manifest entries could not be read: cannot read slice entry 2: .*; cannot read path entry 1: .*`)
+
+	bom, err := manifestData.BuildCycloneDX()
+	c.Assert(err, IsNil)
+	c.Assert(bom.Annotations, HasLen, 1)
+	c.Assert(bom.Annotations[0].Subjects, DeepEquals, []string{"Package-test"})
+
+	spdx3Doc, err := manifestData.BuildSPDX3()
+	c.Assert(err, IsNil)
+	annotation, ok := spdx3Doc.Graph[len(spdx3Doc.Graph)-1].(*spdx3.Annotation)
+	c.Assert(ok, Equals, true)
+	c.Assert(annotation.Subject, Equals, spdx3Doc.Graph[1].(*spdx3.SpdxDocument).SpdxID)
+}
+
+func (s *S) TestConvertSPDX3Namespace(c *C) {
+	ids := make(map[string]bool)
+	for _, manifest := range []string{corruptJSONWall, multiArchJSONWall} {
+		manifestData, err := converter.ReadManifest(strings.NewReader(manifest))
+		c.Assert(err, IsNil)
+		manifestData.Document.NamespacePrefix = "https://example.com/spdxdocs"
+		manifestData.Document.Digest = manifestData.Digest()
+		namespace := manifestData.Document.DocumentNamespace()
+		c.Assert(namespace, Equals, "https://example.com/spdxdocs/chiselled-ubuntu-rootfs-"+manifestData.Digest())
+
+		doc, err := manifestData.BuildSPDX3()
+		c.Assert(err, IsNil)
+		spdxDoc := doc.Graph[1].(*spdx3.SpdxDocument)
+		c.Assert(spdxDoc.SpdxID, Equals, builder.SPDX3Id(namespace, "DOCUMENT"))
+		for _, id := range spdxDoc.Elements {
+			c.Assert(strings.HasPrefix(id, namespace+"#"), Equals, true)
+		}
+		ids[spdxDoc.SpdxID] = true
+	}
+	c.Assert(ids, HasLen, 2)
+}
+
+// multiArchJSONWall has packages of two architectures with the same name.
+// As in the manifests written by chisel, slices are named after their
+// package only and belong to the package of the architecture of their
+// multiarch paths, if any.
+var multiArchJSONWall = strings.Join([]string{
+	`{"jsonwall":"1.0","schema":"1.0","count":15}`,
+	`{"kind":"content","slice":"libc6_config","path":"/etc/ld.so.conf.d/"}`,
+	`{"kind":"content","slice":"libc6_i386-libs","path":"/usr/lib/i386-linux-gnu/libc.so.6"}`,
+	`{"kind":"content","slice":"libc6_libs","path":"/usr/lib/x86_64-linux-gnu/libc.so.6"}`,
+	`{"kind":"content","slice":"zlib1g_libs","path":"/usr/lib/x86_64-linux-gnu/libz.so.1"}`,
+	`{"kind":"package","name":"libc6","version":"2.39-0ubuntu8","sha256":"aaaa","arch":"amd64"}`,
+	`{"kind":"package","name":"libc6","version":"2.39-0ubuntu8","sha256":"bbbb","arch":"i386"}`,
+	`{"kind":"package","name":"zlib1g","version":"1:1.3.dfsg-3.1ubuntu2","sha256":"cccc","arch":"amd64"}`,
+	`{"kind":"path","path":"/etc/ld.so.conf.d/","mode":"0755","slices":["libc6_config"]}`,
+	`{"kind":"path","path":"/usr/lib/i386-linux-gnu/libc.so.6","mode":"0644","slices":["libc6_i386-libs"],"sha256":"dddd","size":1}`,
+	`{"kind":"path","path":"/usr/lib/x86_64-linux-gnu/libc.so.6","mode":"0644","slices":["libc6_libs"],"sha256":"eeee","size":1}`,
+	`{"kind":"path","path":"/usr/lib/x86_64-linux-gnu/libz.so.1","mode":"0644","slices":["zlib1g_libs"],"sha256":"ffff","size":1}`,
+	`{"kind":"slice","name":"libc6_config"}`,
+	`{"kind":"slice","name":"libc6_i386-libs"}`,
+	`{"kind":"slice","name":"libc6_libs"}`,
+	`{"kind":"slice","name":"zlib1g_libs"}`,
+}, "\n")
+
+func (s *S) TestConvertMultiArch(c *C) {
+	md, err := converter.ReadManifest(strings.NewReader(multiArchJSONWall))
+	c.Assert(err, IsNil)
+	c.Assert(md.Err(), IsNil)
+	c.Assert(md.CheckConsistency(), HasLen, 0)
+
+	doc, err := converter.Convert(strings.NewReader(multiArchJSONWall), nil)
+	c.Assert(err, IsNil)
+	var ids []string
+	for _, p := range doc.Packages {
+		ids = append(ids, string(p.PackageSPDXIdentifier))
+	}
+	c.Assert(ids, DeepEquals, []string{
+		"Package-libc6-amd64-057a44a5",
+		"Package-libc6-i386-a7d42ebf",
+		"Package-zlib1g",
+		"Slice-libc6-config-da7c1f3a",
+		"Slice-libc6-i386-libs-dc1e9248",
+		"Slice-libc6-libs-c4058a07",
+		"Slice-zlib1g-libs-d86c4417",
+	})
+	var contains []string
+	for _, rln := range doc.Relationships {
+		if rln.Relationship == "CONTAINS" && strings.HasPrefix(string(rln.RefA.ElementRefID), "Package-") {
+			contains = append(contains, string(rln.RefA.ElementRefID)+" "+string(rln.RefB.ElementRefID))
+		}
+	}
+	c.Assert(contains, DeepEquals, []string{
+		"Package-libc6-amd64-057a44a5 Slice-libc6-config-da7c1f3a",
+		"Package-libc6-amd64-057a44a5 Slice-libc6-libs-c4058a07",
+		"Package-libc6-i386-a7d42ebf Slice-libc6-i386-libs-dc1e9248",
+		"Package-zlib1g Slice-zlib1g-libs-d86c4417",
+	})
+	c.Assert(doc.Packages[0].PackageExternalReferences[1].Locator, Equals, "pkg:deb/ubuntu/libc6@2.39-0ubuntu8?arch=amd64")
+	c.Assert(doc.Packages[1].PackageExternalReferences[1].Locator, Equals, "pkg:deb/ubuntu/libc6@2.39-0ubuntu8?arch=i386")
+
+	bom, err := converter.ConvertCycloneDX(strings.NewReader(multiArchJSONWall), nil)
+	c.Assert(err, IsNil)
+	c.Assert(bom.Components, HasLen, 3)
+	sliceCount := 0
+	for _, comp := range bom.Components {
+		sliceCount += len(comp.Components)
+	}
+	c.Assert(sliceCount, Equals, 4)
+	c.Assert(bom.Components[1].Components, HasLen, 1)
+	c.Assert(bom.Components[1].Components[0].Name, Equals, "libc6_i386-libs")
+}
+
+func (s *S) TestConvertMultiArchIds(c *C) {
+	// A package may be named like another package qualified with its
+	// architecture.
+	lines := strings.Split(strings.Replace(multiArchJSONWall, `"count":15`, `"count":17`, 1), "\n")
+	lines = append(lines,
+		`{"kind":"package","name":"libc6-amd64","version":"2.39-0ubuntu8","sha256":"abab","arch":"i386"}`,
+		`{"kind":"slice","name":"libc6-amd64_libs"}`,
+	)
+	// The entries of jsonwall files are sorted.
+	sort.Strings(lines[1:])
+	wall := strings.Join(lines, "\n")
+	doc, err := converter.Convert(strings.NewReader(wall), nil)
+	c.Assert(err, IsNil)
+	ids := make(map[common.ElementID]string)
+	for _, p := range doc.Packages {
+		if other, ok := ids[p.PackageSPDXIdentifier]; ok {
+			c.Fatalf("packages %s and %s have the same id %s", other, p.PackageName, p.PackageSPDXIdentifier)
+		}
+		ids[p.PackageSPDXIdentifier] = p.PackageName
+	}
+	c.Assert(ids["Package-libc6-amd64"], Equals, "libc6-amd64")
+}
+
+func (s *S) TestConvertMultiArchLicenses(c *C) {
+	md, err := converter.ReadManifest(strings.NewReader(multiArchJSONWall))
+	c.Assert(err, IsNil)
+	libc6, err := copyright.Parse([]byte("Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\nFiles: *\nCopyright: 1991-2024 Free Software Foundation, Inc.\nLicense: LGPL-2.1+\n"))
+	c.Assert(err, IsNil)
+	md.Copyrights = map[string]*copyright.Copyright{"libc6": libc6}
+	licenses := make(map[string]string)
+	for _, p := range md.ProcessPaths() {
+		licenses[p.Path] = p.License
+	}
+	c.Assert(licenses, DeepEquals, map[string]string{
+		"/usr/lib/i386-linux-gnu/libc.so.6":   "LGPL-2.1-or-later",
+		"/usr/lib/x86_64-linux-gnu/libc.so.6": "LGPL-2.1-or-later",
+		"/usr/lib/x86_64-linux-gnu/libz.so.1": "",
+	})
 }
@@ -0,0 +1,132 @@
+package converter
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+
+	"github.com/canonical/chisel/public/manifest"
+)
+
+// ManifestName is the file name chisel gives to the manifests it generates.
+const ManifestName = "manifest.wall"
+
+// FindManifests returns the names of every chisel manifest in fsys, in
+// lexical order. Symbolic links to directories are not followed.
+func FindManifests(fsys fs.FS) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && path.Base(name) == ManifestName {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find manifests: %s", err)
+	}
+	return names, nil
+}
+
+// MergeManifests merges the manifests, indexed by their path, into one.
+// Entries found in several manifests are expected to agree; for every
+// disagreement a warning is returned and the entry of the manifest whose
+// path sorts first is kept. The slices of a path are merged.
+func MergeManifests(manifests map[string]*ManifestData) (*ManifestData, []string) {
+	names := make([]string, 0, len(manifests))
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, err := range manifests[name].ReadErrors {
+			err.Manifest = name
+		}
+	}
+	if len(names) == 1 {
+		return manifests[names[0]], nil
+	}
+
+	merged := &ManifestData{}
+	var warnings []string
+	warnf := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	// The index of each package and path in merged and the manifest they
+	// were taken from.
+	packages, packageOwners := make(map[string]int), make(map[string]string)
+	paths, pathOwners := make(map[string]int), make(map[string]string)
+	slicesSeen := make(map[string]bool)
+	content := make(map[manifest.Content]bool)
+	for _, name := range names {
+		md := manifests[name]
+		if merged.OSRelease == nil {
+			merged.OSRelease = md.OSRelease
+		}
+		merged.ReadErrors = append(merged.ReadErrors, md.ReadErrors...)
+		for _, p := range md.Packages {
+			// Packages of different architectures may have the same name.
+			key := p.Name + ":" + p.Arch
+			i, ok := packages[key]
+			if !ok {
+				packages[key] = len(merged.Packages)
+				packageOwners[key] = name
+				merged.Packages = append(merged.Packages, p)
+				continue
+			}
+			if q := merged.Packages[i]; q != p {
+				warnf("manifests %s and %s disagree on package %s: %s %s (%s) and %s %s (%s)",
+					packageOwners[key], name, p.Name, q.Version, q.Arch, q.Digest, p.Version, p.Arch, p.Digest)
+			}
+		}
+		for _, s := range md.Slices {
+			if !slicesSeen[s.Name] {
+				slicesSeen[s.Name] = true
+				merged.Slices = append(merged.Slices, s)
+			}
+		}
+		for _, p := range md.Paths {
+			i, ok := paths[p.Path]
+			if !ok {
+				paths[p.Path] = len(merged.Paths)
+				pathOwners[p.Path] = name
+				p.Slices = slices.Clone(p.Slices)
+				merged.Paths = append(merged.Paths, p)
+				continue
+			}
+			q := &merged.Paths[i]
+			if q.Mode != p.Mode || q.SHA256 != p.SHA256 || q.FinalSHA256 != p.FinalSHA256 || q.Size != p.Size || q.Link != p.Link {
+				warnf("manifests %s and %s disagree on path %s", pathOwners[p.Path], name, p.Path)
+			}
+			for _, s := range p.Slices {
+				if !slices.Contains(q.Slices, s) {
+					q.Slices = append(q.Slices, s)
+				}
+			}
+			sort.Strings(q.Slices)
+		}
+		for _, c := range md.Content {
+			if !content[c] {
+				content[c] = true
+				merged.Content = append(merged.Content, c)
+			}
+		}
+	}
+
+	sort.Slice(merged.Packages, func(i, j int) bool {
+		a, b := merged.Packages[i], merged.Packages[j]
+		return a.Name < b.Name || a.Name == b.Name && a.Arch < b.Arch
+	})
+	sort.Slice(merged.Slices, func(i, j int) bool { return merged.Slices[i].Name < merged.Slices[j].Name })
+	sort.Slice(merged.Paths, func(i, j int) bool { return merged.Paths[i].Path < merged.Paths[j].Path })
+	sort.Slice(merged.Content, func(i, j int) bool {
+		a, b := merged.Content[i], merged.Content[j]
+		return a.Slice < b.Slice || a.Slice == b.Slice && a.Path < b.Path
+	})
+	return merged, warnings
+}
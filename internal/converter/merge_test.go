@@ -0,0 +1,147 @@
+package converter_test
+
+import (
+	"io/fs"
+	"testing/fstest"
+
+	"github.com/canonical/chisel/public/manifest"
+	"github.com/canonical/ssbom/internal/converter"
+	"github.com/canonical/ssbom/internal/testutil"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestFindManifests(c *C) {
+	fsys := fstest.MapFS{
+		"var/lib/chisel/manifest.wall": &fstest.MapFile{},
+		"opt/app/manifest.wall":        &fstest.MapFile{},
+		"opt/app/other.wall":           &fstest.MapFile{},
+		"opt/link/manifest.wall":       &fstest.MapFile{Mode: fs.ModeSymlink},
+		"manifest.wall/file":           &fstest.MapFile{},
+	}
+	names, err := converter.FindManifests(fsys)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"opt/app/manifest.wall", "var/lib/chisel/manifest.wall"})
+}
+
+var mergeManifestA = &converter.ManifestData{
+	Packages: []manifest.Package{
+		{Kind: "package", Name: "base-files", Version: "13ubuntu10", Digest: "aaaa", Arch: "amd64"},
+		{Kind: "package", Name: "libc6", Version: "2.39-0ubuntu8", Digest: "bbbb", Arch: "amd64"},
+	},
+	Slices: []manifest.Slice{
+		{Kind: "slice", Name: "base-files_base"},
+		{Kind: "slice", Name: "libc6_libs"},
+	},
+	Paths: []manifest.Path{
+		{Kind: "path", Path: "/etc/os-release", Mode: "0644", Slices: []string{"base-files_base"}, SHA256: "cccc"},
+		{Kind: "path", Path: "/var/lib/chisel/manifest.wall", Mode: "0644", Slices: []string{"base-files_chisel"}},
+	},
+	Content: []manifest.Content{
+		{Kind: "content", Slice: "base-files_base", Path: "/etc/os-release"},
+	},
+	OSRelease: testutil.SampleOSReleaseNoble,
+}
+
+var mergeManifestB = &converter.ManifestData{
+	Packages: []manifest.Package{
+		{Kind: "package", Name: "base-files", Version: "13ubuntu10", Digest: "aaaa", Arch: "amd64"},
+		{Kind: "package", Name: "ca-certificates", Version: "20240203", Digest: "dddd", Arch: "all"},
+	},
+	Slices: []manifest.Slice{
+		{Kind: "slice", Name: "base-files_base"},
+		{Kind: "slice", Name: "ca-certificates_data"},
+	},
+	Paths: []manifest.Path{
+		{Kind: "path", Path: "/etc/os-release", Mode: "0644", Slices: []string{"base-files_release-info"}, SHA256: "cccc"},
+		{Kind: "path", Path: "/etc/ssl/certs/ca-certificates.crt", Mode: "0644", Slices: []string{"ca-certificates_data"}, SHA256: "eeee"},
+	},
+	Content: []manifest.Content{
+		{Kind: "content", Slice: "base-files_base", Path: "/etc/os-release"},
+		{Kind: "content", Slice: "ca-certificates_data", Path: "/etc/ssl/certs/ca-certificates.crt"},
+	},
+}
+
+func (s *S) TestMergeManifests(c *C) {
+	merged, warnings := converter.MergeManifests(map[string]*converter.ManifestData{
+		"/b/manifest.wall": mergeManifestB,
+		"/a/manifest.wall": mergeManifestA,
+	})
+	c.Assert(warnings, HasLen, 0)
+	c.Assert(merged, DeepEquals, &converter.ManifestData{
+		Packages: []manifest.Package{
+			mergeManifestA.Packages[0],
+			mergeManifestB.Packages[1],
+			mergeManifestA.Packages[1],
+		},
+		Slices: []manifest.Slice{
+			mergeManifestA.Slices[0],
+			mergeManifestB.Slices[1],
+			mergeManifestA.Slices[1],
+		},
+		Paths: []manifest.Path{
+			{Kind: "path", Path: "/etc/os-release", Mode: "0644", Slices: []string{"base-files_base", "base-files_release-info"}, SHA256: "cccc"},
+			mergeManifestB.Paths[1],
+			mergeManifestA.Paths[1],
+		},
+		Content: []manifest.Content{
+			mergeManifestA.Content[0],
+			mergeManifestB.Content[1],
+		},
+		OSRelease: testutil.SampleOSReleaseNoble,
+	})
+	// The inputs are left untouched.
+	c.Assert(mergeManifestA.Paths[0].Slices, DeepEquals, []string{"base-files_base"})
+}
+
+func (s *S) TestMergeManifestsSingle(c *C) {
+	merged, warnings := converter.MergeManifests(map[string]*converter.ManifestData{
+		"/var/lib/chisel/manifest.wall": mergeManifestA,
+	})
+	c.Assert(warnings, HasLen, 0)
+	c.Assert(merged, Equals, mergeManifestA)
+}
+
+func (s *S) TestMergeManifestsDisagree(c *C) {
+	other := &converter.ManifestData{
+		Packages: []manifest.Package{
+			{Kind: "package", Name: "libc6", Version: "2.39-0ubuntu8.3", Digest: "ffff", Arch: "amd64"},
+		},
+		Paths: []manifest.Path{
+			{Kind: "path", Path: "/etc/os-release", Mode: "0644", Slices: []string{"base-files_base"}, SHA256: "9999"},
+		},
+	}
+	merged, warnings := converter.MergeManifests(map[string]*converter.ManifestData{
+		"/a/manifest.wall": mergeManifestA,
+		"/z/manifest.wall": other,
+	})
+	c.Assert(warnings, DeepEquals, []string{
+		"manifests /a/manifest.wall and /z/manifest.wall disagree on package libc6: 2.39-0ubuntu8 amd64 (bbbb) and 2.39-0ubuntu8.3 amd64 (ffff)",
+		"manifests /a/manifest.wall and /z/manifest.wall disagree on path /etc/os-release",
+	})
+	c.Assert(merged.Packages, DeepEquals, mergeManifestA.Packages)
+	c.Assert(merged.Paths[0].SHA256, Equals, "cccc")
+}
+
+func (s *S) TestMergeManifestsMultiArch(c *C) {
+	// Manifests of different architectures have slices with the same name.
+	amd64 := &converter.ManifestData{
+		Packages: []manifest.Package{{Kind: "package", Name: "libc6", Version: "2.39-0ubuntu8", Digest: "aaaa", Arch: "amd64"}},
+		Slices:   []manifest.Slice{{Kind: "slice", Name: "libc6_libs"}},
+		Paths:    []manifest.Path{{Kind: "path", Path: "/usr/lib/x86_64-linux-gnu/libc.so.6", Mode: "0644", Slices: []string{"libc6_libs"}, SHA256: "cccc", Size: 1}},
+		Content:  []manifest.Content{{Kind: "content", Slice: "libc6_libs", Path: "/usr/lib/x86_64-linux-gnu/libc.so.6"}},
+	}
+	i386 := &converter.ManifestData{
+		Packages: []manifest.Package{{Kind: "package", Name: "libc6", Version: "2.39-0ubuntu8", Digest: "bbbb", Arch: "i386"}},
+		Slices:   []manifest.Slice{{Kind: "slice", Name: "libc6_libs"}},
+		Paths:    []manifest.Path{{Kind: "path", Path: "/usr/lib/i386-linux-gnu/libc.so.6", Mode: "0644", Slices: []string{"libc6_libs"}, SHA256: "dddd", Size: 1}},
+		Content:  []manifest.Content{{Kind: "content", Slice: "libc6_libs", Path: "/usr/lib/i386-linux-gnu/libc.so.6"}},
+	}
+	merged, warnings := converter.MergeManifests(map[string]*converter.ManifestData{
+		"/i386/manifest.wall":  i386,
+		"/amd64/manifest.wall": amd64,
+	})
+	c.Assert(warnings, HasLen, 0)
+	c.Assert(merged.Packages, DeepEquals, []manifest.Package{amd64.Packages[0], i386.Packages[0]})
+	c.Assert(merged.Slices, HasLen, 1)
+	c.Assert(merged.CheckConsistency(), HasLen, 0)
+}
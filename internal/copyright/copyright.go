@@ -0,0 +1,174 @@
+// Package copyright reads machine-readable Debian copyright files, as
+// specified by DEP-5, and converts their licenses to SPDX expressions.
+// See https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+package copyright
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/canonical/ssbom/internal/deb822"
+)
+
+// Copyright is a machine-readable copyright file.
+type Copyright struct {
+	// License is the license of the header paragraph, if any.
+	License License
+	// Files lists the Files paragraphs in order.
+	Files []*Files
+	// Licenses holds the text of the stand-alone License paragraphs, by
+	// license short name.
+	Licenses map[string]string
+}
+
+// Files is a Files paragraph, giving the copyright and license of the
+// files that match its patterns.
+type Files struct {
+	Patterns  []string
+	Copyright string
+	License   License
+}
+
+// License is a License field: a Debian license expression, made of license
+// short names, and an optional license text.
+type License struct {
+	Expression string
+	Text       string
+}
+
+// ErrNotMachineReadable is returned by Parse for copyright files that do not
+// follow the machine-readable format, which many packages still use.
+var ErrNotMachineReadable = errors.New("not machine-readable")
+
+// Parse parses a machine-readable copyright file. It fails with
+// ErrNotMachineReadable if its header paragraph has no Format field.
+func Parse(data []byte) (*Copyright, error) {
+	paragraphs, err := deb822.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse copyright file: %w", err)
+	}
+	if len(paragraphs) == 0 || paragraphs[0]["Format"] == "" {
+		return nil, fmt.Errorf("cannot parse copyright file: %w", ErrNotMachineReadable)
+	}
+
+	c := &Copyright{
+		License:  parseLicense(paragraphs[0]["License"]),
+		Licenses: make(map[string]string),
+	}
+	for _, p := range paragraphs[1:] {
+		switch {
+		case p["Files"] != "":
+			c.Files = append(c.Files, &Files{
+				Patterns:  strings.Fields(p["Files"]),
+				Copyright: strings.TrimSpace(p["Copyright"]),
+				License:   parseLicense(p["License"]),
+			})
+		case p["License"] != "":
+			license := parseLicense(p["License"])
+			c.Licenses[license.Expression] = license.Text
+		}
+	}
+	return c, nil
+}
+
+// Match returns the last Files paragraph whose patterns match the path, as
+// later paragraphs override earlier ones, or nil if none does. Paths are
+// matched relative to the root, as patterns are relative to the root of the
+// source tree.
+func (c *Copyright) Match(filePath string) *Files {
+	filePath = strings.TrimPrefix(filePath, "/")
+	for i := len(c.Files) - 1; i >= 0; i-- {
+		for _, pattern := range c.Files[i].Patterns {
+			if matchPattern(pattern, filePath) {
+				return c.Files[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Expressions returns the distinct license expressions of the Files
+// paragraphs, or of the header paragraph if there are none, in order.
+func (c *Copyright) Expressions() []string {
+	var expressions []string
+	seen := make(map[string]bool)
+	add := func(expression string) {
+		if expression != "" && !seen[expression] {
+			seen[expression] = true
+			expressions = append(expressions, expression)
+		}
+	}
+	for _, f := range c.Files {
+		add(f.License.Expression)
+	}
+	if len(expressions) == 0 {
+		add(c.License.Expression)
+	}
+	return expressions
+}
+
+// SPDXLicense returns the SPDX license expression of the whole package: the
+// conjunction of the licenses of its files.
+func (c *Copyright) SPDXLicense() (string, []LicenseRef) {
+	var parts []string
+	var refs []LicenseRef
+	expressions := c.Expressions()
+	for _, expression := range expressions {
+		converted, exprRefs := SPDXExpression(expression)
+		if converted == "" {
+			continue
+		}
+		if len(expressions) > 1 && strings.Contains(converted, " ") {
+			converted = "(" + converted + ")"
+		}
+		parts = append(parts, converted)
+		refs = append(refs, exprRefs...)
+	}
+	return strings.Join(parts, " AND "), refs
+}
+
+// LicenseText returns the text of the license with the given short name,
+// from its stand-alone paragraph or from any field that has it.
+func (c *Copyright) LicenseText(name string) string {
+	if text := c.Licenses[name]; text != "" {
+		return text
+	}
+	for _, f := range c.Files {
+		if f.License.Expression == name && f.License.Text != "" {
+			return f.License.Text
+		}
+	}
+	if c.License.Expression == name {
+		return c.License.Text
+	}
+	return ""
+}
+
+// matchPattern matches a Files pattern, where "*" matches any sequence of
+// characters, including slashes, and "?" any single character. A pattern
+// naming a directory matches the files below it.
+func matchPattern(pattern, name string) bool {
+	var re strings.Builder
+	re.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("(/.*)?$")
+	matched, err := regexp.MatchString(re.String(), name)
+	return err == nil && matched
+}
+
+func parseLicense(value string) License {
+	expression, text, _ := strings.Cut(value, "\n")
+	return License{Expression: strings.TrimSpace(expression), Text: text}
+}
@@ -0,0 +1,108 @@
+package copyright_test
+
+import (
+	"errors"
+
+	"github.com/canonical/ssbom/internal/copyright"
+	. "gopkg.in/check.v1"
+)
+
+const sampleCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: libfoo
+Source: https://example.com/libfoo
+
+Files: *
+Copyright: 2001-2020 Foo Developers
+           2021 Bar Contributors
+License: LGPL-2.1+
+
+# Installed headers.
+files: usr/include/foo/*.h
+ usr/include/foo/config.?
+Copyright: 2020 Foo Developers
+license: BSD-3-clause or Expat
+
+Files: usr/share/foo
+Copyright: 2019 Data Authors
+License: Foo-Data-License
+ Permission is granted
+ .
+ to use this data.
+
+License: LGPL-2.1+
+ On Debian systems, see /usr/share/common-licenses/LGPL-2.1.
+`
+
+func (s *S) TestParse(c *C) {
+	cr, err := copyright.Parse([]byte(sampleCopyright))
+	c.Assert(err, IsNil)
+	c.Assert(cr.Files, HasLen, 3)
+	c.Assert(cr.Files[0].Copyright, Equals, "2001-2020 Foo Developers\n2021 Bar Contributors")
+	c.Assert(cr.Files[1].Patterns, DeepEquals, []string{"usr/include/foo/*.h", "usr/include/foo/config.?"})
+	c.Assert(cr.Files[1].License.Expression, Equals, "BSD-3-clause or Expat")
+	c.Assert(cr.Files[2].License, DeepEquals, copyright.License{
+		Expression: "Foo-Data-License",
+		Text:       "Permission is granted\n\nto use this data.",
+	})
+	c.Assert(cr.Expressions(), DeepEquals, []string{"LGPL-2.1+", "BSD-3-clause or Expat", "Foo-Data-License"})
+	c.Assert(cr.LicenseText("LGPL-2.1+"), Equals, "On Debian systems, see /usr/share/common-licenses/LGPL-2.1.")
+	c.Assert(cr.LicenseText("Foo-Data-License"), Equals, "Permission is granted\n\nto use this data.")
+
+	license, refs := cr.SPDXLicense()
+	c.Assert(license, Equals, "LGPL-2.1-or-later AND (BSD-3-Clause OR MIT) AND LicenseRef-Foo-Data-License")
+	c.Assert(refs, DeepEquals, []copyright.LicenseRef{{ID: "LicenseRef-Foo-Data-License", Name: "Foo-Data-License"}})
+
+	for path, expression := range map[string]string{
+		"/usr/lib/libfoo.so.1":         "LGPL-2.1+",
+		"/usr/include/foo/foo.h":       "BSD-3-clause or Expat",
+		"/usr/include/foo/config.h":    "BSD-3-clause or Expat",
+		"/usr/include/foo/config.hpp":  "LGPL-2.1+",
+		"/usr/share/foo/data/table.db": "Foo-Data-License",
+		"/usr/share/foobar":            "LGPL-2.1+",
+	} {
+		files := cr.Match(path)
+		c.Assert(files, NotNil)
+		c.Assert(files.License.Expression, Equals, expression, Commentf("path %s", path))
+	}
+}
+
+func (s *S) TestParseErrors(c *C) {
+	_, err := copyright.Parse([]byte("This package was debianized by someone.\n\nIt is GPL.\n"))
+	c.Assert(err, ErrorMatches, "cannot parse copyright file: line 1: expected a field")
+
+	_, err = copyright.Parse([]byte("Upstream-Name: foo\n\nFiles: *\nLicense: MIT\n"))
+	c.Assert(err, ErrorMatches, "cannot parse copyright file: not machine-readable")
+	c.Assert(errors.Is(err, copyright.ErrNotMachineReadable), Equals, true)
+
+	_, err = copyright.Parse([]byte(" Format: foo\n"))
+	c.Assert(err, ErrorMatches, "cannot parse copyright file: line 1: continuation line outside of a field")
+}
+
+func (s *S) TestSPDXExpression(c *C) {
+	for _, test := range []struct {
+		debian, spdx string
+		refs         []string
+	}{
+		{"GPL-2", "GPL-2.0-only", nil},
+		{"GPL-2+", "GPL-2.0-or-later", nil},
+		{"LGPL-2.1", "LGPL-2.1-only", nil},
+		{"gfdl-1.3+", "GFDL-1.3-or-later", nil},
+		{"Expat", "MIT", nil},
+		{"Apache-2.0 or MIT", "Apache-2.0 OR MIT", nil},
+		{"GPL-2+ or Artistic", "GPL-2.0-or-later OR Artistic-1.0", nil},
+		{"MPL-1.1+", "MPL-1.1+", nil},
+		{"GPL-2+ with Autoconf exception", "GPL-2.0-or-later WITH Autoconf-exception-3.0", nil},
+		{"GPL-2+ with OpenSSL exception", "LicenseRef-GPL-2.0-or-later-with-OpenSSL-exception", []string{"LicenseRef-GPL-2.0-or-later-with-OpenSSL-exception"}},
+		{"GPL-2+ or LGPL-2.1+, and MIT", "(GPL-2.0-or-later OR LGPL-2.1-or-later) AND MIT", nil},
+		{"public-domain and BSD-2-clause", "LicenseRef-public-domain AND BSD-2-Clause", []string{"LicenseRef-public-domain"}},
+		{"", "", nil},
+	} {
+		expression, refs := copyright.SPDXExpression(test.debian)
+		c.Assert(expression, Equals, test.spdx, Commentf("expression %q", test.debian))
+		var ids []string
+		for _, ref := range refs {
+			ids = append(ids, ref.ID)
+		}
+		c.Assert(ids, DeepEquals, test.refs)
+	}
+}
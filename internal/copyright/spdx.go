@@ -0,0 +1,182 @@
+package copyright
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LicenseRefPrefix starts the identifiers of the licenses that are not in
+// the SPDX license list.
+const LicenseRefPrefix = "LicenseRef-"
+
+// spdxLicenses maps the Debian short names that differ from SPDX license
+// identifiers, lowercased, to the latter.
+var spdxLicenses = map[string]string{
+	"apache-1.0":       "Apache-1.0",
+	"apache-1.1":       "Apache-1.1",
+	"apache-2.0":       "Apache-2.0",
+	"artistic":         "Artistic-1.0",
+	"artistic-1.0":     "Artistic-1.0",
+	"artistic-2.0":     "Artistic-2.0",
+	"bsd-2-clause":     "BSD-2-Clause",
+	"bsd-3-clause":     "BSD-3-Clause",
+	"bsd-4-clause":     "BSD-4-Clause",
+	"cc0-1.0":          "CC0-1.0",
+	"cc-by-3.0":        "CC-BY-3.0",
+	"cc-by-4.0":        "CC-BY-4.0",
+	"cc-by-sa-3.0":     "CC-BY-SA-3.0",
+	"cc-by-sa-4.0":     "CC-BY-SA-4.0",
+	"expat":            "MIT",
+	"mit":              "MIT",
+	"isc":              "ISC",
+	"mpl-1.1":          "MPL-1.1",
+	"mpl-2.0":          "MPL-2.0",
+	"openssl":          "OpenSSL",
+	"psf-2":            "PSF-2.0",
+	"python-2.0":       "Python-2.0",
+	"zlib":             "Zlib",
+	"zope-2.1":         "ZPL-2.1",
+	"boost-1.0":        "BSL-1.0",
+	"bsl-1.0":          "BSL-1.0",
+	"curl":             "curl",
+	"ftl":              "FTL",
+	"ijg":              "IJG",
+	"libpng":           "Libpng",
+	"libpng-2.0":       "libpng-2.0",
+	"x11":              "X11",
+	"unicode-dfs-2016": "Unicode-DFS-2016",
+	"sleepycat":        "Sleepycat",
+	"ofl-1.1":          "OFL-1.1",
+	"epl-1.0":          "EPL-1.0",
+	"epl-2.0":          "EPL-2.0",
+	"cddl-1.0":         "CDDL-1.0",
+	"gpl":              "GPL-1.0-or-later",
+	"lgpl":             "LGPL-2.0-or-later",
+}
+
+// spdxExceptions maps the names of Debian license exceptions, lowercased,
+// to SPDX license exception identifiers.
+var spdxExceptions = map[string]string{
+	"autoconf":  "Autoconf-exception-3.0",
+	"bison":     "Bison-exception-2.2",
+	"classpath": "Classpath-exception-2.0",
+	"font":      "Font-exception-2.0",
+	"gcc":       "GCC-exception-3.1",
+	"libtool":   "Libtool-exception",
+}
+
+// gnuLicense matches the versioned short names of the GNU licenses, which
+// distinguish "only" and "or later" versions in SPDX.
+var gnuLicense = regexp.MustCompile(`(?i)^(gpl|lgpl|agpl|gfdl)-(\d+)(\.\d+)?(\+)?$`)
+
+// LicenseRef is a license that is not in the SPDX license list.
+type LicenseRef struct {
+	// ID is the LicenseRef identifier, as in "LicenseRef-public-domain".
+	ID string
+	// Name is the Debian name of the license, as in "public-domain".
+	Name string
+}
+
+// SPDXExpression converts a Debian license expression to an SPDX license
+// expression. License short names that are not in the SPDX license list are
+// converted to LicenseRef identifiers, which are also returned.
+func SPDXExpression(expression string) (spdxExpression string, licenseRefs []LicenseRef) {
+	var parts []string
+	for i, part := range strings.Split(expression, ",") {
+		words := strings.Fields(part)
+		op := "AND"
+		if i > 0 && len(words) > 0 && isOperator(words[0]) {
+			op, words = strings.ToUpper(words[0]), words[1:]
+		}
+		converted, refs := convertWords(words)
+		licenseRefs = append(licenseRefs, refs...)
+		if converted == "" {
+			continue
+		}
+		if strings.Contains(expression, ",") && strings.Contains(converted, " ") {
+			// Commas have a lower precedence than the operators.
+			converted = "(" + converted + ")"
+		}
+		if len(parts) > 0 {
+			parts = append(parts, op)
+		}
+		parts = append(parts, converted)
+	}
+	return strings.Join(parts, " "), licenseRefs
+}
+
+func isOperator(word string) bool {
+	return strings.EqualFold(word, "and") || strings.EqualFold(word, "or")
+}
+
+// convertWords converts a Debian license expression without commas.
+func convertWords(words []string) (string, []LicenseRef) {
+	var out []string
+	var refs []LicenseRef
+	for i := 0; i < len(words); i++ {
+		if isOperator(words[i]) {
+			out = append(out, strings.ToUpper(words[i]))
+			continue
+		}
+		license, exception := words[i], ""
+		name := license
+		if i+1 < len(words) && strings.EqualFold(words[i+1], "with") {
+			// "with <name> exception" applies to the license.
+			end := i + 2
+			for end < len(words) && !strings.EqualFold(words[end], "exception") {
+				end++
+			}
+			exception = strings.Join(words[i+2:min(end, len(words))], " ")
+			name = strings.Join(words[i:min(end+1, len(words))], " ")
+			i = end
+		}
+
+		id, isRef := licenseId(license)
+		switch {
+		case exception == "" && !isRef:
+		case exception == "":
+			refs = append(refs, LicenseRef{ID: id, Name: name})
+		case spdxExceptions[strings.ToLower(exception)] != "" && !isRef:
+			id += " WITH " + spdxExceptions[strings.ToLower(exception)]
+		default:
+			// Unknown exceptions make a different license.
+			id = sanitizeRef(LicenseRefPrefix + strings.TrimPrefix(id, LicenseRefPrefix) + "-with-" + exception + "-exception")
+			refs = append(refs, LicenseRef{ID: id, Name: name})
+		}
+		out = append(out, id)
+	}
+	return strings.Join(out, " "), refs
+}
+
+// licenseId returns the SPDX identifier of a Debian license short name, and
+// whether it is a LicenseRef.
+func licenseId(name string) (string, bool) {
+	if id, ok := spdxLicenses[strings.ToLower(name)]; ok {
+		return id, false
+	}
+	if m := gnuLicense.FindStringSubmatch(name); m != nil {
+		minor := m[3]
+		if minor == "" {
+			minor = ".0"
+		}
+		suffix := "-only"
+		if m[4] != "" {
+			suffix = "-or-later"
+		}
+		return strings.ToUpper(m[1]) + "-" + m[2] + minor + suffix, false
+	}
+	if base, ok := strings.CutSuffix(name, "+"); ok {
+		if id, ok := spdxLicenses[strings.ToLower(base)]; ok {
+			return id + "+", false
+		}
+	}
+	return sanitizeRef(LicenseRefPrefix + name), true
+}
+
+var invalidRefChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// sanitizeRef replaces the characters that are not allowed in LicenseRef
+// identifiers.
+func sanitizeRef(ref string) string {
+	return invalidRefChars.ReplaceAllString(ref, "-")
+}
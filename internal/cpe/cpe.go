@@ -0,0 +1,158 @@
+// Package cpe generates the CPE 2.3 names of Ubuntu packages, which
+// vulnerability databases such as the NVD use to identify software.
+package cpe
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// Product is a vendor and product of CPE names.
+type Product struct {
+	Vendor  string
+	Product string
+}
+
+// Table maps binary or source package names to the products they are
+// known as in CPE names.
+type Table map[string][]Product
+
+//go:embed table.txt
+var bundledTable string
+
+var bundled = mustParseTable(bundledTable)
+
+func mustParseTable(data string) Table {
+	t, err := ParseTable([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Bundled returns the table bundled with ssbom. It must not be modified.
+func Bundled() Table {
+	return bundled
+}
+
+// ParseTable parses a table where every line gives a package name followed
+// by one or more vendor:product pairs, separated by spaces. Empty lines and
+// lines starting with "#" are ignored.
+func ParseTable(data []byte) (Table, error) {
+	t := make(Table)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("cannot parse CPE table: line %d: expected a package name and vendor:product pairs", i+1)
+		}
+		for _, pair := range fields[1:] {
+			vendor, product, ok := strings.Cut(pair, ":")
+			if !ok || vendor == "" || product == "" {
+				return nil, fmt.Errorf("cannot parse CPE table: line %d: invalid vendor:product pair %q", i+1, pair)
+			}
+			t[fields[0]] = append(t[fields[0]], Product{Vendor: vendor, Product: product})
+		}
+	}
+	return t, nil
+}
+
+// With returns a table with the entries of both tables, where the entries
+// of overrides replace those of t for the same package names.
+func (t Table) With(overrides Table) Table {
+	merged := make(Table, len(t)+len(overrides))
+	for name, products := range t {
+		merged[name] = products
+	}
+	for name, products := range overrides {
+		merged[name] = products
+	}
+	return merged
+}
+
+// CPEs returns the candidate CPE 2.3 names of a package given its name, the
+// name of its source package, if known, and its Debian version. The products
+// of the table for the package name come first, then those for the source
+// name. A package that is not in the table is assumed to be its own vendor
+// and product, under both names.
+func (t Table) CPEs(name, source, version string) []string {
+	var products []Product
+	for _, n := range []string{name, source} {
+		products = append(products, t[n]...)
+	}
+	if len(products) == 0 {
+		for _, n := range []string{source, name} {
+			if n != "" {
+				products = append(products, Product{Vendor: n, Product: n})
+			}
+		}
+	}
+	upstream := escape(UpstreamVersion(version))
+	var cpes []string
+	seen := make(map[string]bool)
+	for _, p := range products {
+		cpe := fmt.Sprintf("cpe:2.3:a:%s:%s:%s:*:*:*:*:*:*:*", escape(p.Vendor), escape(p.Product), upstream)
+		if !seen[cpe] {
+			seen[cpe] = true
+			cpes = append(cpes, cpe)
+		}
+	}
+	return cpes
+}
+
+// repackSuffixes mark the upstream versions of tarballs repacked by Debian,
+// such as "1.2.13.dfsg".
+var repackSuffixes = []string{"+dfsg", ".dfsg", "~dfsg", "+ds", "~ds", "+repack", "~repack"}
+
+// UpstreamVersion returns the upstream version of a Debian version: without
+// its epoch, its Debian revision and the suffixes of repacked tarballs. For
+// instance, the upstream version of "1:1.2.13.dfsg-1ubuntu4" is "1.2.13".
+func UpstreamVersion(version string) string {
+	if epoch, rest, ok := strings.Cut(version, ":"); ok && isDigits(epoch) {
+		version = rest
+	}
+	if i := strings.LastIndex(version, "-"); i >= 0 {
+		version = version[:i]
+	}
+	if _, really, ok := strings.Cut(version, "+really"); ok {
+		// The package holds an older upstream version, as in
+		// "2.4+really2.3".
+		version = really
+	}
+	for _, suffix := range repackSuffixes {
+		if i := strings.Index(version, suffix); i > 0 {
+			version = version[:i]
+		}
+	}
+	return version
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// escape quotes the characters that are special in the components of CPE
+// 2.3 formatted strings.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
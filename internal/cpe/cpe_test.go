@@ -0,0 +1,64 @@
+package cpe_test
+
+import (
+	"github.com/canonical/ssbom/internal/cpe"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestUpstreamVersion(c *C) {
+	for version, upstream := range map[string]string{
+		"3.0.2-0ubuntu1.10":       "3.0.2",
+		"1:1.2.13.dfsg-1ubuntu4":  "1.2.13",
+		"2.39-0ubuntu8.3":         "2.39",
+		"1:9.6p1-3ubuntu13.5":     "9.6p1",
+		"5.2.21-2ubuntu4":         "5.2.21",
+		"2024a-3ubuntu1":          "2024a",
+		"1.0":                     "1.0",
+		"2.4+really2.3-1":         "2.3",
+		"1.1.1+ds-1~ubuntu22.04":  "1.1.1",
+		"0.6.3+git20240101-2":     "0.6.3+git20240101",
+		"1:2.3.4+repack-1-ubuntu": "2.3.4",
+	} {
+		c.Check(cpe.UpstreamVersion(version), Equals, upstream, Commentf("version %s", version))
+	}
+}
+
+func (s *S) TestCPEs(c *C) {
+	table := cpe.Bundled()
+	c.Assert(table.CPEs("libssl3t64", "openssl", "3.0.13-0ubuntu3.4"), DeepEquals, []string{
+		"cpe:2.3:a:openssl:openssl:3.0.13:*:*:*:*:*:*:*",
+	})
+	c.Assert(table.CPEs("libcurl4t64", "curl", "8.5.0-2ubuntu10.6"), DeepEquals, []string{
+		"cpe:2.3:a:haxx:libcurl:8.5.0:*:*:*:*:*:*:*",
+		"cpe:2.3:a:haxx:curl:8.5.0:*:*:*:*:*:*:*",
+	})
+	// Unknown packages are their own vendor and product, under both names.
+	c.Assert(table.CPEs("libfoo1", "foo", "1:1.0+git1-1"), DeepEquals, []string{
+		"cpe:2.3:a:foo:foo:1.0\\+git1:*:*:*:*:*:*:*",
+		"cpe:2.3:a:libfoo1:libfoo1:1.0\\+git1:*:*:*:*:*:*:*",
+	})
+	c.Assert(table.CPEs("hello", "", "2.10-3build1"), DeepEquals, []string{
+		"cpe:2.3:a:hello:hello:2.10:*:*:*:*:*:*:*",
+	})
+
+	overrides, err := cpe.ParseTable([]byte("# Local packages.\n\nlibfoo1 example:foo example:libfoo\nlibssl3t64 example:ssl\n"))
+	c.Assert(err, IsNil)
+	table = table.With(overrides)
+	c.Assert(table.CPEs("libfoo1", "", "1.0-1"), DeepEquals, []string{
+		"cpe:2.3:a:example:foo:1.0:*:*:*:*:*:*:*",
+		"cpe:2.3:a:example:libfoo:1.0:*:*:*:*:*:*:*",
+	})
+	c.Assert(table.CPEs("libssl3t64", "openssl", "3.0.13-0ubuntu3.4"), DeepEquals, []string{
+		"cpe:2.3:a:example:ssl:3.0.13:*:*:*:*:*:*:*",
+		"cpe:2.3:a:openssl:openssl:3.0.13:*:*:*:*:*:*:*",
+	})
+	// The bundled table is left as it is.
+	c.Assert(cpe.Bundled()["libssl3t64"], DeepEquals, []cpe.Product{{Vendor: "openssl", Product: "openssl"}})
+}
+
+func (s *S) TestParseTableErrors(c *C) {
+	_, err := cpe.ParseTable([]byte("libfoo1\n"))
+	c.Assert(err, ErrorMatches, `cannot parse CPE table: line 1: expected a package name and vendor:product pairs`)
+	_, err = cpe.ParseTable([]byte("# comment\nlibfoo1 foo\n"))
+	c.Assert(err, ErrorMatches, `cannot parse CPE table: line 2: invalid vendor:product pair "foo"`)
+}
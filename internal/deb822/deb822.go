@@ -0,0 +1,69 @@
+// Package deb822 reads the control file format of Debian, used by copyright
+// files and archive indexes.
+// See https://manpages.ubuntu.com/manpages/man5/deb822.5.html
+package deb822
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Paragraph is a paragraph of a control file, by canonical field name. The
+// values of multi-line fields keep their lines, without the leading space,
+// and lines made of a single "." are empty lines.
+type Paragraph map[string]string
+
+// Parse reads the paragraphs of a control file.
+func Parse(r io.Reader) ([]Paragraph, error) {
+	var paragraphs []Paragraph
+	var current Paragraph
+	var field string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case line == "":
+			current, field = nil, ""
+		case line[0] == '#':
+		case line[0] == ' ' || line[0] == '\t':
+			if current == nil || field == "" {
+				return nil, fmt.Errorf("line %d: continuation line outside of a field", lineNum)
+			}
+			line = strings.TrimSpace(line)
+			if line == "." {
+				line = ""
+			}
+			current[field] += "\n" + line
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a field", lineNum)
+			}
+			if current == nil {
+				current = make(Paragraph)
+				paragraphs = append(paragraphs, current)
+			}
+			field = CanonicalField(name)
+			current[field] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return paragraphs, nil
+}
+
+// CanonicalField returns the canonical form of a field name, as field names
+// are case-insensitive: "upstream-name" is "Upstream-Name".
+func CanonicalField(name string) string {
+	parts := strings.Split(strings.TrimSpace(name), "-")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		}
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,191 @@
+// Package diff compares the packages and files described by two SBOMs.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/canonical/ssbom/internal/format"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx"
+)
+
+// Inventory holds the packages (name to version) and files (path to sha256)
+// described by an SBOM, independently of its format.
+type Inventory struct {
+	Packages map[string]string
+	Files    map[string]string
+}
+
+// Read reads an SBOM in any of the supported formats into an Inventory.
+func Read(data []byte) (*Inventory, error) {
+	f, err := format.Detect(data)
+	if err != nil {
+		return nil, err
+	}
+	inv := &Inventory{
+		Packages: make(map[string]string),
+		Files:    make(map[string]string),
+	}
+	switch f {
+	case format.SPDX:
+		err = inv.readSPDX(data)
+	case format.SPDX3:
+		err = inv.readSPDX3(data)
+	case format.CycloneDX, format.CycloneDX15:
+		err = inv.readCycloneDX(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s document: %s", f, err)
+	}
+	return inv, nil
+}
+
+func (inv *Inventory) readSPDX(data []byte) error {
+	doc, err := spdxjson.Read(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for _, p := range doc.Packages {
+		inv.Packages[p.PackageName] = p.PackageVersion
+	}
+	for _, f := range doc.Files {
+		inv.Files[f.FileName] = checksum(f.Checksums)
+	}
+	return nil
+}
+
+func checksum(checksums []spdx.Checksum) string {
+	for _, c := range checksums {
+		if c.Algorithm == spdx.SHA256 {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func (inv *Inventory) readSPDX3(data []byte) error {
+	var doc struct {
+		Graph []json.RawMessage `json:"@graph"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for _, raw := range doc.Graph {
+		var element spdx3.Element
+		if err := json.Unmarshal(raw, &element); err != nil {
+			return err
+		}
+		switch element.Type {
+		case spdx3.TypePackage:
+			var pkg spdx3.Package
+			if err := json.Unmarshal(raw, &pkg); err != nil {
+				return err
+			}
+			inv.Packages[pkg.Name] = pkg.PackageVersion
+		case spdx3.TypeFile:
+			var file spdx3.File
+			if err := json.Unmarshal(raw, &file); err != nil {
+				return err
+			}
+			inv.Files[file.Name] = ""
+			for _, h := range file.VerifiedUsing {
+				if h.Algorithm == "sha256" {
+					inv.Files[file.Name] = h.HashValue
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (inv *Inventory) readCycloneDX(data []byte) error {
+	var bom cyclonedx.BOM
+	if err := json.Unmarshal(data, &bom); err != nil {
+		return err
+	}
+	var walk func(components []*cyclonedx.Component)
+	walk = func(components []*cyclonedx.Component) {
+		for _, c := range components {
+			if c.Type == cyclonedx.TypeFile {
+				inv.Files[c.Name] = ""
+				for _, h := range c.Hashes {
+					if h.Algorithm == cyclonedx.SHA256 {
+						inv.Files[c.Name] = h.Content
+					}
+				}
+			} else {
+				inv.Packages[c.Name] = c.Version
+			}
+			walk(c.Components)
+		}
+	}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		walk([]*cyclonedx.Component{bom.Metadata.Component})
+	}
+	walk(bom.Components)
+	return nil
+}
+
+// Change kinds.
+const (
+	Added   = "+"
+	Removed = "-"
+	Changed = "~"
+)
+
+// Change is a difference between two inventories.
+type Change struct {
+	Kind string
+	// Type is either "package" or "file".
+	Type string
+	Name string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	var s string
+	switch c.Kind {
+	case Added:
+		s = fmt.Sprintf("%s %s %s %s", c.Kind, c.Type, c.Name, c.New)
+	case Removed:
+		s = fmt.Sprintf("%s %s %s %s", c.Kind, c.Type, c.Name, c.Old)
+	default:
+		s = fmt.Sprintf("%s %s %s %s -> %s", c.Kind, c.Type, c.Name, c.Old, c.New)
+	}
+	return strings.TrimSpace(s)
+}
+
+// Compare returns the changes from old to new, packages first, each sorted
+// by name.
+func Compare(old, new *Inventory) []Change {
+	changes := compareMaps("package", old.Packages, new.Packages)
+	return append(changes, compareMaps("file", old.Files, new.Files)...)
+}
+
+func compareMaps(kind string, old, new map[string]string) []Change {
+	var changes []Change
+	for name, o := range old {
+		n, ok := new[name]
+		if !ok {
+			changes = append(changes, Change{Kind: Removed, Type: kind, Name: name, Old: o})
+		} else if n != o {
+			changes = append(changes, Change{Kind: Changed, Type: kind, Name: name, Old: o, New: n})
+		}
+	}
+	for name, n := range new {
+		if _, ok := old[name]; !ok {
+			changes = append(changes, Change{Kind: Added, Type: kind, Name: name, New: n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
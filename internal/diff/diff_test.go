@@ -0,0 +1,93 @@
+package diff_test
+
+import (
+	"github.com/canonical/ssbom/internal/diff"
+	. "gopkg.in/check.v1"
+)
+
+const spdxSample = `{
+	"spdxVersion": "SPDX-2.3",
+	"dataLicense": "CC0-1.0",
+	"SPDXID": "SPDXRef-DOCUMENT",
+	"name": "test",
+	"documentNamespace": "https://example.com/test",
+	"creationInfo": {"creators": ["Tool: test"], "created": "2024-01-01T00:00:00Z"},
+	"packages": [
+		{"name": "test", "SPDXID": "SPDXRef-Package-test", "versionInfo": "1.0", "downloadLocation": "NOASSERTION"},
+		{"name": "gone", "SPDXID": "SPDXRef-Package-gone", "versionInfo": "1.0", "downloadLocation": "NOASSERTION"}
+	],
+	"files": [
+		{"fileName": "/test", "SPDXID": "SPDXRef-File-test", "checksums": [{"algorithm": "SHA256", "checksumValue": "sha256"}]}
+	]
+}`
+
+const cycloneDXSample = `{
+	"bomFormat": "CycloneDX",
+	"specVersion": "1.6",
+	"version": 1,
+	"components": [
+		{"type": "library", "name": "test", "version": "1.1", "components": [
+			{"type": "library", "name": "test_slice", "components": [
+				{"type": "file", "name": "/test", "hashes": [{"alg": "SHA-256", "content": "final_sha256"}]}
+			]}
+		]}
+	]
+}`
+
+const spdx3Sample = `{
+	"@context": "https://spdx.org/rdf/3.0.1/spdx-context.jsonld",
+	"@graph": [
+		{"type": "software_Package", "spdxId": "https://example.com#test", "name": "test", "software_packageVersion": "1.0"},
+		{"type": "software_File", "spdxId": "https://example.com#file", "name": "/test", "verifiedUsing": [{"type": "Hash", "algorithm": "sha256", "hashValue": "sha256"}]}
+	]
+}`
+
+func (s *S) TestRead(c *C) {
+	inv, err := diff.Read([]byte(spdxSample))
+	c.Assert(err, IsNil)
+	c.Assert(inv, DeepEquals, &diff.Inventory{
+		Packages: map[string]string{"test": "1.0", "gone": "1.0"},
+		Files:    map[string]string{"/test": "sha256"},
+	})
+
+	inv, err = diff.Read([]byte(spdx3Sample))
+	c.Assert(err, IsNil)
+	c.Assert(inv, DeepEquals, &diff.Inventory{
+		Packages: map[string]string{"test": "1.0"},
+		Files:    map[string]string{"/test": "sha256"},
+	})
+
+	inv, err = diff.Read([]byte(cycloneDXSample))
+	c.Assert(err, IsNil)
+	c.Assert(inv, DeepEquals, &diff.Inventory{
+		Packages: map[string]string{"test": "1.1", "test_slice": ""},
+		Files:    map[string]string{"/test": "final_sha256"},
+	})
+}
+
+func (s *S) TestCompare(c *C) {
+	old, err := diff.Read([]byte(spdxSample))
+	c.Assert(err, IsNil)
+	new, err := diff.Read([]byte(cycloneDXSample))
+	c.Assert(err, IsNil)
+
+	changes := diff.Compare(old, new)
+	c.Assert(changes, DeepEquals, []diff.Change{
+		{Kind: diff.Removed, Type: "package", Name: "gone", Old: "1.0"},
+		{Kind: diff.Changed, Type: "package", Name: "test", Old: "1.0", New: "1.1"},
+		{Kind: diff.Added, Type: "package", Name: "test_slice"},
+		{Kind: diff.Changed, Type: "file", Name: "/test", Old: "sha256", New: "final_sha256"},
+	})
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	c.Assert(lines, DeepEquals, []string{
+		"- package gone 1.0",
+		"~ package test 1.0 -> 1.1",
+		"+ package test_slice",
+		"~ file /test sha256 -> final_sha256",
+	})
+
+	c.Assert(diff.Compare(old, old), HasLen, 0)
+}
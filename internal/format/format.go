@@ -0,0 +1,67 @@
+// Package format identifies the SBOM formats supported by ssbom.
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Format string
+
+const (
+	SPDX        Format = "spdx"
+	SPDX3       Format = "spdx3"
+	CycloneDX   Format = "cyclonedx"
+	CycloneDX15 Format = "cyclonedx-1.5"
+)
+
+// Formats lists the supported output formats.
+var Formats = []Format{SPDX, SPDX3, CycloneDX, CycloneDX15}
+
+// Parse returns the format with the given name.
+func Parse(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", name)
+}
+
+// DefaultFileName returns the file name used for documents of the format
+// when no output file is given.
+func (f Format) DefaultFileName() string {
+	switch f {
+	case SPDX3:
+		return "manifest.spdx3.json"
+	case CycloneDX, CycloneDX15:
+		return "manifest.cdx.json"
+	default:
+		return "manifest.spdx.json"
+	}
+}
+
+// Detect returns the format of a JSON encoded SBOM.
+func Detect(data []byte) (Format, error) {
+	var probe struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Context     any    `json:"@context"`
+		BOMFormat   string `json:"bomFormat"`
+		SpecVersion string `json:"specVersion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", fmt.Errorf("cannot detect document format: %s", err)
+	}
+	switch {
+	case strings.HasPrefix(probe.SPDXVersion, "SPDX-2."):
+		return SPDX, nil
+	case probe.BOMFormat == "CycloneDX" && probe.SpecVersion == "1.5":
+		return CycloneDX15, nil
+	case probe.BOMFormat == "CycloneDX":
+		return CycloneDX, nil
+	case probe.Context != nil && strings.Contains(fmt.Sprint(probe.Context), "spdx.org/rdf/3."):
+		return SPDX3, nil
+	}
+	return "", fmt.Errorf("cannot detect document format: not an SPDX or CycloneDX JSON document")
+}
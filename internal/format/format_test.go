@@ -0,0 +1,63 @@
+package format_test
+
+import (
+	"github.com/canonical/ssbom/internal/format"
+	. "gopkg.in/check.v1"
+)
+
+var detectTests = []struct {
+	summary string
+	data    string
+	format  format.Format
+	error   string
+}{
+	{
+		summary: "Detects SPDX 2.3",
+		data:    `{"spdxVersion":"SPDX-2.3"}`,
+		format:  format.SPDX,
+	}, {
+		summary: "Detects SPDX 3.0",
+		data:    `{"@context":"https://spdx.org/rdf/3.0.1/spdx-context.jsonld","@graph":[]}`,
+		format:  format.SPDX3,
+	}, {
+		summary: "Detects CycloneDX 1.6",
+		data:    `{"bomFormat":"CycloneDX","specVersion":"1.6"}`,
+		format:  format.CycloneDX,
+	}, {
+		summary: "Detects CycloneDX 1.5",
+		data:    `{"bomFormat":"CycloneDX","specVersion":"1.5"}`,
+		format:  format.CycloneDX15,
+	}, {
+		summary: "Rejects unknown documents",
+		data:    `{"name":"foo"}`,
+		error:   "cannot detect document format: not an SPDX or CycloneDX JSON document",
+	}, {
+		summary: "Rejects invalid JSON",
+		data:    `{`,
+		error:   "cannot detect document format: .*",
+	},
+}
+
+func (s *S) TestDetect(c *C) {
+	for _, test := range detectTests {
+		c.Logf("Running test: %s", test.summary)
+		f, err := format.Detect([]byte(test.data))
+		if test.error != "" {
+			c.Assert(err, ErrorMatches, test.error)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Assert(f, Equals, test.format)
+	}
+}
+
+func (s *S) TestParse(c *C) {
+	for _, f := range format.Formats {
+		parsed, err := format.Parse(string(f))
+		c.Assert(err, IsNil)
+		c.Assert(parsed, Equals, f)
+	}
+	_, err := format.Parse("xml")
+	c.Assert(err, ErrorMatches, `unknown format "xml"`)
+	c.Assert(format.CycloneDX15.DefaultFileName(), Equals, "manifest.cdx.json")
+}
@@ -0,0 +1,154 @@
+// Package gobinary finds the Go executables of a rootfs and reads the module
+// information that the Go toolchain embeds in them.
+package gobinary
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/canonical/ssbom/internal/rootfs"
+)
+
+// Module is a Go module linked into an executable.
+type Module struct {
+	Path    string
+	Version string
+	// Sum is the go.sum hash of the module, if known.
+	Sum string
+}
+
+// Binary is a Go executable.
+type Binary struct {
+	// Path is the absolute path of the executable.
+	Path   string
+	SHA256 string
+	// GoVersion is the version of the toolchain that built the executable,
+	// such as "go1.22.1".
+	GoVersion string
+	// Main is the module of the main package. Its version is empty when it
+	// was not built from a tagged module version.
+	Main Module
+	// Deps lists the other modules linked into the executable, after
+	// replacements.
+	Deps []Module
+}
+
+// elfMagic starts every ELF file. Only ELF executables are scanned, as they
+// are the only ones expected in an Ubuntu rootfs.
+var elfMagic = []byte("\x7fELF")
+
+// Scan returns the Go executables of fsys, in lexical order. Executables
+// are regular files with an execute permission bit set.
+func Scan(ctx context.Context, fsys fs.FS) ([]*Binary, error) {
+	var binaries []*Binary
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0111 == 0 {
+			return nil
+		}
+		binary, err := readBinary(fsys, name)
+		if err != nil {
+			return fmt.Errorf("cannot read /%s: %w", name, err)
+		}
+		if binary != nil {
+			binaries = append(binaries, binary)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot scan Go executables: %w", err)
+	}
+	return binaries, nil
+}
+
+// readBinary returns the Go executable at name, or nil if it is not one.
+func readBinary(fsys fs.FS, name string) (*Binary, error) {
+	f, err := fsys.Open(name)
+	if errors.Is(err, rootfs.ErrContentNotLoaded) {
+		// Only the content of ELF executables is kept from archives.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(elfMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, elfMagic) {
+		return nil, nil
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, ok := f.(io.ReaderAt)
+	if !ok {
+		// Files of generic file systems may not support random access:
+		// copy them to a temporary file rather than to memory.
+		tmp, err := os.CreateTemp("", "ssbom-binary-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, io.MultiReader(bytes.NewReader(magic), f)); err != nil {
+			return nil, err
+		}
+		r = tmp
+	}
+	info, err := buildinfo.Read(r)
+	if err != nil {
+		// Not built by Go, or stripped of its build information.
+		return nil, nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, stat.Size())); err != nil {
+		return nil, err
+	}
+	binary := &Binary{
+		Path:      "/" + name,
+		SHA256:    hex.EncodeToString(h.Sum(nil)),
+		GoVersion: info.GoVersion,
+		Main:      Module{Path: info.Main.Path, Version: info.Main.Version, Sum: info.Main.Sum},
+	}
+	if binary.Main.Version == "(devel)" {
+		binary.Main.Version = ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		binary.Deps = append(binary.Deps, Module{Path: dep.Path, Version: dep.Version, Sum: dep.Sum})
+	}
+	return binary, nil
+}
+
+// StdlibVersion returns the version of the standard library linked into the
+// executable, without the "go" prefix, as vulnerability databases expect.
+func (b *Binary) StdlibVersion() string {
+	version := strings.TrimPrefix(b.GoVersion, "go")
+	// Drop experiments and other build suffixes, as in "1.22.1 X:boringcrypto".
+	version, _, _ = strings.Cut(version, " ")
+	return version
+}
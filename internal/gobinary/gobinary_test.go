@@ -0,0 +1,98 @@
+package gobinary_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing/fstest"
+
+	"github.com/canonical/ssbom/internal/gobinary"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestScan(c *C) {
+	// The test executable is itself built by Go.
+	exe, err := os.Executable()
+	c.Assert(err, IsNil)
+	data, err := os.ReadFile(exe)
+	c.Assert(err, IsNil)
+	digest := sha256.Sum256(data)
+
+	fsys := fstest.MapFS{
+		"usr/bin/gotool":   &fstest.MapFile{Data: data, Mode: 0755},
+		"usr/lib/gotool":   &fstest.MapFile{Data: data, Mode: 0644},
+		"usr/bin/script":   &fstest.MapFile{Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"usr/bin/notgo":    &fstest.MapFile{Data: []byte("\x7fELF not really"), Mode: 0755},
+		"usr/bin/empty":    &fstest.MapFile{Mode: 0755},
+		"usr/share/readme": &fstest.MapFile{Data: []byte("hello\n"), Mode: 0644},
+	}
+	binaries, err := gobinary.Scan(context.Background(), fsys)
+	c.Assert(err, IsNil)
+	c.Assert(binaries, HasLen, 1)
+
+	binary := binaries[0]
+	c.Assert(binary.Path, Equals, "/usr/bin/gotool")
+	c.Assert(binary.SHA256, Equals, hex.EncodeToString(digest[:]))
+	c.Assert(binary.GoVersion, Equals, runtime.Version())
+	c.Assert(strings.HasPrefix(runtime.Version(), "go"+binary.StdlibVersion()), Equals, true)
+	var checkDep gobinary.Module
+	for _, dep := range binary.Deps {
+		if dep.Path == "gopkg.in/check.v1" {
+			checkDep = dep
+		}
+	}
+	c.Assert(checkDep.Version, Not(Equals), "")
+}
+
+// sequentialFS hides the random access of the files of a FS.
+type sequentialFS struct{ fs.FS }
+
+func (s sequentialFS) Open(name string) (fs.File, error) {
+	f, err := s.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.ReadDirFile }{f.(fs.ReadDirFile)}, nil
+}
+
+func (s *S) TestScanReaders(c *C) {
+	exe, err := os.Executable()
+	c.Assert(err, IsNil)
+	data, err := os.ReadFile(exe)
+	c.Assert(err, IsNil)
+	digest := sha256.Sum256(data)
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "gotool"), data, 0755), IsNil)
+
+	// Files of directories are read in place; other files are read whole.
+	for _, fsys := range []fs.FS{os.DirFS(dir), sequentialFS{os.DirFS(dir)}} {
+		binaries, err := gobinary.Scan(context.Background(), fsys)
+		c.Assert(err, IsNil)
+		c.Assert(binaries, HasLen, 1)
+		c.Assert(binaries[0].SHA256, Equals, hex.EncodeToString(digest[:]))
+	}
+}
+
+func (s *S) TestScanCancelled(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fsys := fstest.MapFS{"usr/bin/script": &fstest.MapFile{Data: []byte("#!/bin/sh\n"), Mode: 0755}}
+	_, err := gobinary.Scan(ctx, fsys)
+	c.Assert(err, ErrorMatches, "cannot scan Go executables: context canceled")
+}
+
+func (s *S) TestStdlibVersion(c *C) {
+	for _, test := range []struct{ goVersion, version string }{
+		{"go1.22.1", "1.22.1"},
+		{"go1.21.0 X:boringcrypto", "1.21.0"},
+		{"", ""},
+	} {
+		binary := &gobinary.Binary{GoVersion: test.goVersion}
+		c.Assert(binary.StdlibVersion(), Equals, test.version)
+	}
+}
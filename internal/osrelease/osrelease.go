@@ -0,0 +1,95 @@
+// Package osrelease reads os-release files, which identify the distribution
+// of a rootfs. See https://www.freedesktop.org/software/systemd/man/latest/os-release.html
+package osrelease
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// DefaultID is the ID of the distribution when the os-release file does not
+// give one, as chisel rootfs are most often cut from Ubuntu archives.
+const DefaultID = "ubuntu"
+
+// OSRelease holds the fields of an os-release file.
+type OSRelease struct {
+	// ID identifies the distribution in lower case, as in "ubuntu" or
+	// "debian".
+	ID string
+	// IDLike lists the IDs of the distributions this one derives from.
+	IDLike          []string
+	Name            string
+	PrettyName      string
+	VersionID       string
+	VersionCodename string
+	HomeURL         string
+}
+
+// Parse parses an os-release file.
+func Parse(data []byte) (*OSRelease, error) {
+	cfg, err := ini.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse os-release: %w", err)
+	}
+	section := cfg.Section("")
+	osRelease := &OSRelease{
+		ID:              strings.ToLower(section.Key("ID").String()),
+		IDLike:          strings.Fields(section.Key("ID_LIKE").String()),
+		Name:            section.Key("NAME").String(),
+		PrettyName:      section.Key("PRETTY_NAME").String(),
+		VersionID:       section.Key("VERSION_ID").String(),
+		VersionCodename: section.Key("VERSION_CODENAME").String(),
+		HomeURL:         section.Key("HOME_URL").String(),
+	}
+	if osRelease.ID == "" {
+		osRelease.ID = DefaultID
+	}
+	return osRelease, nil
+}
+
+// Version returns the version of the distribution: its VERSION_ID, or its
+// VERSION_CODENAME for distributions without versions, such as Debian
+// testing.
+func (r *OSRelease) Version() string {
+	if r.VersionID != "" {
+		return r.VersionID
+	}
+	return r.VersionCodename
+}
+
+// Distro returns the value of the distro qualifier of the package URLs of
+// the distribution, as in "ubuntu-24.04", or "" if it has no version.
+func (r *OSRelease) Distro() string {
+	if r.Version() == "" {
+		return ""
+	}
+	return r.ID + "-" + r.Version()
+}
+
+// IsLike reports whether the distribution is, or derives from, the one with
+// the given ID.
+func (r *OSRelease) IsLike(id string) bool {
+	if r.ID == id {
+		return true
+	}
+	for _, like := range r.IDLike {
+		if like == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Family returns the ID of the distribution the packages come from: "ubuntu"
+// or "debian" for them and the distributions deriving from them, which list
+// them in ID_LIKE, or the ID of other distributions.
+func (r *OSRelease) Family() string {
+	for _, id := range []string{"ubuntu", "debian"} {
+		if r.IsLike(id) {
+			return id
+		}
+	}
+	return r.ID
+}
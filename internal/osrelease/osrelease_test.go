@@ -0,0 +1,69 @@
+package osrelease_test
+
+import (
+	"github.com/canonical/ssbom/internal/osrelease"
+	. "gopkg.in/check.v1"
+)
+
+const ubuntuOSRelease = `PRETTY_NAME="Ubuntu 24.04.1 LTS"
+NAME="Ubuntu"
+VERSION_ID="24.04"
+VERSION="24.04.1 LTS (Noble Numbat)"
+VERSION_CODENAME=noble
+ID=ubuntu
+ID_LIKE=debian
+HOME_URL="https://www.ubuntu.com/"
+UBUNTU_CODENAME=noble
+`
+
+func (s *S) TestParse(c *C) {
+	r, err := osrelease.Parse([]byte(ubuntuOSRelease))
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, &osrelease.OSRelease{
+		ID:              "ubuntu",
+		IDLike:          []string{"debian"},
+		Name:            "Ubuntu",
+		PrettyName:      "Ubuntu 24.04.1 LTS",
+		VersionID:       "24.04",
+		VersionCodename: "noble",
+		HomeURL:         "https://www.ubuntu.com/",
+	})
+	c.Assert(r.Version(), Equals, "24.04")
+	c.Assert(r.Distro(), Equals, "ubuntu-24.04")
+	c.Assert(r.IsLike("debian"), Equals, true)
+	c.Assert(r.IsLike("ubuntu"), Equals, true)
+	c.Assert(r.IsLike("fedora"), Equals, false)
+	c.Assert(r.Family(), Equals, "ubuntu")
+}
+
+func (s *S) TestFamily(c *C) {
+	for data, family := range map[string]string{
+		"ID=debian\n":                              "debian",
+		"ID=pop\nID_LIKE=\"ubuntu debian\"\n":      "ubuntu",
+		"ID=raspbian\nID_LIKE=debian\n":            "debian",
+		"ID=fedora\n":                              "fedora",
+		"ID=rocky\nID_LIKE=\"rhel centos fedora\"": "rocky",
+	} {
+		r, err := osrelease.Parse([]byte(data))
+		c.Assert(err, IsNil)
+		c.Assert(r.Family(), Equals, family, Commentf("%q", data))
+	}
+}
+
+func (s *S) TestParseDebianTesting(c *C) {
+	r, err := osrelease.Parse([]byte("PRETTY_NAME=\"Debian GNU/Linux trixie/sid\"\nNAME=\"Debian GNU/Linux\"\nVERSION_CODENAME=trixie\nID=debian\n"))
+	c.Assert(err, IsNil)
+	c.Assert(r.Version(), Equals, "trixie")
+	c.Assert(r.Distro(), Equals, "debian-trixie")
+}
+
+func (s *S) TestParseDefaults(c *C) {
+	r, err := osrelease.Parse([]byte("VERSION_ID=22.04\n"))
+	c.Assert(err, IsNil)
+	c.Assert(r.ID, Equals, osrelease.DefaultID)
+	c.Assert(r.Distro(), Equals, "ubuntu-22.04")
+
+	r, err = osrelease.Parse(nil)
+	c.Assert(err, IsNil)
+	c.Assert(r.Distro(), Equals, "")
+}
@@ -0,0 +1,82 @@
+// Package pro identifies the packages that come from the archives of Ubuntu
+// Pro, such as Expanded Security Maintenance (ESM) and FIPS, rather than
+// from the Ubuntu archive.
+package pro
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pocket is an Ubuntu Pro archive, named as in "pro enable".
+type Pocket string
+
+const (
+	ESMInfra    Pocket = "esm-infra"
+	ESMApps     Pocket = "esm-apps"
+	FIPS        Pocket = "fips"
+	FIPSUpdates Pocket = "fips-updates"
+	FIPSPreview Pocket = "fips-preview"
+)
+
+var repositoryURLs = map[Pocket]string{
+	ESMInfra:    "https://esm.ubuntu.com/infra/ubuntu",
+	ESMApps:     "https://esm.ubuntu.com/apps/ubuntu",
+	FIPS:        "https://esm.ubuntu.com/fips/ubuntu",
+	FIPSUpdates: "https://esm.ubuntu.com/fips-updates/ubuntu",
+	FIPSPreview: "https://esm.ubuntu.com/fips-preview/ubuntu",
+}
+
+// ParsePocket returns the pocket with the given name.
+func ParsePocket(name string) (Pocket, error) {
+	p := Pocket(name)
+	if _, ok := repositoryURLs[p]; !ok {
+		return "", fmt.Errorf("unknown Ubuntu Pro pocket %q, expected one of esm-infra, esm-apps, fips, fips-updates or fips-preview", name)
+	}
+	return p, nil
+}
+
+// RepositoryURL returns the URL of the apt archive of the pocket.
+func (p Pocket) RepositoryURL() string {
+	return repositoryURLs[p]
+}
+
+func (p Pocket) isESM() bool {
+	return strings.HasPrefix(string(p), "esm-")
+}
+
+func (p Pocket) isFIPS() bool {
+	return strings.HasPrefix(string(p), "fips")
+}
+
+var (
+	esmVersion  = regexp.MustCompile(`[+~]esm\d*`)
+	fipsVersion = regexp.MustCompile(`(?i)[.+~]fips`)
+)
+
+// Detect returns the pocket a package comes from, or "" if it comes from
+// the Ubuntu archive, given its version and, if known, its archive
+// component. ESM and FIPS versions are recognized by their "+esm" and
+// "fips" suffixes. The label, if given, names the pocket the packages of
+// its kind come from; otherwise ESM packages of the universe component come
+// from esm-apps, other ESM packages from esm-infra and FIPS packages from
+// fips.
+func Detect(version, component string, label Pocket) Pocket {
+	switch {
+	case fipsVersion.MatchString(version):
+		if label.isFIPS() {
+			return label
+		}
+		return FIPS
+	case esmVersion.MatchString(version):
+		if label.isESM() {
+			return label
+		}
+		if component == "universe" || component == "multiverse" {
+			return ESMApps
+		}
+		return ESMInfra
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package pro_test
+
+import (
+	"github.com/canonical/ssbom/internal/pro"
+	. "gopkg.in/check.v1"
+)
+
+var detectTests = []struct {
+	version   string
+	component string
+	label     pro.Pocket
+	pocket    pro.Pocket
+}{
+	{"3.0.2-0ubuntu1.15", "main", "", ""},
+	{"3.0.2-0ubuntu1.15", "main", pro.ESMInfra, ""},
+	{"1.1.1f-1ubuntu2.22+esm1", "main", "", pro.ESMInfra},
+	{"1.1.1f-1ubuntu2.22+esm1", "", "", pro.ESMInfra},
+	{"2.7.18-1~20.04.7+esm2", "universe", "", pro.ESMApps},
+	{"2.7.18-1~20.04.7+esm2", "main", pro.ESMApps, pro.ESMApps},
+	{"2.7.18-1~20.04.7+esm2", "main", pro.FIPSUpdates, pro.ESMInfra},
+	{"3.0.5-0ubuntu0.1+Fips2.1", "main", "", pro.FIPS},
+	{"1.1.1f-1ubuntu2.fips.2.1", "main", pro.FIPSUpdates, pro.FIPSUpdates},
+	{"1.1.1f-1ubuntu2.fips.2.1~esm1", "main", pro.ESMInfra, pro.FIPS},
+}
+
+func (s *S) TestDetect(c *C) {
+	for _, test := range detectTests {
+		pocket := pro.Detect(test.version, test.component, test.label)
+		c.Check(pocket, Equals, test.pocket, Commentf("version %s, component %s, label %s", test.version, test.component, test.label))
+	}
+}
+
+func (s *S) TestParsePocket(c *C) {
+	pocket, err := pro.ParsePocket("fips-updates")
+	c.Assert(err, IsNil)
+	c.Assert(pocket, Equals, pro.FIPSUpdates)
+	c.Assert(pocket.RepositoryURL(), Equals, "https://esm.ubuntu.com/fips-updates/ubuntu")
+
+	_, err = pro.ParsePocket("esm")
+	c.Assert(err, ErrorMatches, `unknown Ubuntu Pro pocket "esm", .*`)
+}
@@ -0,0 +1,12 @@
+package rootfs
+
+// Spooled tells whether the content of the named file of an archived rootfs
+// is held in a temporary file rather than in memory.
+func Spooled(fsys FS, name string) bool {
+	m, ok := fsys.(*memFS)
+	if !ok {
+		return false
+	}
+	e, err := m.lookup("open", name, true)
+	return err == nil && e.spooled && e.data == nil
+}
@@ -0,0 +1,249 @@
+package rootfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memEntry is a file, directory or symbolic link of a memFS.
+type memEntry struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	link    string
+	data    []byte
+	loaded  bool
+	// spooled tells that the content is in the spool file of the memFS,
+	// at offset, rather than in data.
+	spooled bool
+	offset  int64
+	// sha1 and sha256 are the digests of the content of regular files,
+	// if computed while reading them.
+	sha1   string
+	sha256 string
+}
+
+func (e *memEntry) Name() string               { return path.Base(e.name) }
+func (e *memEntry) Size() int64                { return e.size }
+func (e *memEntry) Mode() fs.FileMode          { return e.mode }
+func (e *memEntry) ModTime() time.Time         { return e.modTime }
+func (e *memEntry) IsDir() bool                { return e.mode.IsDir() }
+func (e *memEntry) Sys() any                   { return nil }
+func (e *memEntry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *memEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+// memFS is a FS whose entries are held in memory. Parent directories of
+// entries are implied when missing.
+type memFS struct {
+	entries map[string]*memEntry
+	// children indexes the entries by parent directory. It is built on
+	// demand and dropped whenever entries change.
+	children map[string][]fs.DirEntry
+	// spool is the temporary file holding the content of the files that
+	// are kept but too large to hold in memory, and spoolSize its size.
+	spool     *os.File
+	spoolSize int64
+}
+
+func newMemFS() *memFS {
+	return &memFS{entries: map[string]*memEntry{
+		".": {name: ".", mode: fs.ModeDir | 0755},
+	}}
+}
+
+// add adds an entry and any missing parent directory.
+func (m *memFS) add(e *memEntry) {
+	for dir := path.Dir(e.name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := m.entries[dir]; ok {
+			break
+		}
+		m.entries[dir] = &memEntry{name: dir, mode: fs.ModeDir | 0755}
+	}
+	m.entries[e.name] = e
+	m.children = nil
+}
+
+// remove removes the named entry and everything under it.
+func (m *memFS) remove(name string) {
+	delete(m.entries, name)
+	m.removeChildren(name)
+}
+
+// get returns the named entry without resolving symbolic links.
+func (m *memFS) get(name string) *memEntry {
+	return m.entries[name]
+}
+
+// removeChildren removes everything under the named directory.
+func (m *memFS) removeChildren(name string) {
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	for n := range m.entries {
+		if n != "." && strings.HasPrefix(n, prefix) {
+			delete(m.entries, n)
+		}
+	}
+	m.children = nil
+}
+
+func (m *memFS) lookup(op, name string, follow bool) (*memEntry, error) {
+	if follow {
+		resolved, err := resolve(m, op, name)
+		if err != nil {
+			return nil, err
+		}
+		name = resolved
+	} else if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	} else if name != "." {
+		parent, err := resolve(m, op, path.Dir(name))
+		if err != nil {
+			return nil, err
+		}
+		name = path.Join(parent, path.Base(name))
+	}
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	e, err := m.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if e.IsDir() {
+		entries, err := m.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &memDir{entry: e, entries: entries}, nil
+	}
+	if !e.loaded {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrContentNotLoaded}
+	}
+	if e.spooled {
+		return &memFile{entry: e, content: io.NewSectionReader(m.spool, e.offset, e.size)}, nil
+	}
+	return &memFile{entry: e, content: bytes.NewReader(e.data)}, nil
+}
+
+// spoolContent appends the content read from r to the spool file, which is
+// created on first use, and returns its offset and size. The spool file is
+// removed as soon as it is created, so that it goes away with the process;
+// it is closed when the memFS is garbage collected.
+func (m *memFS) spoolContent(r io.Reader) (offset, size int64, err error) {
+	if m.spool == nil {
+		f, err := os.CreateTemp("", "ssbom-spool-*")
+		if err != nil {
+			return 0, 0, err
+		}
+		os.Remove(f.Name())
+		m.spool = f
+	}
+	size, err = io.Copy(m.spool, r)
+	offset = m.spoolSize
+	m.spoolSize += size
+	return offset, size, err
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	return m.lookup("stat", name, true)
+}
+
+func (m *memFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.lookup("lstat", name, false)
+}
+
+func (m *memFS) ReadLink(name string) (string, error) {
+	e, err := m.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if e.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return e.link, nil
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	dir, err := m.lookup("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !dir.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if m.children == nil {
+		m.children = make(map[string][]fs.DirEntry)
+		for n, e := range m.entries {
+			if n != "." {
+				m.children[path.Dir(n)] = append(m.children[path.Dir(n)], e)
+			}
+		}
+		for _, entries := range m.children {
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].Name() < entries[j].Name()
+			})
+		}
+	}
+	entries := m.children[dir.name]
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+type memFile struct {
+	entry   *memEntry
+	content interface {
+		io.Reader
+		io.ReaderAt
+		io.Seeker
+	}
+}
+
+func (f *memFile) Read(p []byte) (int, error)              { return f.content.Read(p) }
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) { return f.content.ReadAt(p, off) }
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	return f.content.Seek(offset, whence)
+}
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	entry   *memEntry
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,435 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	ociLayoutFile      = "oci-layout"
+	ociIndexFile       = "index.json"
+	dockerManifestFile = "manifest.json"
+)
+
+// Media types of OCI and Docker manifests and indexes.
+const (
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// Annotations naming the images of an OCI index.
+const (
+	annotationRefName    = "org.opencontainers.image.ref.name"
+	annotationContainerd = "io.containerd.image.name"
+)
+
+// blobStore gives access to the files of an image layout or archive.
+type blobStore interface {
+	open(name string) (io.ReadCloser, error)
+}
+
+// dirBlobs is an image layout directory.
+type dirBlobs string
+
+func (d dirBlobs) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// archiveBlobs is an image layout or "docker save" output in an
+// uncompressed tar archive. The files are read in place from the archive, so
+// that no blob needs to be extracted or held in memory.
+type archiveBlobs struct {
+	r       io.ReaderAt
+	entries map[string]archiveSection
+}
+
+// archiveSection is the position of the content of a file in an archive.
+type archiveSection struct {
+	offset int64
+	size   int64
+}
+
+func (a *archiveBlobs) open(name string) (io.ReadCloser, error) {
+	e, ok := a.entries[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return io.NopCloser(io.NewSectionReader(a.r, e.offset, e.size)), nil
+}
+
+// indexArchive returns the position of the regular files of the
+// uncompressed tar archive read from r, in a single pass. The content of the
+// files is skipped rather than read. Hard links are indexed as their target.
+func indexArchive(r io.ReadSeeker) (map[string]archiveSection, error) {
+	entries := make(map[string]archiveSection)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeLink {
+			continue
+		}
+		name, err := cleanName(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeLink {
+			target, err := cleanName(hdr.Linkname)
+			if err != nil {
+				return nil, err
+			}
+			if e, ok := entries[target]; ok {
+				entries[name] = e
+			}
+			continue
+		}
+		// The reader is left at the start of the content of the entry.
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = archiveSection{offset: offset, size: hdr.Size}
+	}
+}
+
+// openArchive opens a tar archive, which may hold a rootfs or a container
+// image. Uncompressed archives are indexed first, which skips over the
+// content of their files: images are then read in place and rootfs in a
+// single pass. Compressed archives are read as a rootfs in a single pass; if
+// they turn out to hold an image, they are decompressed once into a
+// temporary file which is then read as an uncompressed archive.
+func openArchive(p string, opts *Options) (FS, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var magic [4]byte
+	n, err := f.ReadAt(magic[:], 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !isCompressed(magic[:n]) {
+		return openUncompressedArchive(f, p, opts)
+	}
+
+	m := newMemFS()
+	if err := m.readTar(f, opts, false); err != nil {
+		return nil, fmt.Errorf("cannot read archive %s: %s", p, err)
+	}
+	if !isImage(func(name string) bool {
+		e := m.get(name)
+		return e != nil && e.Mode().IsRegular()
+	}) {
+		return m, nil
+	}
+
+	tmp, err := os.CreateTemp("", "ssbom-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	rc, err := decompress(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read archive %s: %s", p, err)
+	}
+	defer rc.Close()
+	if _, err := io.Copy(tmp, rc); err != nil {
+		return nil, fmt.Errorf("cannot read archive %s: %s", p, err)
+	}
+	return openUncompressedArchive(tmp, p, opts)
+}
+
+// openUncompressedArchive opens the uncompressed tar archive f, read from
+// the file at path p.
+func openUncompressedArchive(f *os.File, p string, opts *Options) (FS, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	entries, err := indexArchive(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read archive %s: %s", p, err)
+	}
+	if isImage(func(name string) bool { _, ok := entries[name]; return ok }) {
+		return openImage(&archiveBlobs{r: f, entries: entries}, opts)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	m := newMemFS()
+	if err := m.readTar(f, opts, false); err != nil {
+		return nil, fmt.Errorf("cannot read archive %s: %s", p, err)
+	}
+	return m, nil
+}
+
+// isImage reports whether an archive holds an OCI image layout or the output
+// of "docker save" rather than a rootfs, given whether it holds a regular
+// file with the given name.
+func isImage(hasFile func(name string) bool) bool {
+	return hasFile(ociLayoutFile) || hasFile(dockerManifestFile)
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Platform    *ociPlatform      `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociPlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p *ociPlatform) String() string {
+	if p == nil {
+		return "unknown"
+	}
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+type ociIndex struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+type dockerManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// openImage flattens the layers of the image in blobs into a FS.
+func openImage(blobs blobStore, opts *Options) (FS, error) {
+	layers, err := imageLayers(blobs, opts)
+	if err != nil {
+		return nil, err
+	}
+	m := newMemFS()
+	for _, layer := range layers {
+		r, err := blobs.open(layer)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open image layer: %w", err)
+		}
+		err = m.applyLayer(r, opts)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read image layer %s: %w", layer, err)
+		}
+	}
+	return m, nil
+}
+
+// imageLayers returns the names of the layer blobs of the selected image,
+// from the lowest to the topmost.
+func imageLayers(blobs blobStore, opts *Options) ([]string, error) {
+	var index ociIndex
+	err := readJSON(blobs, ociIndexFile, &index)
+	if err == nil {
+		return ociLayers(blobs, &index, opts)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var manifests []dockerManifest
+	if err := readJSON(blobs, dockerManifestFile, &manifests); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot find %s or %s in image", ociIndexFile, dockerManifestFile)
+		}
+		return nil, err
+	}
+	var selected []dockerManifest
+	var tags []string
+	for _, m := range manifests {
+		tags = append(tags, m.RepoTags...)
+		if opts == nil || opts.Image == "" || contains(m.RepoTags, opts.Image) {
+			selected = append(selected, m)
+		}
+	}
+	switch {
+	case len(selected) == 0 && len(manifests) > 0:
+		return nil, fmt.Errorf("cannot find image %q; available: %s", opts.Image, strings.Join(tags, ", "))
+	case len(selected) == 0:
+		return nil, fmt.Errorf("cannot find any image in %s", dockerManifestFile)
+	case len(selected) > 1:
+		return nil, fmt.Errorf("cannot select image: several available: %s", strings.Join(tags, ", "))
+	}
+	var layers []string
+	for _, l := range selected[0].Layers {
+		name, err := cleanName(l)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, name)
+	}
+	return layers, nil
+}
+
+func ociLayers(blobs blobStore, index *ociIndex, opts *Options) ([]string, error) {
+	image, platform := "", ""
+	if opts != nil {
+		image, platform = opts.Image, opts.Platform
+	}
+	manifests := index.Manifests
+	if image != "" {
+		var available []string
+		manifests = nil
+		for _, d := range index.Manifests {
+			if name := refName(d); name != "" {
+				available = append(available, name)
+			}
+			if refName(d) == image {
+				manifests = append(manifests, d)
+			}
+		}
+		if len(manifests) == 0 {
+			return nil, fmt.Errorf("cannot find image %q; available: %s", image, strings.Join(available, ", "))
+		}
+	}
+
+	for {
+		d, err := selectPlatform(manifests, platform)
+		if err != nil {
+			return nil, err
+		}
+		switch d.MediaType {
+		case mediaTypeOCIIndex, mediaTypeDockerList:
+			var nested ociIndex
+			name, err := blobName(d.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if err := readJSON(blobs, name, &nested); err != nil {
+				return nil, err
+			}
+			manifests = nested.Manifests
+			continue
+		case mediaTypeOCIManifest, mediaTypeDockerManifest, "":
+		default:
+			return nil, fmt.Errorf("unsupported manifest media type %q", d.MediaType)
+		}
+
+		var manifest ociManifest
+		name, err := blobName(d.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if err := readJSON(blobs, name, &manifest); err != nil {
+			return nil, err
+		}
+		var layers []string
+		for _, l := range manifest.Layers {
+			name, err := blobName(l.Digest)
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, name)
+		}
+		return layers, nil
+	}
+}
+
+// selectPlatform returns the only descriptor matching the platform, if any,
+// ignoring attestation manifests. Nested indexes match every platform.
+func selectPlatform(manifests []descriptor, platform string) (descriptor, error) {
+	var selected []descriptor
+	var available []string
+	for _, d := range manifests {
+		if d.Platform != nil && d.Platform.OS == "unknown" {
+			continue
+		}
+		isIndex := d.MediaType == mediaTypeOCIIndex || d.MediaType == mediaTypeDockerList
+		if !isIndex {
+			available = append(available, d.Platform.String())
+		}
+		if platform == "" || isIndex || d.Platform.String() == platform {
+			selected = append(selected, d)
+		}
+	}
+	switch {
+	case len(selected) == 0 && platform != "":
+		return descriptor{}, fmt.Errorf("cannot find platform %q; available: %s", platform, strings.Join(available, ", "))
+	case len(selected) == 0:
+		return descriptor{}, fmt.Errorf("cannot find any image manifest")
+	case len(selected) > 1:
+		return descriptor{}, fmt.Errorf("cannot select image: several available: %s", strings.Join(available, ", "))
+	}
+	return selected[0], nil
+}
+
+func refName(d descriptor) string {
+	if name := d.Annotations[annotationRefName]; name != "" {
+		return name
+	}
+	return d.Annotations[annotationContainerd]
+}
+
+// blobName returns the name of the blob with the given digest in an OCI
+// image layout.
+func blobName(digest string) (string, error) {
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	alg, hex, _ := strings.Cut(digest, ":")
+	if alg == "sha256" && !sha256Pattern.MatchString(hex) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return path.Join("blobs", alg, hex), nil
+}
+
+// digestPattern is the grammar of digests in the OCI image specification.
+var digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func readJSON(blobs blobStore, name string, v any) error {
+	r, err := blobs.open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("cannot parse %s: %s", name, err)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
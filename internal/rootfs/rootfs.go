@@ -0,0 +1,244 @@
+// Package rootfs provides a read-only view of a chiselled rootfs, which may
+// be a directory, a tar archive or be stored in container image layers.
+package rootfs
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FS is a read-only view of a rootfs. Names are slash-separated paths
+// relative to the root of the rootfs, as in io/fs. Open and Stat follow
+// symbolic links, resolving absolute targets against the root of the rootfs
+// rather than against the host.
+type FS interface {
+	fs.ReadDirFS
+	fs.StatFS
+	// Lstat returns information about the named file without following a
+	// final symbolic link.
+	Lstat(name string) (fs.FileInfo, error)
+	// ReadLink returns the target of the named symbolic link.
+	ReadLink(name string) (string, error)
+}
+
+// ErrContentNotLoaded is returned when opening a file of an archive whose
+// content was not selected by Options.Want.
+var ErrContentNotLoaded = errors.New("file content not loaded")
+
+// Options control how a rootfs is read.
+type Options struct {
+	// Want selects the regular files of archived rootfs whose content is
+	// kept in memory. The metadata of every entry is always kept. If nil,
+	// the content of every file is kept. It is ignored for directories.
+	Want func(name string) bool
+	// Digests computes the sha1 and sha256 digests of the regular files of
+	// archives as they are read, so that FileDigests returns them even for
+	// files whose content is not kept.
+	Digests bool
+	// Executables keeps the content of the ELF executables of archives,
+	// whatever Want selects, so that their build information can be read.
+	// Unless selected by Want, it is kept in a temporary file rather than
+	// in memory.
+	Executables bool
+	// Image selects the image to read from an archive holding several,
+	// by reference name or tag. If empty, the archive must hold one image.
+	Image string
+	// Platform selects the image of a multi-platform index, in the
+	// "os/arch[/variant]" form. If empty, the only image of the index is
+	// used.
+	Platform string
+}
+
+func (opts *Options) want(name string) bool {
+	return opts == nil || opts.Want == nil || opts.Want(name)
+}
+
+func (opts *Options) digests() bool {
+	return opts != nil && opts.Digests
+}
+
+func (opts *Options) executables() bool {
+	return opts != nil && opts.Executables
+}
+
+// FileDigests returns the hex-encoded sha1 and sha256 digests of the content
+// of the named regular file. The digests computed while reading an archive
+// with Options.Digests are used if available; otherwise the content is read.
+func FileDigests(fsys fs.FS, name string) (sha1sum, sha256sum string, err error) {
+	if m, ok := fsys.(*memFS); ok {
+		e, err := m.lookup("open", name, true)
+		if err != nil {
+			return "", "", err
+		}
+		if e.sha256 != "" {
+			return e.sha1, e.sha256, nil
+		}
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	h1, h256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(h1, h256), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(h1.Sum(nil)), hex.EncodeToString(h256.Sum(nil)), nil
+}
+
+// Open opens the rootfs at the given path, which may be a rootfs directory,
+// a tar archive of a rootfs, an OCI image layout directory, or a tar archive
+// holding an OCI image layout or the output of "docker save". Tar archives
+// may be gzip or zstd compressed.
+func Open(p string, opts *Options) (FS, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		if _, err := os.Stat(filepath.Join(p, ociLayoutFile)); err == nil {
+			return openImage(dirBlobs(p), opts)
+		}
+		return Dir(p), nil
+	}
+	return openArchive(p, opts)
+}
+
+// dirFS is a FS backed by a directory of the host.
+type dirFS struct {
+	root string
+}
+
+// Dir returns a FS for the rootfs directory at the given path.
+func Dir(root string) FS {
+	return &dirFS{root: root}
+}
+
+func (d *dirFS) hostPath(name string) string {
+	return filepath.Join(d.root, filepath.FromSlash(name))
+}
+
+func (d *dirFS) Open(name string) (fs.File, error) {
+	resolved, err := resolve(d, "open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(d.hostPath(resolved))
+}
+
+func (d *dirFS) Stat(name string) (fs.FileInfo, error) {
+	resolved, err := resolve(d, "stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(d.hostPath(resolved))
+}
+
+func (d *dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	resolved, err := resolve(d, "readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(d.hostPath(resolved))
+}
+
+func (d *dirFS) Lstat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrInvalid}
+	}
+	parent, err := resolve(d, "lstat", path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(d.hostPath(path.Join(parent, path.Base(name))))
+}
+
+func (d *dirFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	parent, err := resolve(d, "readlink", path.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(d.hostPath(path.Join(parent, path.Base(name))))
+}
+
+// maxSymlinks is the maximum number of symbolic links followed when
+// resolving a path.
+const maxSymlinks = 40
+
+// resolve returns the name with every symbolic link in it replaced by its
+// target, without ever leaving the root of fsys.
+func resolve(fsys FS, op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	resolved := "."
+	pending := strings.Split(name, "/")
+	links := 0
+	for len(pending) > 0 {
+		elem := pending[0]
+		pending = pending[1:]
+		if elem == "." || elem == "" {
+			continue
+		}
+		if elem == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, elem)
+		info, err := fsys.Lstat(next)
+		if err != nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: unwrapPathError(err)}
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		links++
+		if links > maxSymlinks {
+			return "", &fs.PathError{Op: op, Path: name, Err: errors.New("too many levels of symbolic links")}
+		}
+		target, err := fsys.ReadLink(next)
+		if err != nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: unwrapPathError(err)}
+		}
+		if strings.HasPrefix(target, "/") {
+			resolved = "."
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+	return resolved, nil
+}
+
+func unwrapPathError(err error) error {
+	var perr *fs.PathError
+	if errors.As(err, &perr) {
+		return perr.Err
+	}
+	return err
+}
+
+// cleanName converts an archive entry name into a FS name, or returns an
+// error if the name escapes the root.
+func cleanName(name string) (string, error) {
+	cleaned := path.Clean("/" + strings.TrimPrefix(name, "./"))
+	if cleaned == "/" {
+		return ".", nil
+	}
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	if !fs.ValidPath(cleaned) {
+		return "", fmt.Errorf("invalid entry name %q", name)
+	}
+	return cleaned, nil
+}
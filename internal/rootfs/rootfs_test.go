@@ -0,0 +1,398 @@
+package rootfs_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/canonical/ssbom/internal/rootfs"
+	"github.com/klauspost/compress/zstd"
+	. "gopkg.in/check.v1"
+)
+
+type tarEntry struct {
+	name string
+	data string
+	link string
+	// typeflag defaults to tar.TypeReg.
+	typeflag byte
+	// mode defaults to 0644.
+	mode int64
+}
+
+func makeTar(c *C, entries []tarEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: e.typeflag, Linkname: e.link}
+		switch e.typeflag {
+		case 0:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(e.data))
+		case tar.TypeDir:
+			hdr.Mode = 0755
+		}
+		if e.mode != 0 {
+			hdr.Mode = e.mode
+		}
+		c.Assert(tw.WriteHeader(hdr), IsNil)
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte(e.data))
+			c.Assert(err, IsNil)
+		}
+	}
+	c.Assert(tw.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func gzipData(c *C, data []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write(data)
+	c.Assert(err, IsNil)
+	c.Assert(gw.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+var lowerLayer = []tarEntry{
+	{name: "etc/", typeflag: tar.TypeDir},
+	{name: "etc/os-release", typeflag: tar.TypeSymlink, link: "../usr/lib/os-release"},
+	{name: "usr/lib/os-release", data: "VERSION_ID=\"24.04\"\n"},
+	{name: "var/lib/chisel/manifest.wall", data: "lower"},
+	{name: "opt/app/old", data: "old"},
+	{name: "tmp/removed", data: "removed"},
+}
+
+var upperLayer = []tarEntry{
+	{name: "./var/lib/chisel/manifest.wall", data: "upper"},
+	{name: "opt/app/.wh..wh..opq"},
+	{name: "opt/app/new", data: "new"},
+	{name: "opt/app/hardlink", typeflag: tar.TypeLink, link: "opt/app/new"},
+	{name: "tmp/.wh.removed"},
+	{name: "bin", typeflag: tar.TypeSymlink, link: "/usr/bin"},
+	{name: "usr/bin/tool", data: "tool"},
+}
+
+// writeOCILayout writes an OCI image layout with the given layers to dir
+// and returns the files written, relative to dir.
+func writeOCILayout(c *C, dir string, layers ...[]byte) map[string][]byte {
+	files := map[string][]byte{
+		"oci-layout": []byte(`{"imageLayoutVersion":"1.0.0"}`),
+	}
+	var layerDescs []map[string]any
+	for _, l := range layers {
+		d := digest(l)
+		files["blobs/sha256/"+d[len("sha256:"):]] = l
+		layerDescs = append(layerDescs, map[string]any{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+			"digest":    d,
+			"size":      len(l),
+		})
+	}
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers":        layerDescs,
+	})
+	c.Assert(err, IsNil)
+	md := digest(manifest)
+	files["blobs/sha256/"+md[len("sha256:"):]] = manifest
+	index, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"manifests": []map[string]any{{
+			"mediaType":   "application/vnd.oci.image.manifest.v1+json",
+			"digest":      md,
+			"size":        len(manifest),
+			"platform":    map[string]string{"os": "linux", "architecture": "amd64"},
+			"annotations": map[string]string{"org.opencontainers.image.ref.name": "latest"},
+		}, {
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"digest":    "sha256:0000",
+			"platform":  map[string]string{"os": "unknown", "architecture": "unknown"},
+		}},
+	})
+	c.Assert(err, IsNil)
+	files["index.json"] = index
+
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(os.WriteFile(p, data, 0644), IsNil)
+	}
+	return files
+}
+
+func checkFlattenedImage(c *C, fsys rootfs.FS) {
+	data, err := fs.ReadFile(fsys, "var/lib/chisel/manifest.wall")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "upper")
+
+	// Symlinks are resolved within the rootfs.
+	data, err = fs.ReadFile(fsys, "etc/os-release")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "VERSION_ID=\"24.04\"\n")
+	data, err = fs.ReadFile(fsys, "bin/tool")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "tool")
+	target, err := fsys.ReadLink("etc/os-release")
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "../usr/lib/os-release")
+	info, err := fsys.Lstat("bin")
+	c.Assert(err, IsNil)
+	c.Assert(info.Mode()&fs.ModeSymlink, Not(Equals), fs.FileMode(0))
+
+	// Whiteouts remove the files of the lower layers.
+	entries, err := fsys.ReadDir("opt/app")
+	c.Assert(err, IsNil)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	c.Assert(names, DeepEquals, []string{"hardlink", "new"})
+	_, err = fsys.Stat("tmp/removed")
+	c.Assert(errors.Is(err, fs.ErrNotExist), Equals, true)
+
+	data, err = fs.ReadFile(fsys, "opt/app/hardlink")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "new")
+}
+
+func (s *S) TestOpenOCILayout(c *C) {
+	dir := c.MkDir()
+	writeOCILayout(c, dir, gzipData(c, makeTar(c, lowerLayer)), makeTar(c, upperLayer))
+
+	fsys, err := rootfs.Open(dir, nil)
+	c.Assert(err, IsNil)
+	checkFlattenedImage(c, fsys)
+
+	_, err = rootfs.Open(dir, &rootfs.Options{Image: "missing"})
+	c.Assert(err, ErrorMatches, `cannot find image "missing"; available: latest`)
+	_, err = rootfs.Open(dir, &rootfs.Options{Platform: "linux/arm64"})
+	c.Assert(err, ErrorMatches, `cannot find platform "linux/arm64"; available: linux/amd64`)
+}
+
+func (s *S) TestOpenOCIInvalidDigest(c *C) {
+	for _, d := range []string{"sha256:../../../etc/passwd", "sha256:abcd", "../sha256:" + strings.Repeat("0", 64), "sha256"} {
+		c.Logf("Digest: %s", d)
+		dir := c.MkDir()
+		writeOCILayout(c, dir, makeTar(c, upperLayer))
+		index := fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q}]}`, d)
+		c.Assert(os.WriteFile(filepath.Join(dir, "index.json"), []byte(index), 0644), IsNil)
+		_, err := rootfs.Open(dir, nil)
+		c.Assert(err, ErrorMatches, fmt.Sprintf(`.*invalid digest %q`, d))
+	}
+}
+
+func (s *S) TestOpenOCIArchive(c *C) {
+	dir := c.MkDir()
+	files := writeOCILayout(c, dir, gzipData(c, makeTar(c, lowerLayer)), makeTar(c, upperLayer))
+	var entries []tarEntry
+	for name, data := range files {
+		entries = append(entries, tarEntry{name: name, data: string(data)})
+	}
+	archive := filepath.Join(c.MkDir(), "image.tar")
+	c.Assert(os.WriteFile(archive, makeTar(c, entries), 0644), IsNil)
+
+	fsys, err := rootfs.Open(archive, nil)
+	c.Assert(err, IsNil)
+	checkFlattenedImage(c, fsys)
+}
+
+func (s *S) TestOpenDockerArchive(c *C) {
+	manifest, err := json.Marshal([]map[string]any{{
+		"Config":   "config.json",
+		"RepoTags": []string{"chiselled:latest"},
+		"Layers":   []string{"lower/layer.tar", "upper/layer.tar"},
+	}})
+	c.Assert(err, IsNil)
+	archive := filepath.Join(c.MkDir(), "image.tar.gz")
+	data := makeTar(c, []tarEntry{
+		{name: "upper/layer.tar", data: string(makeTar(c, upperLayer))},
+		{name: "lower/layer.tar", data: string(makeTar(c, lowerLayer))},
+		{name: "manifest.json", data: string(manifest)},
+	})
+	c.Assert(os.WriteFile(archive, gzipData(c, data), 0644), IsNil)
+
+	fsys, err := rootfs.Open(archive, &rootfs.Options{Image: "chiselled:latest"})
+	c.Assert(err, IsNil)
+	checkFlattenedImage(c, fsys)
+
+	_, err = rootfs.Open(archive, &rootfs.Options{Image: "other"})
+	c.Assert(err, ErrorMatches, `cannot find image "other"; available: chiselled:latest`)
+}
+
+func (s *S) TestOpenWant(c *C) {
+	dir := c.MkDir()
+	writeOCILayout(c, dir, makeTar(c, lowerLayer), makeTar(c, upperLayer))
+
+	fsys, err := rootfs.Open(dir, &rootfs.Options{Want: func(name string) bool {
+		return name == "var/lib/chisel/manifest.wall"
+	}})
+	c.Assert(err, IsNil)
+	data, err := fs.ReadFile(fsys, "var/lib/chisel/manifest.wall")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "upper")
+	_, err = fs.ReadFile(fsys, "usr/bin/tool")
+	c.Assert(errors.Is(err, rootfs.ErrContentNotLoaded), Equals, true)
+	info, err := fsys.Stat("usr/bin/tool")
+	c.Assert(err, IsNil)
+	c.Assert(info.Size(), Equals, int64(4))
+}
+
+func (s *S) TestOpenDigests(c *C) {
+	archive := filepath.Join(c.MkDir(), "rootfs.tar.gz")
+	data := makeTar(c, []tarEntry{
+		{name: "usr/bin/elf", data: "\x7fELF binary", mode: 0755},
+		{name: "usr/bin/script", data: "#!/bin/sh\n", mode: 0755},
+		{name: "etc/config", data: "config"},
+		{name: "etc/link", typeflag: tar.TypeLink, link: "etc/config"},
+	})
+	c.Assert(os.WriteFile(archive, gzipData(c, data), 0644), IsNil)
+
+	fsys, err := rootfs.Open(archive, &rootfs.Options{
+		Want:        func(string) bool { return false },
+		Digests:     true,
+		Executables: true,
+	})
+	c.Assert(err, IsNil)
+	content, err := fs.ReadFile(fsys, "usr/bin/elf")
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "\x7fELF binary")
+	// Executables are not held in memory, but can be read at random.
+	c.Assert(rootfs.Spooled(fsys, "usr/bin/elf"), Equals, true)
+	f, err := fsys.Open("usr/bin/elf")
+	c.Assert(err, IsNil)
+	defer f.Close()
+	buf := make([]byte, 6)
+	n, err := f.(io.ReaderAt).ReadAt(buf, 5)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf[:n]), Equals, "binary")
+	_, err = fs.ReadFile(fsys, "usr/bin/script")
+	c.Assert(errors.Is(err, rootfs.ErrContentNotLoaded), Equals, true)
+	_, err = fs.ReadFile(fsys, "etc/config")
+	c.Assert(errors.Is(err, rootfs.ErrContentNotLoaded), Equals, true)
+
+	for _, name := range []string{"etc/config", "etc/link"} {
+		sha1sum, sha256sum, err := rootfs.FileDigests(fsys, name)
+		c.Assert(err, IsNil)
+		c.Assert(sha1sum, Equals, "dfba7aade0868074c2861c98e2a9a92f3178a51b")
+		c.Assert(sha256sum, Equals, digest([]byte("config"))[len("sha256:"):])
+	}
+
+	// Without digests from the archive, the content is read.
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "config"), []byte("config"), 0644), IsNil)
+	sha1sum, sha256sum, err := rootfs.FileDigests(rootfs.Dir(dir), "config")
+	c.Assert(err, IsNil)
+	c.Assert(sha1sum, Equals, "dfba7aade0868074c2861c98e2a9a92f3178a51b")
+	c.Assert(sha256sum, Equals, digest([]byte("config"))[len("sha256:"):])
+}
+
+func (s *S) TestDir(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(dir, "usr/lib"), 0755), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(dir, "etc"), 0755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "usr/lib/os-release"), []byte("ID=ubuntu\n"), 0644), IsNil)
+	// An absolute link must be resolved within the rootfs.
+	c.Assert(os.Symlink("/usr/lib/os-release", filepath.Join(dir, "etc/os-release")), IsNil)
+	c.Assert(os.Symlink("../../../../../../etc/hostname", filepath.Join(dir, "etc/escape")), IsNil)
+
+	fsys, err := rootfs.Open(dir, nil)
+	c.Assert(err, IsNil)
+	data, err := fs.ReadFile(fsys, "etc/os-release")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "ID=ubuntu\n")
+	_, err = fs.ReadFile(fsys, "etc/escape")
+	c.Assert(errors.Is(err, fs.ErrNotExist), Equals, true)
+
+	info, err := fsys.Lstat("etc/os-release")
+	c.Assert(err, IsNil)
+	c.Assert(info.Mode()&fs.ModeSymlink, Not(Equals), fs.FileMode(0))
+}
+
+func zstdData(c *C, data []byte) []byte {
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	c.Assert(err, IsNil)
+	_, err = zw.Write(data)
+	c.Assert(err, IsNil)
+	c.Assert(zw.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func (s *S) TestOpenRootfsArchive(c *C) {
+	data := makeTar(c, append(lowerLayer, tarEntry{name: "tmp/.wh.kept", data: "kept"}))
+	archives := map[string][]byte{
+		"rootfs.tar":     data,
+		"rootfs.tar.gz":  gzipData(c, data),
+		"rootfs.tar.zst": zstdData(c, data),
+	}
+	for name, content := range archives {
+		c.Logf("Archive: %s", name)
+		archive := filepath.Join(c.MkDir(), name)
+		c.Assert(os.WriteFile(archive, content, 0644), IsNil)
+
+		fsys, err := rootfs.Open(archive, &rootfs.Options{Want: func(name string) bool {
+			return name != "opt/app/old"
+		}})
+		c.Assert(err, IsNil)
+		data, err := fs.ReadFile(fsys, "etc/os-release")
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, "VERSION_ID=\"24.04\"\n")
+		data, err = fs.ReadFile(fsys, "var/lib/chisel/manifest.wall")
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, "lower")
+		_, err = fs.ReadFile(fsys, "opt/app/old")
+		c.Assert(errors.Is(err, rootfs.ErrContentNotLoaded), Equals, true)
+
+		// Whiteouts only have a meaning in image layers.
+		data, err = fs.ReadFile(fsys, "tmp/.wh.kept")
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, "kept")
+		_, err = fsys.Stat("tmp/removed")
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *S) TestOpenCompressedOCIArchive(c *C) {
+	dir := c.MkDir()
+	files := writeOCILayout(c, dir, gzipData(c, makeTar(c, lowerLayer)), makeTar(c, upperLayer))
+	var entries []tarEntry
+	for name, data := range files {
+		if name == "index.json" {
+			// Hard links are read as their target.
+			entries = append(entries,
+				tarEntry{name: "index.orig", data: string(data)},
+				tarEntry{name: name, typeflag: tar.TypeLink, link: "index.orig"})
+			continue
+		}
+		entries = append(entries, tarEntry{name: name, data: string(data)})
+	}
+	data := makeTar(c, entries)
+	for name, archive := range map[string][]byte{
+		"image.tar":     data,
+		"image.tar.zst": zstdData(c, data),
+	} {
+		c.Logf("Archive: %s", name)
+		p := filepath.Join(c.MkDir(), name)
+		c.Assert(os.WriteFile(p, archive, 0644), IsNil)
+		fsys, err := rootfs.Open(p, nil)
+		c.Assert(err, IsNil)
+		checkFlattenedImage(c, fsys)
+	}
+}
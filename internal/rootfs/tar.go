@@ -0,0 +1,216 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// isCompressed reports whether data starting with magic is gzip or zstd
+// compressed.
+func isCompressed(magic []byte) bool {
+	return bytes.HasPrefix(magic, gzipMagic) || bytes.HasPrefix(magic, zstdMagic)
+}
+
+// decompress returns a reader of the uncompressed content of r, which may
+// be gzip or zstd compressed, or not compressed at all.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return io.NopCloser(br), nil
+}
+
+// OCI whiteout markers.
+// See https://github.com/opencontainers/image-spec/blob/main/layer.md#whiteouts
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// applyLayer reads the tar stream of an image layer on top of m. Whiteout
+// entries remove the files they refer to from the lower layers.
+func (m *memFS) applyLayer(r io.Reader, opts *Options) error {
+	return m.readTar(r, opts, true)
+}
+
+// readTar reads a (possibly compressed) tar stream into m. If whiteouts is
+// true, whiteout entries are handled as in image layers; otherwise they are
+// regular entries.
+func (m *memFS) readTar(r io.Reader, opts *Options, whiteouts bool) error {
+	rc, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var removed, opaques []string
+	var upper []*memEntry
+	added := make(map[string]*memEntry)
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name, err := cleanName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		dir, base := path.Dir(name), path.Base(name)
+		if whiteouts && base == whiteoutOpaque {
+			opaques = append(opaques, dir)
+			continue
+		}
+		if whiteouts && strings.HasPrefix(base, whiteoutPrefix) {
+			removed = append(removed, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+			continue
+		}
+
+		e, err := m.readEntry(tr, hdr, name, added, opts)
+		if err != nil {
+			return err
+		}
+		added[name] = e
+		upper = append(upper, e)
+	}
+
+	for _, dir := range opaques {
+		m.removeChildren(dir)
+	}
+	for _, name := range removed {
+		m.remove(name)
+	}
+	for _, e := range upper {
+		if lower := m.get(e.name); lower != nil && lower.IsDir() && !e.IsDir() {
+			m.removeChildren(e.name)
+		}
+		m.add(e)
+	}
+	return nil
+}
+
+// readEntry reads the tar entry described by hdr. Hard links are resolved
+// against the entries added earlier in the same stream, then against m.
+func (m *memFS) readEntry(tr *tar.Reader, hdr *tar.Header, name string, added map[string]*memEntry, opts *Options) (*memEntry, error) {
+	e := &memEntry{
+		name:    name,
+		mode:    hdr.FileInfo().Mode(),
+		size:    hdr.Size,
+		modTime: hdr.ModTime,
+	}
+	switch hdr.Typeflag {
+	case tar.TypeSymlink:
+		e.link = hdr.Linkname
+	case tar.TypeLink:
+		target, err := cleanName(hdr.Linkname)
+		if err != nil {
+			return nil, err
+		}
+		linked, ok := added[target]
+		if !ok {
+			linked = m.get(target)
+		}
+		if linked == nil {
+			return nil, fmt.Errorf("cannot read entry %q: hard link target %q not found", hdr.Name, hdr.Linkname)
+		}
+		e.mode = linked.mode
+		e.size = linked.size
+		e.data = linked.data
+		e.loaded = linked.loaded
+		e.spooled = linked.spooled
+		e.offset = linked.offset
+		e.sha1 = linked.sha1
+		e.sha256 = linked.sha256
+	case tar.TypeReg:
+		if err := m.readContent(e, tr, opts); err != nil {
+			return nil, fmt.Errorf("cannot read entry %q: %w", hdr.Name, err)
+		}
+	}
+	return e, nil
+}
+
+// elfMagic starts every ELF file.
+var elfMagic = []byte("\x7fELF")
+
+// readContent reads the content of the regular file e from r, keeping it if
+// selected by the options and computing its digests if requested. Files that
+// are neither kept nor hashed are skipped. ELF executables kept only for
+// Options.Executables go to the spool file of m rather than to memory.
+func (m *memFS) readContent(e *memEntry, r io.Reader, opts *Options) error {
+	keep := opts.want(e.name)
+	executable := opts.executables() && e.mode.Perm()&0111 != 0
+	if !keep && !executable && !opts.digests() {
+		return nil
+	}
+	spool := false
+	if !keep && executable {
+		magic := make([]byte, len(elfMagic))
+		n, err := io.ReadFull(r, magic)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		spool = bytes.Equal(magic[:n], elfMagic)
+		r = io.MultiReader(bytes.NewReader(magic[:n]), r)
+	}
+	h1, h256 := sha1.New(), sha256.New()
+	if opts.digests() {
+		r = io.TeeReader(r, io.MultiWriter(h1, h256))
+	}
+	switch {
+	case keep:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		e.data = data
+		e.loaded = true
+	case spool:
+		offset, size, err := m.spoolContent(r)
+		if err != nil {
+			return err
+		}
+		e.offset, e.size = offset, size
+		e.spooled = true
+		e.loaded = true
+	default:
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return err
+		}
+	}
+	if opts.digests() {
+		e.sha1 = hex.EncodeToString(h1.Sum(nil))
+		e.sha256 = hex.EncodeToString(h256.Sum(nil))
+	}
+	return nil
+}
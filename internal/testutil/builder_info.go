@@ -2,6 +2,7 @@ package testutil
 
 import (
 	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/internal/osrelease"
 	"github.com/spdx/tools-golang/spdx"
 	"github.com/spdx/tools-golang/spdx/v2/common"
 )
@@ -125,12 +126,12 @@ var SPDXDocSampleSingleSlice = spdx.Package{
 	PackageName:             "test_slice",
 	FilesAnalyzed:           false,
 	PackageDownloadLocation: "NOASSERTION",
-	PackageSPDXIdentifier:   spdx.ElementID("Slice-test_slice"),
+	PackageSPDXIdentifier:   spdx.ElementID("Slice-test-slice-1196cbd5"),
 	PackageComment:          "This slice is a sub-package of the package test; see Relationship information.",
 }
 
 var SPDXDocSampleSingleFileNoFinalSHA256 = spdx.File{
-	FileSPDXIdentifier: spdx.ElementID("File-/test"),
+	FileSPDXIdentifier: spdx.ElementID("File-test-b306d9ff"),
 	FileName:           "/test",
 	Checksums: []spdx.Checksum{
 		{
@@ -143,7 +144,7 @@ var SPDXDocSampleSingleFileNoFinalSHA256 = spdx.File{
 }
 
 var SPDXDocSampleSingleFileModified = spdx.File{
-	FileSPDXIdentifier: spdx.ElementID("File-/test"),
+	FileSPDXIdentifier: spdx.ElementID("File-test-b306d9ff"),
 	FileName:           "/test",
 	Checksums: []spdx.Checksum{
 		{
@@ -156,7 +157,7 @@ var SPDXDocSampleSingleFileModified = spdx.File{
 }
 
 var SPDXDocSampleSingleFileLnk = spdx.File{
-	FileSPDXIdentifier: spdx.ElementID("File-/test"),
+	FileSPDXIdentifier: spdx.ElementID("File-test-b306d9ff"),
 	FileName:           "/test",
 	Checksums: []spdx.Checksum{
 		{
@@ -169,7 +170,7 @@ var SPDXDocSampleSingleFileLnk = spdx.File{
 }
 
 var SPDXDocSampleSingleFileHlk = spdx.File{
-	FileSPDXIdentifier: spdx.ElementID("File-/test"),
+	FileSPDXIdentifier: spdx.ElementID("File-test-b306d9ff"),
 	FileName:           "/test",
 	Checksums: []spdx.Checksum{
 		{
@@ -189,30 +190,36 @@ var SPDXRelSampleSingleDocDescribesPkg = spdx.Relationship{
 
 var SPDXRelSampleSinglePkgContainsSlice = spdx.Relationship{
 	RefA:         common.MakeDocElementID("", "Package-test"),
-	RefB:         common.MakeDocElementID("", "Slice-test_slice"),
+	RefB:         common.MakeDocElementID("", "Slice-test-slice-1196cbd5"),
 	Relationship: "CONTAINS",
 }
 
 var SPDXRelSampleSingleSliceContainsFile = spdx.Relationship{
-	RefA:                common.MakeDocElementID("", "Slice-test_slice"),
-	RefB:                common.MakeDocElementID("", "File-/test"),
+	RefA:                common.MakeDocElementID("", "Slice-test-slice-1196cbd5"),
+	RefB:                common.MakeDocElementID("", "File-test-b306d9ff"),
 	Relationship:        "CONTAINS",
 	RelationshipComment: "File /test is included in the slice test_slice.",
 }
 
 var SPDXRelSampleSingleFileModifiedBySlice = spdx.Relationship{
-	RefA:                common.MakeDocElementID("", "File-/test"),
-	RefB:                common.MakeDocElementID("", "Slice-test_slice"),
+	RefA:                common.MakeDocElementID("", "File-test-b306d9ff"),
+	RefB:                common.MakeDocElementID("", "Slice-test-slice-1196cbd5"),
 	Relationship:        "FILE_MODIFIED",
 	RelationshipComment: "File /test is mutated by the slice test_slice.",
 }
 
+var SampleOSReleaseNoble = &osrelease.OSRelease{
+	ID:        "ubuntu",
+	VersionID: "24.04",
+}
+
 var SPDXDocSampleUbuntuNoble = spdx.Package{
 	PackageName:             "ubuntu",
 	PackageSPDXIdentifier:   "OperatingSystem-ubuntu-24.04",
 	PackageDownloadLocation: "NOASSERTION",
 	FilesAnalyzed:           false,
 	PackageComment:          "This package is the distribution of the rootfs.",
+	PackageSupplier:         &builder.UbuntuPackageSupplier,
 	PackageVersion:          "24.04",
 	PrimaryPackagePurpose:   "OPERATING_SYSTEM",
 }
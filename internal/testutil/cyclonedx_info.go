@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+)
+
+// CycloneDXSamplePackage returns the component built from SampleSinglePackage
+// with the given sub-components.
+func CycloneDXSamplePackage(components ...*cyclonedx.Component) *cyclonedx.Component {
+	return &cyclonedx.Component{
+		Type:       cyclonedx.TypeLibrary,
+		BOMRef:     "Package-test",
+		Supplier:   builder.UbuntuCycloneDXSupplier,
+		Name:       "test",
+		Version:    "1.0",
+		Hashes:     []cyclonedx.Hash{{Algorithm: cyclonedx.SHA256, Content: "sha256"}},
+		CPE:        "cpe:2.3:a:test:test:1.0:*:*:*:*:*:*:*",
+		PURL:       "pkg:deb/ubuntu/test@1.0?arch=amd64",
+		Components: components,
+	}
+}
+
+// CycloneDXSampleSlice returns the component built from SampleSingleSlice
+// with the given sub-components.
+func CycloneDXSampleSlice(components ...*cyclonedx.Component) *cyclonedx.Component {
+	return &cyclonedx.Component{
+		Type:        cyclonedx.TypeLibrary,
+		BOMRef:      "Slice-test-slice-1196cbd5",
+		Name:        "test_slice",
+		Description: "This slice is a sub-package of the package test.",
+		Components:  components,
+	}
+}
+
+var CycloneDXSampleFile = cyclonedx.Component{
+	Type:        cyclonedx.TypeFile,
+	BOMRef:      "File-test-b306d9ff",
+	Name:        "/test",
+	Description: "This file is included in the slice(s) test_slice.",
+	Hashes:      []cyclonedx.Hash{{Algorithm: cyclonedx.SHA256, Content: "sha256"}},
+	Properties: []cyclonedx.Property{
+		{Name: "chisel:mode", Value: "0644"},
+		{Name: "chisel:slice", Value: "test_slice"},
+	},
+}
@@ -0,0 +1,114 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+)
+
+// serialNumberPattern is the pattern of serial numbers in the CycloneDX
+// schema: a UUID of version 1 to 5 as a URN.
+var serialNumberPattern = regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-[1-5][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// cycloneDX checks a CycloneDX BOM.
+func (c *checker) cycloneDX(data []byte) error {
+	var bom cyclonedx.BOM
+	if err := json.Unmarshal(data, &bom); err != nil {
+		return fmt.Errorf("invalid CycloneDX document: %s", err)
+	}
+
+	if bom.BOMFormat != cyclonedx.BOMFormat {
+		c.errorf("", "bomFormat must be %q, not %q", cyclonedx.BOMFormat, bom.BOMFormat)
+	}
+	if bom.SpecVersion != cyclonedx.SpecVersion && bom.SpecVersion != cyclonedx.SpecVersion15 {
+		c.errorf("", "unsupported specVersion %q", bom.SpecVersion)
+	}
+	if bom.Version < 1 {
+		c.errorf("", "version must be at least 1, not %d", bom.Version)
+	}
+	if bom.SerialNumber == "" {
+		c.warnf("", "missing serialNumber")
+	} else if !serialNumberPattern.MatchString(bom.SerialNumber) {
+		c.errorf("", "serialNumber %q is not a URN of a UUID", bom.SerialNumber)
+	}
+
+	refs := map[string]bool{}
+	var checkComponent func(comp *cyclonedx.Component)
+	checkComponent = func(comp *cyclonedx.Component) {
+		element := comp.BOMRef
+		if element == "" {
+			element = comp.Name
+		}
+		if comp.Type == "" {
+			c.errorf(element, "missing component type")
+		}
+		if comp.Name == "" {
+			c.errorf(element, "missing component name")
+		}
+		if comp.BOMRef != "" {
+			if refs[comp.BOMRef] {
+				c.errorf(element, "duplicate bom-ref")
+			}
+			refs[comp.BOMRef] = true
+		}
+		for _, h := range comp.Hashes {
+			c.checkDigest(element, h.Algorithm, h.Content)
+		}
+		if comp.PURL != "" && !strings.HasPrefix(comp.PURL, "pkg:") {
+			c.errorf(element, "purl %q does not start with \"pkg:\"", comp.PURL)
+		}
+		for _, sub := range comp.Components {
+			checkComponent(sub)
+		}
+	}
+	if bom.Metadata != nil {
+		if bom.Metadata.Timestamp != "" {
+			c.checkTime("", "metadata timestamp", bom.Metadata.Timestamp)
+		}
+		if bom.Metadata.Tools != nil {
+			for _, comp := range bom.Metadata.Tools.Components {
+				checkComponent(comp)
+			}
+		}
+		if bom.Metadata.Component != nil {
+			checkComponent(bom.Metadata.Component)
+		}
+	}
+	for _, comp := range bom.Components {
+		checkComponent(comp)
+	}
+
+	dependencies := map[string]bool{}
+	for _, dep := range bom.Dependencies {
+		if !refs[dep.Ref] {
+			c.errorf(dep.Ref, "dependency ref %s is not in the document", dep.Ref)
+		}
+		if dependencies[dep.Ref] {
+			c.errorf(dep.Ref, "duplicate dependency")
+		}
+		dependencies[dep.Ref] = true
+		for _, ref := range dep.DependsOn {
+			if !refs[ref] {
+				c.errorf(dep.Ref, "dependency %s dependsOn %s: %s is not in the document", dep.Ref, ref, ref)
+			}
+		}
+	}
+	for _, a := range bom.Annotations {
+		if len(a.Subjects) == 0 {
+			c.errorf("", "annotation without subjects")
+		}
+		for _, ref := range a.Subjects {
+			if !refs[ref] {
+				c.errorf(ref, "annotation subject %s is not in the document", ref)
+			}
+		}
+		if a.Annotator == nil {
+			c.errorf("", "annotation without annotator")
+		}
+		c.checkTime("", "annotation timestamp", a.Timestamp)
+	}
+	return nil
+}
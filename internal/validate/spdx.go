@@ -0,0 +1,147 @@
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+var (
+	spdxIdPattern      = regexp.MustCompile(`^[A-Za-z0-9.-]+$`)
+	licenseRefPattern  = regexp.MustCompile(`^LicenseRef-[A-Za-z0-9.-]+$`)
+	documentRefPattern = regexp.MustCompile(`^DocumentRef-[A-Za-z0-9.-]+$`)
+)
+
+// spdx checks an SPDX 2.3 document.
+func (c *checker) spdx(data []byte) error {
+	doc, err := spdxjson.Read(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid SPDX document: %s", err)
+	}
+
+	const document = "SPDXRef-DOCUMENT"
+	if !strings.HasPrefix(doc.SPDXVersion, "SPDX-2.") {
+		c.errorf("", "unsupported spdxVersion %q", doc.SPDXVersion)
+	}
+	if doc.DataLicense != "CC0-1.0" {
+		c.errorf(document, "dataLicense must be \"CC0-1.0\", not %q", doc.DataLicense)
+	}
+	if doc.SPDXIdentifier != "DOCUMENT" {
+		c.errorf(common.RenderElementID(doc.SPDXIdentifier), "document SPDXID must be %q", document)
+	}
+	if doc.DocumentName == "" {
+		c.errorf(document, "missing name")
+	}
+	if doc.DocumentNamespace == "" {
+		c.errorf(document, "missing documentNamespace")
+	} else if u, err := url.Parse(doc.DocumentNamespace); err != nil || u.Scheme == "" || strings.Contains(doc.DocumentNamespace, "#") {
+		c.errorf(document, "documentNamespace %q is not an absolute URI without \"#\"", doc.DocumentNamespace)
+	}
+	if doc.CreationInfo == nil {
+		c.errorf(document, "missing creationInfo")
+	} else {
+		if len(doc.CreationInfo.Creators) == 0 {
+			c.errorf(document, "missing creators")
+		}
+		c.checkTime(document, "created", doc.CreationInfo.Created)
+	}
+
+	refs := map[string]bool{}
+	for _, ref := range doc.ExternalDocumentReferences {
+		id := "DocumentRef-" + ref.DocumentRefID
+		if !documentRefPattern.MatchString(id) {
+			c.errorf(id, "invalid external document reference identifier")
+		}
+		refs[ref.DocumentRefID] = true
+		c.checkDigest(id, string(ref.Checksum.Algorithm), ref.Checksum.Value)
+	}
+
+	ids := map[common.ElementID]bool{doc.SPDXIdentifier: true}
+	addId := func(id common.ElementID) string {
+		element := common.RenderElementID(id)
+		if !spdxIdPattern.MatchString(string(id)) {
+			c.errorf(element, "invalid SPDX identifier: only letters, digits, \".\" and \"-\" may follow \"SPDXRef-\"")
+		}
+		if ids[id] {
+			c.errorf(element, "duplicate SPDX identifier")
+		}
+		ids[id] = true
+		return element
+	}
+	checkFile := func(f *spdx.File) {
+		element := addId(f.FileSPDXIdentifier)
+		if f.FileName == "" {
+			c.errorf(element, "missing file name")
+		}
+		for _, sum := range f.Checksums {
+			c.checkDigest(element, string(sum.Algorithm), sum.Value)
+		}
+	}
+	for _, p := range doc.Packages {
+		element := addId(p.PackageSPDXIdentifier)
+		if p.PackageName == "" {
+			c.errorf(element, "missing package name")
+		}
+		if p.PackageDownloadLocation == "" {
+			c.errorf(element, "missing downloadLocation")
+		}
+		for _, sum := range p.PackageChecksums {
+			c.checkDigest(element, string(sum.Algorithm), sum.Value)
+		}
+		for _, f := range p.Files {
+			checkFile(f)
+		}
+	}
+	for _, f := range doc.Files {
+		checkFile(f)
+	}
+	for _, s := range doc.Snippets {
+		addId(s.SnippetSPDXIdentifier)
+	}
+	for _, l := range doc.OtherLicenses {
+		if !licenseRefPattern.MatchString(l.LicenseIdentifier) {
+			c.errorf(l.LicenseIdentifier, "invalid license identifier: expected \"LicenseRef-\" followed by letters, digits, \".\" and \"-\"")
+		}
+	}
+
+	// resolves returns why the reference is dangling, if it is.
+	resolves := func(id common.DocElementID) string {
+		switch {
+		case id.SpecialID != "":
+			return ""
+		case id.DocumentRefID != "":
+			if !refs[id.DocumentRefID] {
+				return fmt.Sprintf("no external document reference DocumentRef-%s", id.DocumentRefID)
+			}
+		case !ids[id.ElementRefID]:
+			return fmt.Sprintf("%s is not in the document", common.RenderDocElementID(id))
+		}
+		return ""
+	}
+	describes := false
+	for _, r := range doc.Relationships {
+		a, b := common.RenderDocElementID(r.RefA), common.RenderDocElementID(r.RefB)
+		if r.RefA.SpecialID != "" {
+			c.errorf(a, "relationship %s %s %s: %s cannot be the first element", a, r.Relationship, b, a)
+		} else if msg := resolves(r.RefA); msg != "" {
+			c.errorf(a, "relationship %s %s %s: %s", a, r.Relationship, b, msg)
+		}
+		if msg := resolves(r.RefB); msg != "" {
+			c.errorf(a, "relationship %s %s %s: %s", a, r.Relationship, b, msg)
+		}
+		if r.Relationship == common.TypeRelationshipDescribe && r.RefA.ElementRefID == doc.SPDXIdentifier ||
+			r.Relationship == common.TypeRelationshipDescribeBy && r.RefB.ElementRefID == doc.SPDXIdentifier {
+			describes = true
+		}
+	}
+	if !describes {
+		c.warnf(document, "document does not describe any element")
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/canonical/ssbom/sbom/spdx3"
+)
+
+// spdx3Node holds the properties of any SPDX 3.0 graph node that are checked.
+type spdx3Node struct {
+	Type          string       `json:"type"`
+	ID            string       `json:"@id"`
+	SpdxID        string       `json:"spdxId"`
+	CreationInfo  string       `json:"creationInfo"`
+	Name          string       `json:"name"`
+	SpecVersion   string       `json:"specVersion"`
+	Created       string       `json:"created"`
+	CreatedBy     []string     `json:"createdBy"`
+	CreatedUsing  []string     `json:"createdUsing"`
+	DataLicense   string       `json:"dataLicense"`
+	RootElement   []string     `json:"rootElement"`
+	Elements      []string     `json:"element"`
+	From          string       `json:"from"`
+	To            []string     `json:"to"`
+	Relationship  string       `json:"relationshipType"`
+	Subject       string       `json:"subject"`
+	SuppliedBy    string       `json:"suppliedBy"`
+	OriginatedBy  []string     `json:"originatedBy"`
+	VerifiedUsing []spdx3.Hash `json:"verifiedUsing"`
+}
+
+// spdx3Vocabulary is the prefix of the individuals defined by the SPDX 3.0
+// model, such as NoAssertionElement, that may be referenced without being in
+// the graph.
+const spdx3Vocabulary = "https://spdx.org/rdf/3.0.1/terms/"
+
+// spdx3 checks an SPDX 3.0 JSON-LD document.
+func (c *checker) spdx3(data []byte) error {
+	var doc struct {
+		Context any         `json:"@context"`
+		Graph   []spdx3Node `json:"@graph"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid SPDX 3.0 document: %s", err)
+	}
+	if doc.Context == nil {
+		c.errorf("", "missing @context")
+	}
+
+	creationInfos := map[string]bool{}
+	ids := map[string]*spdx3Node{}
+	for i := range doc.Graph {
+		n := &doc.Graph[i]
+		if n.Type == "" {
+			c.errorf(n.SpdxID, "missing type")
+		}
+		if n.Type == spdx3.TypeCreationInfo {
+			if n.ID != "" {
+				creationInfos[n.ID] = true
+			}
+			continue
+		}
+		if n.SpdxID == "" {
+			c.errorf("", "%s element without spdxId", n.Type)
+			continue
+		}
+		if u, err := url.Parse(n.SpdxID); err != nil || u.Scheme == "" {
+			c.errorf(n.SpdxID, "spdxId is not an absolute IRI")
+		}
+		if ids[n.SpdxID] != nil {
+			c.errorf(n.SpdxID, "duplicate spdxId")
+		}
+		ids[n.SpdxID] = n
+	}
+
+	// resolve reports an error if the reference is not in the graph.
+	resolve := func(element, field, ref string) {
+		if ref == "" {
+			c.errorf(element, "missing %s", field)
+		} else if ids[ref] == nil && !strings.HasPrefix(ref, spdx3Vocabulary) {
+			c.errorf(element, "%s %s is not in the document", field, ref)
+		}
+	}
+	documents := 0
+	for i := range doc.Graph {
+		n := &doc.Graph[i]
+		if n.Type == spdx3.TypeCreationInfo {
+			element := n.ID
+			if n.SpecVersion == "" {
+				c.errorf(element, "missing specVersion")
+			} else if !strings.HasPrefix(n.SpecVersion, "3.") {
+				c.errorf(element, "unsupported specVersion %q", n.SpecVersion)
+			}
+			c.checkTime(element, "created", n.Created)
+			if len(n.CreatedBy) == 0 {
+				c.errorf(element, "missing createdBy")
+			}
+			for _, ref := range n.CreatedBy {
+				resolve(element, "createdBy", ref)
+			}
+			for _, ref := range n.CreatedUsing {
+				resolve(element, "createdUsing", ref)
+			}
+			continue
+		}
+		if n.SpdxID == "" {
+			continue
+		}
+		element := n.SpdxID
+		if n.CreationInfo == "" {
+			c.errorf(element, "missing creationInfo")
+		} else if !creationInfos[n.CreationInfo] {
+			c.errorf(element, "creationInfo %s is not in the document", n.CreationInfo)
+		}
+		for _, h := range n.VerifiedUsing {
+			c.checkDigest(element, h.Algorithm, h.HashValue)
+		}
+		if n.SuppliedBy != "" {
+			resolve(element, "suppliedBy", n.SuppliedBy)
+		}
+		for _, ref := range n.OriginatedBy {
+			resolve(element, "originatedBy", ref)
+		}
+		switch n.Type {
+		case spdx3.TypeSpdxDocument:
+			documents++
+			if n.DataLicense == "" {
+				c.errorf(element, "missing dataLicense")
+			}
+			if len(n.RootElement) == 0 {
+				c.warnf(element, "document does not describe any element")
+			}
+			for _, ref := range n.RootElement {
+				resolve(element, "rootElement", ref)
+			}
+			for _, ref := range n.Elements {
+				resolve(element, "element", ref)
+			}
+		case spdx3.TypePackage, spdx3.TypeFile:
+			if n.Name == "" {
+				c.errorf(element, "missing name")
+			}
+		case spdx3.TypeRelationship:
+			if n.Relationship == "" {
+				c.errorf(element, "missing relationshipType")
+			}
+			resolve(element, "from", n.From)
+			if len(n.To) == 0 {
+				c.errorf(element, "missing to")
+			}
+			for _, ref := range n.To {
+				if ids[ref] == nil && !strings.HasPrefix(ref, spdx3Vocabulary) {
+					c.errorf(element, "relationship %s %s %s: %s is not in the document", n.From, n.Relationship, ref, ref)
+				}
+			}
+		case spdx3.TypeAnnotation:
+			resolve(element, "subject", n.Subject)
+		}
+	}
+	if documents == 0 {
+		c.errorf("", "no SpdxDocument element")
+	}
+	return nil
+}
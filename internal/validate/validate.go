@@ -0,0 +1,147 @@
+// Package validate checks SBOM documents against the rules of their
+// specifications: required fields, identifier syntax, references between
+// elements and checksum formats.
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/canonical/ssbom/internal/format"
+)
+
+// Severity tells how serious an issue is.
+type Severity string
+
+const (
+	// Error is a violation of the specification of the format.
+	Error Severity = "error"
+	// Warning is a problem that does not make the document invalid but
+	// limits its usefulness.
+	Warning Severity = "warning"
+)
+
+// Issue is a problem found in a document.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	// Element is the identifier of the offending element, if any.
+	Element string `json:"element,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i *Issue) String() string {
+	if i.Element == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Element, i.Message)
+}
+
+// Report lists the issues found in a document.
+type Report struct {
+	Format format.Format `json:"format"`
+	Issues []*Issue      `json:"issues"`
+}
+
+// Count returns the number of issues with the given severity.
+func (r *Report) Count(severity Severity) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// Valid tells whether the document has no errors.
+func (r *Report) Valid() bool {
+	return r.Count(Error) == 0
+}
+
+// Check checks a JSON encoded SBOM in any of the supported formats. It fails
+// only if the document cannot be decoded; the problems of a decoded document
+// are reported as issues.
+func Check(data []byte) (*Report, error) {
+	f, err := format.Detect(data)
+	if err != nil {
+		return nil, err
+	}
+	c := &checker{}
+	switch f {
+	case format.SPDX:
+		err = c.spdx(data)
+	case format.SPDX3:
+		err = c.spdx3(data)
+	case format.CycloneDX, format.CycloneDX15:
+		err = c.cycloneDX(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Format: f, Issues: c.issues}
+	if report.Issues == nil {
+		report.Issues = []*Issue{}
+	}
+	return report, nil
+}
+
+type checker struct {
+	issues []*Issue
+}
+
+func (c *checker) errorf(element, msg string, args ...any) {
+	c.issues = append(c.issues, &Issue{Severity: Error, Element: element, Message: fmt.Sprintf(msg, args...)})
+}
+
+func (c *checker) warnf(element, msg string, args ...any) {
+	c.issues = append(c.issues, &Issue{Severity: Warning, Element: element, Message: fmt.Sprintf(msg, args...)})
+}
+
+// checkTime reports an error if the value is not an RFC 3339 date and time.
+func (c *checker) checkTime(element, field, value string) {
+	if value == "" {
+		c.errorf(element, "missing %s", field)
+		return
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		c.errorf(element, "%s %q is not an RFC 3339 date and time", field, value)
+	}
+}
+
+// digestLengths gives the number of hexadecimal digits of the digests of
+// each algorithm, by name in upper case without separators.
+var digestLengths = map[string]int{
+	"MD5":        32,
+	"SHA1":       40,
+	"SHA224":     56,
+	"SHA256":     64,
+	"SHA384":     96,
+	"SHA512":     128,
+	"SHA3256":    64,
+	"SHA3384":    96,
+	"SHA3512":    128,
+	"BLAKE2B256": 64,
+	"BLAKE2B384": 96,
+	"BLAKE2B512": 128,
+	"BLAKE3":     0,
+	"ADLER32":    8,
+}
+
+var hexDigest = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// checkDigest reports an error if the value is not a lowercase hexadecimal
+// digest of the right length for the algorithm.
+func (c *checker) checkDigest(element, algorithm, value string) {
+	key := strings.ToUpper(strings.NewReplacer("-", "", "_", "").Replace(algorithm))
+	length, ok := digestLengths[key]
+	switch {
+	case !ok:
+		c.errorf(element, "unknown checksum algorithm %q", algorithm)
+	case !hexDigest.MatchString(value):
+		c.errorf(element, "%s checksum %q is not a lowercase hexadecimal digest", algorithm, value)
+	case length != 0 && len(value) != length:
+		c.errorf(element, "%s checksum %q has %d digits instead of %d", algorithm, value, len(value), length)
+	}
+}
@@ -0,0 +1,227 @@
+package validate_test
+
+import (
+	"encoding/json"
+
+	"github.com/canonical/ssbom/internal/format"
+	"github.com/canonical/ssbom/internal/validate"
+	. "gopkg.in/check.v1"
+)
+
+const (
+	spdxHeader = `"spdxVersion":"SPDX-2.3","dataLicense":"CC0-1.0","SPDXID":"SPDXRef-DOCUMENT","name":"test",
+		"documentNamespace":"https://spdx.org/spdxdocs/test-1",
+		"creationInfo":{"creators":["Tool: ssbom-devel"],"created":"2024-01-01T00:00:00Z"}`
+	spdx3Header = `"@context":"https://spdx.org/rdf/3.0.1/spdx-context.jsonld","@graph":[
+		{"type":"CreationInfo","@id":"_:creationinfo","specVersion":"3.0.1","created":"2024-01-01T00:00:00Z","createdBy":["https://example.com#Tool-ssbom"]},
+		{"type":"Tool","spdxId":"https://example.com#Tool-ssbom","creationInfo":"_:creationinfo","name":"ssbom"}`
+	sha256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+)
+
+var checkTests = []struct {
+	summary string
+	data    string
+	format  format.Format
+	issues  []string
+	error   string
+}{
+	{
+		summary: "Valid SPDX document",
+		data: `{` + spdxHeader + `,
+			"packages":[{"name":"test","SPDXID":"SPDXRef-Package-test","downloadLocation":"NOASSERTION",
+				"checksums":[{"algorithm":"SHA256","checksumValue":"` + sha256 + `"}]}],
+			"relationships":[{"spdxElementId":"SPDXRef-DOCUMENT","relatedSpdxElement":"SPDXRef-Package-test","relationshipType":"DESCRIBES"}]}`,
+		format: format.SPDX,
+	}, {
+		summary: "SPDX document with dangling relationship",
+		data: `{` + spdxHeader + `,
+			"relationships":[{"spdxElementId":"SPDXRef-DOCUMENT","relatedSpdxElement":"SPDXRef-Package-test","relationshipType":"DESCRIBES"}]}`,
+		format: format.SPDX,
+		issues: []string{
+			"error: SPDXRef-DOCUMENT: relationship SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-test: SPDXRef-Package-test is not in the document",
+		},
+	}, {
+		summary: "SPDX slice containing a file of a slice not in the document",
+		data: `{` + spdxHeader + `,
+			"packages":[{"name":"test_bins","SPDXID":"SPDXRef-Slice-test-bins","downloadLocation":"NOASSERTION"}],
+			"files":[{"fileName":"/usr/bin/test","SPDXID":"SPDXRef-File-usr-bin-test"}],
+			"relationships":[
+				{"spdxElementId":"SPDXRef-DOCUMENT","relatedSpdxElement":"SPDXRef-Slice-test-bins","relationshipType":"DESCRIBES"},
+				{"spdxElementId":"SPDXRef-Slice-test-bins","relatedSpdxElement":"SPDXRef-File-usr-bin-test","relationshipType":"CONTAINS"},
+				{"spdxElementId":"SPDXRef-Slice-test-libs","relatedSpdxElement":"SPDXRef-File-usr-lib-test","relationshipType":"CONTAINS"}]}`,
+		format: format.SPDX,
+		issues: []string{
+			"error: SPDXRef-Slice-test-libs: relationship SPDXRef-Slice-test-libs CONTAINS SPDXRef-File-usr-lib-test: SPDXRef-Slice-test-libs is not in the document",
+			"error: SPDXRef-Slice-test-libs: relationship SPDXRef-Slice-test-libs CONTAINS SPDXRef-File-usr-lib-test: SPDXRef-File-usr-lib-test is not in the document",
+		},
+	}, {
+		summary: "SPDX document without required fields",
+		data: `{"spdxVersion":"SPDX-2.3","SPDXID":"SPDXRef-DOCUMENT",
+			"packages":[{"SPDXID":"SPDXRef-Package-test"}]}`,
+		format: format.SPDX,
+		issues: []string{
+			`error: SPDXRef-DOCUMENT: dataLicense must be "CC0-1.0", not ""`,
+			"error: SPDXRef-DOCUMENT: missing name",
+			"error: SPDXRef-DOCUMENT: missing documentNamespace",
+			"error: SPDXRef-DOCUMENT: missing creationInfo",
+			"error: SPDXRef-Package-test: missing package name",
+			"error: SPDXRef-Package-test: missing downloadLocation",
+			"warning: SPDXRef-DOCUMENT: document does not describe any element",
+		},
+	}, {
+		summary: "SPDX identifiers",
+		data: `{` + spdxHeader + `,
+			"packages":[
+				{"name":"test","SPDXID":"SPDXRef-Package-test","downloadLocation":"NOASSERTION"},
+				{"name":"test","SPDXID":"SPDXRef-Package-test","downloadLocation":"NOASSERTION"}],
+			"files":[{"fileName":"/test","SPDXID":"SPDXRef-File-/test"}],
+			"hasExtractedLicensingInfos":[{"licenseId":"Custom","extractedText":"text"}],
+			"relationships":[{"spdxElementId":"SPDXRef-DOCUMENT","relatedSpdxElement":"SPDXRef-Package-test","relationshipType":"DESCRIBES"}]}`,
+		format: format.SPDX,
+		issues: []string{
+			"error: SPDXRef-Package-test: duplicate SPDX identifier",
+			`error: SPDXRef-File-/test: invalid SPDX identifier: only letters, digits, "." and "-" may follow "SPDXRef-"`,
+			`error: Custom: invalid license identifier: expected "LicenseRef-" followed by letters, digits, "." and "-"`,
+		},
+	}, {
+		summary: "SPDX checksums",
+		data: `{` + spdxHeader + `,
+			"files":[{"fileName":"/test","SPDXID":"SPDXRef-File-test","checksums":[
+				{"algorithm":"SHA1","checksumValue":"` + sha256 + `"},
+				{"algorithm":"SHA256","checksumValue":"` + sha256[:63] + `G"},
+				{"algorithm":"MD5","checksumValue":""}]}],
+			"relationships":[{"spdxElementId":"SPDXRef-DOCUMENT","relatedSpdxElement":"SPDXRef-File-test","relationshipType":"DESCRIBES"}]}`,
+		format: format.SPDX,
+		issues: []string{
+			`error: SPDXRef-File-test: SHA1 checksum "` + sha256 + `" has 64 digits instead of 40`,
+			`error: SPDXRef-File-test: SHA256 checksum "` + sha256[:63] + `G" is not a lowercase hexadecimal digest`,
+			`error: SPDXRef-File-test: MD5 checksum "" is not a lowercase hexadecimal digest`,
+		},
+	}, {
+		summary: "SPDX document with invalid creation time",
+		data: `{"spdxVersion":"SPDX-2.3","dataLicense":"CC0-1.0","SPDXID":"SPDXRef-DOCUMENT","name":"test",
+			"documentNamespace":"test#1","creationInfo":{"creators":[],"created":"yesterday"},
+			"relationships":[{"spdxElementId":"SPDXRef-DOCUMENT","relatedSpdxElement":"NONE","relationshipType":"DESCRIBES"}]}`,
+		format: format.SPDX,
+		issues: []string{
+			`error: SPDXRef-DOCUMENT: documentNamespace "test#1" is not an absolute URI without "#"`,
+			"error: SPDXRef-DOCUMENT: missing creators",
+			`error: SPDXRef-DOCUMENT: created "yesterday" is not an RFC 3339 date and time`,
+		},
+	}, {
+		summary: "Undecodable SPDX document",
+		data:    `{"spdxVersion":"SPDX-2.3","SPDXID":"DOCUMENT"}`,
+		error:   "invalid SPDX document: .*",
+	}, {
+		summary: "Valid SPDX 3.0 document",
+		data: `{` + spdx3Header + `,
+			{"type":"SpdxDocument","spdxId":"https://example.com#Document","creationInfo":"_:creationinfo","dataLicense":"https://spdx.org/licenses/CC0-1.0",
+				"rootElement":["https://example.com#Package-test"]},
+			{"type":"software_Package","spdxId":"https://example.com#Package-test","creationInfo":"_:creationinfo","name":"test",
+				"verifiedUsing":[{"type":"Hash","algorithm":"sha256","hashValue":"` + sha256 + `"}]},
+			{"type":"Relationship","spdxId":"https://example.com#Relationship-1","creationInfo":"_:creationinfo",
+				"from":"https://example.com#Package-test","to":["https://spdx.org/rdf/3.0.1/terms/Core/NoAssertionElement"],"relationshipType":"dependsOn"}]}`,
+		format: format.SPDX3,
+	}, {
+		summary: "SPDX 3.0 document without SpdxDocument",
+		data:    `{"@context":"https://spdx.org/rdf/3.0.1/spdx-context.jsonld","@graph":[]}`,
+		format:  format.SPDX3,
+		issues:  []string{"error: no SpdxDocument element"},
+	}, {
+		summary: "SPDX 3.0 document with dangling references",
+		data: `{` + spdx3Header + `,
+			{"type":"SpdxDocument","spdxId":"https://example.com#Document","creationInfo":"_:creationinfo","dataLicense":"https://spdx.org/licenses/CC0-1.0",
+				"rootElement":["https://example.com#Package-test"]},
+			{"type":"software_File","spdxId":"https://example.com#File-test","creationInfo":"_:other","name":"/test",
+				"verifiedUsing":[{"type":"Hash","algorithm":"sha1","hashValue":"` + sha256 + `"}]},
+			{"type":"software_File","spdxId":"https://example.com#File-test","creationInfo":"_:creationinfo","name":"/test"},
+			{"type":"Relationship","spdxId":"https://example.com#Relationship-1","creationInfo":"_:creationinfo",
+				"from":"https://example.com#Slice-test","to":["https://example.com#File-test","https://example.com#File-other"],"relationshipType":"contains"},
+			{"type":"Annotation","spdxId":"https://example.com#Annotation-1","creationInfo":"_:creationinfo","subject":"https://example.com#Slice-test"}]}`,
+		format: format.SPDX3,
+		issues: []string{
+			"error: https://example.com#File-test: duplicate spdxId",
+			"error: https://example.com#Document: rootElement https://example.com#Package-test is not in the document",
+			"error: https://example.com#File-test: creationInfo _:other is not in the document",
+			`error: https://example.com#File-test: sha1 checksum "` + sha256 + `" has 64 digits instead of 40`,
+			"error: https://example.com#Relationship-1: from https://example.com#Slice-test is not in the document",
+			"error: https://example.com#Relationship-1: relationship https://example.com#Slice-test contains https://example.com#File-other: https://example.com#File-other is not in the document",
+			"error: https://example.com#Annotation-1: subject https://example.com#Slice-test is not in the document",
+		},
+	}, {
+		summary: "Valid CycloneDX document",
+		data: `{"bomFormat":"CycloneDX","specVersion":"1.6","version":1,
+			"serialNumber":"urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79",
+			"metadata":{"timestamp":"2024-01-01T00:00:00Z"},
+			"components":[{"type":"library","bom-ref":"Package-test","name":"test","purl":"pkg:deb/ubuntu/test@1.0",
+				"hashes":[{"alg":"SHA-256","content":"` + sha256 + `"}]}],
+			"dependencies":[{"ref":"Package-test"}]}`,
+		format: format.CycloneDX,
+	}, {
+		summary: "CycloneDX document without serial number",
+		data:    `{"bomFormat":"CycloneDX","specVersion":"1.6","version":1}`,
+		format:  format.CycloneDX,
+		issues:  []string{"warning: missing serialNumber"},
+	}, {
+		summary: "Invalid CycloneDX document",
+		data: `{"bomFormat":"CycloneDX","specVersion":"1.5","version":0,
+			"serialNumber":"urn:uuid:3e671687-395b-81f5-a30f-a58921a69b79",
+			"metadata":{"timestamp":"2024-01-01"},
+			"components":[
+				{"type":"library","bom-ref":"Package-test","name":"test","purl":"deb/test",
+					"hashes":[{"alg":"SHA-1","content":"` + sha256 + `"},{"alg":"CRC32","content":"00"}]},
+				{"type":"file","bom-ref":"Package-test","name":""}],
+			"dependencies":[{"ref":"Package-test","dependsOn":["Package-other"]},{"ref":"Slice-test"}],
+			"annotations":[{"subjects":["Slice-test"],"annotator":{"component":{"type":"application","name":"ssbom"}},"timestamp":"2024-01-01T00:00:00Z","text":"test"}]}`,
+		format: format.CycloneDX15,
+		issues: []string{
+			"error: version must be at least 1, not 0",
+			`error: serialNumber "urn:uuid:3e671687-395b-81f5-a30f-a58921a69b79" is not a URN of a UUID`,
+			`error: metadata timestamp "2024-01-01" is not an RFC 3339 date and time`,
+			`error: Package-test: SHA-1 checksum "` + sha256 + `" has 64 digits instead of 40`,
+			`error: Package-test: unknown checksum algorithm "CRC32"`,
+			`error: Package-test: purl "deb/test" does not start with "pkg:"`,
+			"error: Package-test: missing component name",
+			"error: Package-test: duplicate bom-ref",
+			"error: Package-test: dependency Package-test dependsOn Package-other: Package-other is not in the document",
+			"error: Slice-test: dependency ref Slice-test is not in the document",
+			"error: Slice-test: annotation subject Slice-test is not in the document",
+		},
+	},
+}
+
+func (s *S) TestCheck(c *C) {
+	for _, test := range checkTests {
+		c.Logf("Running test: %s", test.summary)
+		report, err := validate.Check([]byte(test.data))
+		if test.error != "" {
+			c.Assert(err, ErrorMatches, test.error)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Assert(report.Format, Equals, test.format)
+		issues := []string{}
+		for _, issue := range report.Issues {
+			issues = append(issues, issue.String())
+		}
+		if test.issues == nil {
+			test.issues = []string{}
+		}
+		c.Assert(issues, DeepEquals, test.issues)
+		c.Assert(report.Valid(), Equals, report.Count(validate.Error) == 0)
+	}
+}
+
+func (s *S) TestReportJSON(c *C) {
+	report, err := validate.Check([]byte(`{"bomFormat":"CycloneDX","specVersion":"1.6","version":1,
+		"components":[{"type":"library","bom-ref":"Package-test","name":"test","purl":"test"}]}`))
+	c.Assert(err, IsNil)
+	c.Assert(report.Valid(), Equals, false)
+	c.Assert(report.Count(validate.Error), Equals, 1)
+	c.Assert(report.Count(validate.Warning), Equals, 1)
+	data, err := json.Marshal(report)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, `{"format":"cyclonedx","issues":[`+
+		`{"severity":"warning","message":"missing serialNumber"},`+
+		`{"severity":"error","element":"Package-test","message":"purl \"test\" does not start with \"pkg:\""}]}`)
+}
@@ -0,0 +1,70 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/canonical/chisel/public/manifest"
+	"github.com/canonical/ssbom/internal/rootfs"
+)
+
+// UnmanagedFile is a file of a rootfs that is not in its manifest.
+type UnmanagedFile struct {
+	// Path is the absolute path of the file.
+	Path string
+	Mode fs.FileMode
+	// Size is the size of regular files.
+	Size int64
+	// Link is the target of symbolic links.
+	Link string
+	// SHA1 and SHA256 are the digests of the content of regular files.
+	SHA1   string
+	SHA256 string
+}
+
+// Unmanaged returns the regular files and symbolic links of fsys that are
+// not in the manifest paths, in lexical order. Other special files and
+// directories are ignored.
+func Unmanaged(ctx context.Context, fsys rootfs.FS, paths []manifest.Path) ([]*UnmanagedFile, error) {
+	known := make(map[string]bool)
+	for _, p := range paths {
+		known[p.Path] = true
+	}
+
+	var files []*UnmanagedFile
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || known["/"+name] {
+			return nil
+		}
+		info, err := fsys.Lstat(name)
+		if err != nil {
+			return err
+		}
+		file := &UnmanagedFile{Path: "/" + name, Mode: info.Mode()}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			file.Link, err = fsys.ReadLink(name)
+		case info.Mode().IsRegular():
+			file.Size = info.Size()
+			file.SHA1, file.SHA256, err = rootfs.FileDigests(fsys, name)
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find unmanaged files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,222 @@
+// Package verify compares the files of a rootfs with the paths recorded in
+// its chisel manifest.
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/canonical/chisel/public/manifest"
+	"github.com/canonical/ssbom/internal/rootfs"
+)
+
+// Kind is the kind of a discrepancy.
+type Kind string
+
+const (
+	// Missing paths are in the manifest but not in the rootfs.
+	Missing Kind = "missing"
+	// Tampered paths differ in the rootfs from the manifest.
+	Tampered Kind = "tampered"
+	// Extra paths are in the rootfs but not in the manifest.
+	Extra Kind = "extra"
+)
+
+// Discrepancy is a difference between a rootfs and its manifest.
+type Discrepancy struct {
+	Kind Kind
+	// Path is the absolute path, as in the manifest.
+	Path string
+	// Detail explains how a tampered path differs.
+	Detail string
+}
+
+func (d *Discrepancy) String() string {
+	if d.Detail != "" {
+		return fmt.Sprintf("%s %s: %s", d.Kind, d.Path, d.Detail)
+	}
+	return fmt.Sprintf("%s %s", d.Kind, d.Path)
+}
+
+// Options holds the options of Rootfs.
+type Options struct {
+	// Extra reports the files of the rootfs that are not in the manifest
+	// paths, other than those in Ignore. Most images have some, such as
+	// files added after chisel cut the rootfs.
+	Extra bool
+	// Ignore holds the absolute paths of files never reported as extra,
+	// such as the manifests.
+	Ignore []string
+}
+
+// Rootfs compares the files of fsys with the manifest paths. The content of
+// regular files is checked against their final digest, or their digest if
+// they were not mutated; files without a digest, such as the manifest
+// itself, are only checked for existence. Files that are not in the manifest
+// are only reported with opts.Extra, and directories never are, as chisel
+// creates parent directories as needed. Discrepancies are sorted by path.
+func Rootfs(ctx context.Context, fsys rootfs.FS, paths []manifest.Path, opts *Options) ([]*Discrepancy, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	var found []*Discrepancy
+	known := make(map[string]bool)
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		known[p.Path] = true
+		d, err := checkPath(fsys, &p)
+		if err != nil {
+			return nil, fmt.Errorf("cannot verify %s: %w", p.Path, err)
+		}
+		if d != nil {
+			found = append(found, d)
+		}
+	}
+	if opts.Extra {
+		extra, err := extraFiles(ctx, fsys, known, opts.Ignore)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, extra...)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found, nil
+}
+
+// extraFiles returns the files of fsys that are neither known nor ignored.
+func extraFiles(ctx context.Context, fsys rootfs.FS, known map[string]bool, ignore []string) ([]*Discrepancy, error) {
+	for _, p := range ignore {
+		known[p] = true
+	}
+	var found []*Discrepancy
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.IsDir() && !known["/"+name] {
+			found = append(found, &Discrepancy{Kind: Extra, Path: "/" + name})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify rootfs: %w", err)
+	}
+	return found, nil
+}
+
+func checkPath(fsys rootfs.FS, p *manifest.Path) (*Discrepancy, error) {
+	name := strings.Trim(p.Path, "/")
+	tampered := func(format string, args ...any) (*Discrepancy, error) {
+		return &Discrepancy{Kind: Tampered, Path: p.Path, Detail: fmt.Sprintf(format, args...)}, nil
+	}
+
+	info, err := fsys.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return &Discrepancy{Kind: Missing, Path: p.Path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(p.Path, "/"):
+		if !info.IsDir() {
+			return tampered("%s, expected directory", typeName(info.Mode()))
+		}
+		return nil, nil
+	case p.Link != "":
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return tampered("%s, expected symlink", typeName(info.Mode()))
+		}
+		target, err := fsys.ReadLink(name)
+		if err != nil {
+			return nil, err
+		}
+		if target != p.Link {
+			return tampered("symlink to %s, expected %s", target, p.Link)
+		}
+		return nil, nil
+	}
+
+	if !info.Mode().IsRegular() {
+		return tampered("%s, expected regular file", typeName(info.Mode()))
+	}
+	if mode, err := parseMode(p.Mode); err == nil && info.Mode()&modeMask != mode {
+		return tampered("mode %s, expected %s", formatMode(info.Mode()&modeMask), p.Mode)
+	}
+	expected := p.FinalSHA256
+	if expected == "" {
+		expected = p.SHA256
+	}
+	if expected == "" {
+		return nil, nil
+	}
+	_, digest, err := rootfs.FileDigests(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if digest != expected {
+		return tampered("sha256 %s, expected %s", digest, expected)
+	}
+	return nil, nil
+}
+
+const modeMask = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// parseMode parses an octal mode of the manifest, such as "04755".
+func parseMode(s string) (fs.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	mode := fs.FileMode(m) & fs.ModePerm
+	if m&04000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode, nil
+}
+
+// formatMode formats a mode as in the manifest.
+func formatMode(mode fs.FileMode) string {
+	m := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&fs.ModeSticky != 0 {
+		m |= 01000
+	}
+	return fmt.Sprintf("0%o", m)
+}
+
+func typeName(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsRegular():
+		return "regular file"
+	}
+	return "special file"
+}
@@ -0,0 +1,164 @@
+package verify_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/canonical/chisel/public/manifest"
+	"github.com/canonical/ssbom/internal/rootfs"
+	"github.com/canonical/ssbom/internal/verify"
+	. "gopkg.in/check.v1"
+)
+
+// sha256 of "hello\n".
+const helloSHA256 = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+var verifyPaths = []manifest.Path{
+	{Kind: "path", Path: "/usr/bin/", Mode: "0755", Slices: []string{"hello_bins"}},
+	{Kind: "path", Path: "/usr/bin/hello", Mode: "0755", Slices: []string{"hello_bins"}, SHA256: helloSHA256, Size: 6},
+	{Kind: "path", Path: "/usr/bin/hi", Mode: "0777", Slices: []string{"hello_bins"}, Link: "hello"},
+	{Kind: "path", Path: "/etc/hello.conf", Mode: "0644", Slices: []string{"hello_config"}, SHA256: "0000", FinalSHA256: helloSHA256, Size: 6},
+	{Kind: "path", Path: "/var/lib/chisel/manifest.wall", Mode: "0644", Slices: []string{"hello_bins"}},
+}
+
+func writeRootfs(c *C, files map[string]string, links map[string]string) string {
+	dir := c.MkDir()
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(os.WriteFile(p, []byte(data), 0644), IsNil)
+	}
+	for name, target := range links {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(os.Symlink(target, p), IsNil)
+	}
+	return dir
+}
+
+func (s *S) TestRootfsMatches(c *C) {
+	dir := writeRootfs(c, map[string]string{
+		"usr/bin/hello":                "hello\n",
+		"etc/hello.conf":               "hello\n",
+		"var/lib/chisel/manifest.wall": "manifest",
+	}, map[string]string{
+		"usr/bin/hi": "hello",
+	})
+	c.Assert(os.Chmod(filepath.Join(dir, "usr/bin/hello"), 0755), IsNil)
+
+	found, err := verify.Rootfs(context.Background(), rootfs.Dir(dir), verifyPaths, &verify.Options{Extra: true})
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 0)
+}
+
+func (s *S) TestRootfsDiscrepancies(c *C) {
+	dir := writeRootfs(c, map[string]string{
+		"usr/bin/hello":  "tampered\n",
+		"etc/hello.conf": "hello\n",
+		"etc/extra":      "extra",
+	}, map[string]string{
+		"usr/bin/hi": "/usr/bin/hello",
+	})
+
+	found, err := verify.Rootfs(context.Background(), rootfs.Dir(dir), verifyPaths, &verify.Options{Extra: true})
+	c.Assert(err, IsNil)
+	var lines []string
+	for _, d := range found {
+		lines = append(lines, d.String())
+	}
+	c.Assert(lines, DeepEquals, []string{
+		"extra /etc/extra",
+		"tampered /usr/bin/hello: mode 0644, expected 0755",
+		"tampered /usr/bin/hi: symlink to /usr/bin/hello, expected hello",
+		"missing /var/lib/chisel/manifest.wall",
+	})
+
+	c.Assert(os.Chmod(filepath.Join(dir, "usr/bin/hello"), 0755), IsNil)
+	found, err = verify.Rootfs(context.Background(), rootfs.Dir(dir), verifyPaths[1:2], &verify.Options{Extra: true})
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 4)
+	c.Assert(found[2], DeepEquals, &verify.Discrepancy{
+		Kind:   verify.Tampered,
+		Path:   "/usr/bin/hello",
+		Detail: "sha256 92e78d0b032962f47792a9fa95fd981ef63e1e3ef074d536d6304c75eddbe29f, expected " + helloSHA256,
+	})
+}
+
+func (s *S) TestRootfsWrongTypes(c *C) {
+	dir := writeRootfs(c, map[string]string{
+		"usr/bin":                      "not a directory",
+		"etc/hello.conf/file":          "hello\n",
+		"var/lib/chisel/manifest.wall": "manifest",
+	}, nil)
+
+	found, err := verify.Rootfs(context.Background(), rootfs.Dir(dir), verifyPaths, &verify.Options{Extra: true})
+	c.Assert(err, IsNil)
+	var lines []string
+	for _, d := range found {
+		lines = append(lines, d.String())
+	}
+	c.Assert(lines, DeepEquals, []string{
+		"tampered /etc/hello.conf: directory, expected regular file",
+		"extra /etc/hello.conf/file",
+		"extra /usr/bin",
+		"tampered /usr/bin/: regular file, expected directory",
+		"missing /usr/bin/hello",
+		"missing /usr/bin/hi",
+	})
+}
+
+func (s *S) TestRootfsExtra(c *C) {
+	// Images have files that chisel did not install, such as the os-release
+	// written by the image build, and manifests that do not list themselves.
+	dir := writeRootfs(c, map[string]string{
+		"usr/bin/hello":                "hello\n",
+		"etc/hello.conf":               "hello\n",
+		"etc/os-release":               "ID=ubuntu\n",
+		"var/lib/chisel/manifest.wall": "manifest",
+	}, map[string]string{
+		"usr/bin/hi": "hello",
+	})
+	c.Assert(os.Chmod(filepath.Join(dir, "usr/bin/hello"), 0755), IsNil)
+	paths := verifyPaths[:4]
+
+	found, err := verify.Rootfs(context.Background(), rootfs.Dir(dir), paths, nil)
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 0)
+
+	found, err = verify.Rootfs(context.Background(), rootfs.Dir(dir), paths, &verify.Options{
+		Extra:  true,
+		Ignore: []string{"/var/lib/chisel/manifest.wall"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(found, DeepEquals, []*verify.Discrepancy{{Kind: verify.Extra, Path: "/etc/os-release"}})
+}
+
+func (s *S) TestUnmanaged(c *C) {
+	dir := writeRootfs(c, map[string]string{
+		"usr/bin/hello":      "hello\n",
+		"etc/extra":          "extra",
+		"usr/share/doc/NEWS": "hello\n",
+	}, map[string]string{
+		"usr/bin/hi":   "hello",
+		"usr/bin/hola": "hello",
+	})
+
+	files, err := verify.Unmanaged(context.Background(), rootfs.Dir(dir), verifyPaths)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 3)
+	c.Assert(*files[0], DeepEquals, verify.UnmanagedFile{
+		Path:   "/etc/extra",
+		Mode:   0644,
+		Size:   5,
+		SHA1:   "b43c4b82570e182eb1c74072896167113d2c7345",
+		SHA256: "c8dee78f8c7b466c881847accc196998bad00e2b96c5ef913dfbe454d3807c96",
+	})
+	c.Assert(*files[1], DeepEquals, verify.UnmanagedFile{
+		Path: "/usr/bin/hola",
+		Mode: os.ModeSymlink | 0777,
+		Link: "hello",
+	})
+	c.Assert(files[2].Path, Equals, "/usr/share/doc/NEWS")
+	c.Assert(files[2].SHA256, Equals, helloSHA256)
+}
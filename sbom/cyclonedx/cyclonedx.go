@@ -0,0 +1,144 @@
+// Package cyclonedx contains the subset of the CycloneDX JSON model that is
+// needed to describe a chiselled rootfs.
+// See https://cyclonedx.org/docs/1.6/json/
+package cyclonedx
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	BOMFormat = "CycloneDX"
+	// SpecVersion is the default version of the CycloneDX specification
+	// documents are produced for. The model below is also valid for
+	// SpecVersion15.
+	SpecVersion   = "1.6"
+	SpecVersion15 = "1.5"
+)
+
+// Component types.
+const (
+	TypeApplication     = "application"
+	TypeLibrary         = "library"
+	TypeFile            = "file"
+	TypeOperatingSystem = "operating-system"
+)
+
+// Hash algorithms.
+const (
+	SHA1   = "SHA-1"
+	SHA256 = "SHA-256"
+)
+
+// BOM is a CycloneDX Bill of Materials.
+type BOM struct {
+	BOMFormat    string        `json:"bomFormat"`
+	SpecVersion  string        `json:"specVersion"`
+	SerialNumber string        `json:"serialNumber,omitempty"`
+	Version      int           `json:"version"`
+	Metadata     *Metadata     `json:"metadata,omitempty"`
+	Components   []*Component  `json:"components,omitempty"`
+	Dependencies []*Dependency `json:"dependencies,omitempty"`
+	Annotations  []*Annotation `json:"annotations,omitempty"`
+}
+
+type Metadata struct {
+	Timestamp string     `json:"timestamp,omitempty"`
+	Tools     *Tools     `json:"tools,omitempty"`
+	Component *Component `json:"component,omitempty"`
+}
+
+type Tools struct {
+	Components []*Component `json:"components,omitempty"`
+}
+
+type OrganizationalEntity struct {
+	Name    string                   `json:"name,omitempty"`
+	Contact []*OrganizationalContact `json:"contact,omitempty"`
+}
+
+type OrganizationalContact struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type Component struct {
+	Type               string                `json:"type"`
+	BOMRef             string                `json:"bom-ref,omitempty"`
+	Supplier           *OrganizationalEntity `json:"supplier,omitempty"`
+	Group              string                `json:"group,omitempty"`
+	Name               string                `json:"name"`
+	Version            string                `json:"version,omitempty"`
+	Description        string                `json:"description,omitempty"`
+	Hashes             []Hash                `json:"hashes,omitempty"`
+	Licenses           []LicenseChoice       `json:"licenses,omitempty"`
+	Copyright          string                `json:"copyright,omitempty"`
+	CPE                string                `json:"cpe,omitempty"`
+	PURL               string                `json:"purl,omitempty"`
+	ExternalReferences []ExternalReference   `json:"externalReferences,omitempty"`
+	Properties         []Property            `json:"properties,omitempty"`
+	Components         []*Component          `json:"components,omitempty"`
+	Evidence           *Evidence             `json:"evidence,omitempty"`
+}
+
+// External reference types.
+const (
+	ReferenceWebsite      = "website"
+	ReferenceDistribution = "distribution"
+)
+
+type ExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// LicenseChoice is a license of a component, given as an SPDX license
+// expression.
+type LicenseChoice struct {
+	Expression string `json:"expression"`
+}
+
+// Evidence records how a component was identified.
+type Evidence struct {
+	Occurrences []Occurrence `json:"occurrences,omitempty"`
+}
+
+type Occurrence struct {
+	Location string `json:"location"`
+}
+
+type Hash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+type Annotation struct {
+	Subjects  []string   `json:"subjects"`
+	Annotator *Annotator `json:"annotator"`
+	Timestamp string     `json:"timestamp"`
+	Text      string     `json:"text"`
+}
+
+// Annotator is the entity that created an annotation. Only annotations by
+// components are supported.
+type Annotator struct {
+	Component *Component `json:"component,omitempty"`
+}
+
+// Write writes the BOM to w in JSON format.
+func Write(bom *BOM, w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	return e.Encode(bom)
+}
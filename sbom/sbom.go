@@ -0,0 +1,715 @@
+// Package sbom generates SBOM documents for chiselled Ubuntu rootfs from the
+// manifests that chisel writes into them.
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canonical/chisel/public/manifest"
+	"github.com/canonical/ssbom/internal/apt"
+	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/internal/catalog"
+	"github.com/canonical/ssbom/internal/converter"
+	"github.com/canonical/ssbom/internal/copyright"
+	"github.com/canonical/ssbom/internal/cpe"
+	"github.com/canonical/ssbom/internal/format"
+	"github.com/canonical/ssbom/internal/gobinary"
+	"github.com/canonical/ssbom/internal/osrelease"
+	"github.com/canonical/ssbom/internal/pro"
+	"github.com/canonical/ssbom/internal/rootfs"
+	"github.com/canonical/ssbom/internal/validate"
+	"github.com/canonical/ssbom/internal/verify"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx"
+)
+
+// Format is an SBOM document format.
+type Format = format.Format
+
+const (
+	// SPDX is SPDX 2.3 JSON.
+	SPDX = format.SPDX
+	// SPDX3 is SPDX 3.0 JSON-LD.
+	SPDX3 = format.SPDX3
+	// CycloneDX is CycloneDX 1.6 JSON.
+	CycloneDX = format.CycloneDX
+	// CycloneDX15 is CycloneDX 1.5 JSON.
+	CycloneDX15 = format.CycloneDX15
+)
+
+// OSReleasePath is the path of the os-release file in a rootfs.
+const OSReleasePath = "etc/os-release"
+
+// Source is the input of Generate: a rootfs, given by Path or FS, a chisel
+// manifest, or both. When both are given, Manifest and OSRelease take
+// precedence over the files of the rootfs.
+type Source struct {
+	// Path is the path of a rootfs directory, of a (possibly compressed)
+	// tar archive of a rootfs, of an OCI image layout directory, or of a
+	// tar archive of an OCI image layout or of the output of "docker save".
+	Path string
+	// FS is a rootfs to read instead of Path.
+	FS fs.FS
+	// Manifest is a chisel manifest, zstd compressed or not, to read
+	// instead of the manifests found in the rootfs.
+	Manifest io.Reader
+	// OSRelease is an os-release file to read instead of the one of the
+	// rootfs.
+	OSRelease io.Reader
+	// Copyrights holds Debian copyright files, as <package>/copyright, to
+	// read instead of the ones of the rootfs in /usr/share/doc.
+	Copyrights fs.FS
+	// AptIndexes are Packages and Sources indexes of the apt archive the
+	// packages come from, gzip or zstd compressed or not. They describe
+	// the source, maintainer, homepage, summary, download location and
+	// dependencies of the packages.
+	AptIndexes []io.Reader
+	// AptMirror is a mirror of the apt archive, whose indexes in its dists
+	// directory are read as AptIndexes.
+	AptMirror fs.FS
+}
+
+// Options control how an SBOM is generated.
+type Options struct {
+	// Format is the format of the generated document. It defaults to SPDX.
+	Format Format
+	// Image selects the image to read from an archive holding several,
+	// by reference name or tag.
+	Image string
+	// Platform selects the image to read from a multi-platform index, in
+	// the "os/arch[/variant]" form.
+	Platform string
+	// Lenient makes Generate skip the manifest entries that cannot be read
+	// rather than fail. The document is then annotated with the skipped
+	// entries, which are also reported as warnings.
+	Lenient bool
+	// Verify compares the files of the rootfs with its manifests, reporting
+	// the differences in Result.Discrepancies. The content of every file of
+	// the rootfs is read.
+	Verify bool
+	// VerifyStrict makes Generate fail with a *VerifyError if the rootfs
+	// does not match its manifests. It implies Verify.
+	VerifyStrict bool
+	// VerifyExtra also reports the files of the rootfs that are not in its
+	// manifests, other than the manifests themselves, as Extra
+	// discrepancies. It implies Verify.
+	VerifyExtra bool
+	// Unmanaged adds the regular files and symbolic links of the rootfs
+	// that are not in its manifests to the document, with their digests,
+	// as the content of a synthetic "unmanaged-content" package. They are
+	// also listed in Result.Unmanaged.
+	Unmanaged bool
+	// GoModules adds the modules linked into the Go executables of the
+	// rootfs to the document, as packages with a pkg:golang purl contained
+	// in the file of each executable. They are also listed in
+	// Result.GoBinaries.
+	GoModules bool
+	// Cataloguers find the packages of language ecosystems in the rootfs,
+	// which are added to the document next to the deb packages. They are
+	// also listed in Result.EcosystemPackages.
+	Cataloguers []Cataloguer
+	// ArchiveURL is the URL of the apt archive used to build the download
+	// location of the packages found in the apt indexes of the source. It
+	// defaults to the Debian archive for Debian rootfs, and otherwise to the
+	// Ubuntu archive for amd64 and i386 packages and to the Ubuntu ports
+	// archive for others.
+	ArchiveURL string
+	// CPEMap overrides the entries of the bundled table of the CPE vendors
+	// and products of packages, for the same package names.
+	CPEMap CPEMap
+	// ProPocket is the Ubuntu Pro pocket the packages with ESM or FIPS
+	// versions come from, one of "esm-infra", "esm-apps", "fips",
+	// "fips-updates" or "fips-preview". Otherwise, ESM packages are taken
+	// to come from esm-apps if they are in the universe component of the
+	// apt indexes of the source and from esm-infra if not, and FIPS packages
+	// from fips.
+	ProPocket string
+	// DocumentName is the name of the document. It defaults to
+	// "Chiselled Ubuntu Rootfs".
+	DocumentName string
+	// NamespacePrefix is the URI prefix of the namespace of SPDX 2.3 and
+	// 3.0 documents, which is followed by the name of the document and a
+	// random UUID, or a digest of the document with Reproducible. It
+	// defaults to "https://spdx.org/spdxdocs".
+	NamespacePrefix string
+	// CreatorOrganization is the organization that created the document,
+	// recorded next to ssbom in the creators of SPDX documents.
+	CreatorOrganization string
+	// ToolVersion is the version of ssbom recorded in the document. It
+	// defaults to the version of the ssbom module in the build information.
+	ToolVersion string
+	// Reproducible makes Generate write the same document for the same
+	// content: the namespace of SPDX documents and the serial number of
+	// CycloneDX BOMs are derived from a digest of the document, and the
+	// creation time is the Unix epoch unless SOURCE_DATE_EPOCH is set.
+	Reproducible bool
+}
+
+// sourceDateEpoch returns the time given by the SOURCE_DATE_EPOCH
+// environment variable, or the zero time if it is not set.
+// See https://reproducible-builds.org/specs/source-date-epoch/
+func sourceDateEpoch() (time.Time, error) {
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", value)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// CPEMap maps binary or source package names to the vendors and products
+// they are known as in CPE names.
+type CPEMap = cpe.Table
+
+// ParseCPEMap parses a CPE map where every line gives a package name
+// followed by one or more vendor:product pairs, as in
+// "libssl3 openssl:openssl". Lines starting with "#" are ignored.
+func ParseCPEMap(data []byte) (CPEMap, error) {
+	return cpe.ParseTable(data)
+}
+
+// Cataloguer finds the packages of a language ecosystem in a rootfs.
+type Cataloguer = catalog.Cataloguer
+
+// EcosystemPackage is a package of a language ecosystem found in a rootfs.
+type EcosystemPackage = catalog.Package
+
+// Cataloguers returns the cataloguers for every supported ecosystem: Python
+// distributions, npm packages and Java archives.
+func Cataloguers() []Cataloguer {
+	return catalog.Cataloguers()
+}
+
+// LookupCataloguer returns the cataloguer with the given name, one of
+// "python", "node" or "java".
+func LookupCataloguer(name string) (Cataloguer, error) {
+	return catalog.Lookup(name)
+}
+
+// Discrepancy is a difference between a rootfs and its manifests.
+type Discrepancy = verify.Discrepancy
+
+// Kinds of discrepancies.
+const (
+	Missing  = verify.Missing
+	Tampered = verify.Tampered
+	Extra    = verify.Extra
+)
+
+// UnmanagedFile is a file of a rootfs that is not in its manifests.
+type UnmanagedFile = verify.UnmanagedFile
+
+// GoBinary is a Go executable of a rootfs.
+type GoBinary = gobinary.Binary
+
+// ValidationIssue is a problem found when validating a generated document
+// against the specification of its format.
+type ValidationIssue = validate.Issue
+
+// Severities of validation issues.
+const (
+	ValidationError   = validate.Error
+	ValidationWarning = validate.Warning
+)
+
+// VerifyError is returned by Generate when the rootfs does not match its
+// manifests in VerifyStrict mode.
+type VerifyError struct {
+	Discrepancies []*Discrepancy
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("rootfs does not match its manifest: %d discrepancies found", len(e.Discrepancies))
+}
+
+// Result holds a generated SBOM document. Only the field matching Format is
+// set.
+type Result struct {
+	Format    Format
+	SPDX      *spdx.Document
+	SPDX3     *spdx3.Document
+	CycloneDX *cyclonedx.BOM
+	// Manifests lists the paths of the manifests read from the rootfs.
+	Manifests []string
+	// Warnings describes the issues found that did not prevent the
+	// document from being generated.
+	Warnings []string
+	// Discrepancies lists the differences between the rootfs and its
+	// manifests found in Verify mode.
+	Discrepancies []*Discrepancy
+	// Unmanaged lists the files of the rootfs that are not in its
+	// manifests, found in Unmanaged mode.
+	Unmanaged []*UnmanagedFile
+	// GoBinaries lists the Go executables of the rootfs found in GoModules
+	// mode.
+	GoBinaries []*GoBinary
+	// EcosystemPackages lists the packages found by the cataloguers.
+	EcosystemPackages []*EcosystemPackage
+	// Validation lists the issues found when validating the generated
+	// document. A document with issues of ValidationError severity does not
+	// follow the specification of its format.
+	Validation []*ValidationIssue
+}
+
+// Write writes the document as JSON.
+func (r *Result) Write(w io.Writer) error {
+	switch {
+	case r.SPDX != nil:
+		return json.Write(r.SPDX, w, json.EscapeHTML(false))
+	case r.SPDX3 != nil:
+		return spdx3.Write(r.SPDX3, w)
+	case r.CycloneDX != nil:
+		return cyclonedx.Write(r.CycloneDX, w)
+	}
+	return fmt.Errorf("cannot write SBOM: no document")
+}
+
+// Generate generates an SBOM document for the source.
+func Generate(ctx context.Context, source *Source, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	outFormat := opts.Format
+	if outFormat == "" {
+		outFormat = SPDX
+	}
+	if _, err := format.Parse(string(outFormat)); err != nil {
+		return nil, fmt.Errorf("cannot generate SBOM: %w", err)
+	}
+	var proLabel pro.Pocket
+	if opts.ProPocket != "" {
+		var err error
+		if proLabel, err = pro.ParsePocket(opts.ProPocket); err != nil {
+			return nil, fmt.Errorf("cannot generate SBOM: %w", err)
+		}
+	}
+	created, err := sourceDateEpoch()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate SBOM: %w", err)
+	}
+	if created.IsZero() && opts.Reproducible {
+		created = time.Unix(0, 0).UTC()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fsys, err := openSource(source, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate SBOM: %w", err)
+	}
+	result := &Result{Format: outFormat}
+	manifestData, err := readManifests(ctx, source, fsys, &result.Manifests, &result.Warnings)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Lenient {
+		if err := manifestData.Err(); err != nil {
+			return nil, err
+		}
+	}
+	for _, err := range manifestData.ReadErrors {
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+	for _, inconsistency := range manifestData.CheckConsistency() {
+		result.Warnings = append(result.Warnings, "inconsistent manifest: "+inconsistency.String())
+	}
+	rootFS, isRootFS := fsys.(rootfs.FS)
+	if opts.Verify || opts.VerifyStrict || opts.VerifyExtra {
+		if !isRootFS {
+			return nil, fmt.Errorf("cannot verify rootfs: no rootfs given, or it does not support symbolic links")
+		}
+		result.Discrepancies, err = verify.Rootfs(ctx, rootFS, manifestData.Paths, &verify.Options{
+			Extra:  opts.VerifyExtra,
+			Ignore: result.Manifests,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if opts.VerifyStrict && len(result.Discrepancies) > 0 {
+			return nil, &VerifyError{Discrepancies: result.Discrepancies}
+		}
+	}
+	if opts.Unmanaged {
+		if !isRootFS {
+			return nil, fmt.Errorf("cannot find unmanaged files: no rootfs given, or it does not support symbolic links")
+		}
+		result.Unmanaged, err = verify.Unmanaged(ctx, rootFS, manifestData.Paths)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.GoModules {
+		if fsys == nil {
+			return nil, fmt.Errorf("cannot scan Go executables: no rootfs given")
+		}
+		result.GoBinaries, err = gobinary.Scan(ctx, fsys)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Cataloguers) > 0 {
+		if fsys == nil {
+			return nil, fmt.Errorf("cannot catalog packages: no rootfs given")
+		}
+		var warnings []string
+		result.EcosystemPackages, warnings, err = catalog.Run(ctx, fsys, opts.Cataloguers)
+		if err != nil {
+			return nil, err
+		}
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+
+	var copyrightWarnings []string
+	manifestData.Copyrights, copyrightWarnings = readCopyrights(source, fsys, manifestData.Packages)
+	result.Warnings = append(result.Warnings, copyrightWarnings...)
+
+	var aptWarnings []string
+	manifestData.Archive, aptWarnings, err = readAptIndexes(source, fsys)
+	if err != nil {
+		return nil, err
+	}
+	manifestData.ArchiveURL = opts.ArchiveURL
+	manifestData.CPEMap = opts.CPEMap
+	manifestData.ProLabel = proLabel
+	manifestData.Document = builder.DocumentInfo{
+		Name:            opts.DocumentName,
+		NamespacePrefix: opts.NamespacePrefix,
+		Organization:    opts.CreatorOrganization,
+		ToolVersion:     opts.ToolVersion,
+		Created:         created,
+	}
+	result.Warnings = append(result.Warnings, aptWarnings...)
+
+	var osRelease []byte
+	switch {
+	case source.OSRelease != nil:
+		osRelease, err = io.ReadAll(source.OSRelease)
+	case fsys != nil:
+		osRelease, err = fs.ReadFile(fsys, OSReleasePath)
+	default:
+		err = fs.ErrNotExist
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		result.Warnings = append(result.Warnings, "OS release file not found; the generated SBOM will be incomplete for vulnerability identification")
+	} else if err != nil {
+		return nil, err
+	} else if manifestData.OSRelease, err = osrelease.Parse(osRelease); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	manifestData.Unmanaged = make([]builder.UnmanagedFileInfo, 0, len(result.Unmanaged))
+	for _, f := range result.Unmanaged {
+		manifestData.Unmanaged = append(manifestData.Unmanaged, builder.UnmanagedFileInfo{
+			Path:   f.Path,
+			Link:   f.Link,
+			SHA1:   f.SHA1,
+			SHA256: f.SHA256,
+		})
+	}
+	manifestData.GoBinaries = goBinaryInfos(result.GoBinaries)
+	manifestData.EcosystemPackages = make([]builder.EcosystemPackageInfo, 0, len(result.EcosystemPackages))
+	for _, p := range result.EcosystemPackages {
+		manifestData.EcosystemPackages = append(manifestData.EcosystemPackages, builder.EcosystemPackageInfo{
+			Type:      p.Type,
+			Namespace: p.Namespace,
+			Name:      p.Name,
+			Version:   p.Version,
+			PURL:      p.PURL(),
+			Location:  p.Location,
+		})
+	}
+	if opts.Reproducible {
+		manifestData.Document.Digest = manifestData.Digest()
+	}
+
+	switch outFormat {
+	case SPDX:
+		result.SPDX, err = manifestData.BuildSPDX()
+	case SPDX3:
+		result.SPDX3, err = manifestData.BuildSPDX3()
+	case CycloneDX, CycloneDX15:
+		result.CycloneDX, err = manifestData.BuildCycloneDX()
+		if err == nil && outFormat == CycloneDX15 {
+			result.CycloneDX.SpecVersion = cyclonedx.SpecVersion15
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Reproducible {
+		// The document depends on more than the manifest digest it was
+		// built with: derive its namespace and serial number from all of
+		// its content.
+		info := manifestData.Document
+		h := sha256.New()
+		if err := result.Write(h); err != nil {
+			return nil, err
+		}
+		info.Digest = hex.EncodeToString(h.Sum(nil))
+		switch {
+		case result.SPDX != nil:
+			builder.SetSPDXDocumentInfo(result.SPDX, info)
+		case result.CycloneDX != nil:
+			builder.SetCycloneDXDocumentInfo(result.CycloneDX, info)
+		case result.SPDX3 != nil:
+			// The namespace is part of the identifier of every element.
+			manifestData.Document = info
+			if result.SPDX3, err = manifestData.BuildSPDX3(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := result.Write(&buf); err != nil {
+		return nil, err
+	}
+	report, err := validate.Check(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("cannot validate SBOM: %w", err)
+	}
+	result.Validation = report.Issues
+	return result, nil
+}
+
+// goBinaryInfos returns the builder inputs for the Go executables. The
+// modules of each executable are its main module, if versioned, the
+// standard library and its dependencies.
+func goBinaryInfos(binaries []*GoBinary) []builder.GoBinaryInfo {
+	infos := make([]builder.GoBinaryInfo, 0, len(binaries))
+	for _, b := range binaries {
+		info := builder.GoBinaryInfo{Path: b.Path, SHA256: b.SHA256}
+		if b.Main.Path != "" && b.Main.Version != "" {
+			info.Modules = append(info.Modules, builder.GoModuleInfo{Path: b.Main.Path, Version: b.Main.Version})
+		}
+		if version := b.StdlibVersion(); version != "" {
+			info.Modules = append(info.Modules, builder.GoModuleInfo{Path: "stdlib", Version: version})
+		}
+		for _, dep := range b.Deps {
+			info.Modules = append(info.Modules, builder.GoModuleInfo{Path: dep.Path, Version: dep.Version})
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// ManifestReport is the result of ValidateManifest.
+type ManifestReport struct {
+	// Manifests lists the paths of the manifests read from the rootfs.
+	Manifests []string
+	// Issues describes every manifest entry that cannot be read and every
+	// inconsistency between entries.
+	Issues []string
+	// Warnings describes the disagreements between the manifests of the
+	// rootfs, if it has several.
+	Warnings []string
+}
+
+// ValidateManifest reads the chisel manifests of the source and checks that
+// their entries can be read and agree with each other.
+func ValidateManifest(ctx context.Context, source *Source, opts *Options) (*ManifestReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fsys, err := openSource(source, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot validate manifest: %w", err)
+	}
+	report := &ManifestReport{}
+	manifestData, err := readManifests(ctx, source, fsys, &report.Manifests, &report.Warnings)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range manifestData.ReadErrors {
+		report.Issues = append(report.Issues, err.Error())
+	}
+	for _, inconsistency := range manifestData.CheckConsistency() {
+		report.Issues = append(report.Issues, inconsistency.String())
+	}
+	return report, nil
+}
+
+// openSource opens the rootfs of the source, if any.
+func openSource(source *Source, opts *Options) (fs.FS, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	fsys := source.FS
+	if fsys == nil && source.Path != "" {
+		want := wantFile
+		if len(opts.Cataloguers) > 0 {
+			want = func(name string) bool {
+				return wantFile(name) || catalog.Match(opts.Cataloguers, name)
+			}
+		}
+		var err error
+		fsys, err = rootfs.Open(source.Path, &rootfs.Options{
+			Want: want,
+			// Every file is hashed as it is read, rather than kept.
+			Digests: opts.Verify || opts.VerifyStrict || opts.VerifyExtra || opts.Unmanaged,
+			// The build information of Go executables is read.
+			Executables: opts.GoModules,
+			Image:       opts.Image,
+			Platform:    opts.Platform,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fsys == nil && source.Manifest == nil {
+		return nil, fmt.Errorf("no rootfs or manifest given")
+	}
+	return fsys, nil
+}
+
+// readManifests reads the manifest of the source, or every manifest of its
+// rootfs, recording their paths and disagreements.
+func readManifests(ctx context.Context, source *Source, fsys fs.FS, manifests, warnings *[]string) (*converter.ManifestData, error) {
+	if source.Manifest != nil {
+		return converter.ReadManifest(source.Manifest)
+	}
+	names, err := converter.FindManifests(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("cannot find a chisel manifest in the rootfs")
+	}
+	byPath := make(map[string]*converter.ManifestData)
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		md, err := converter.ReadManifest(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read /%s: %w", name, err)
+		}
+		byPath["/"+name] = md
+		*manifests = append(*manifests, "/"+name)
+	}
+	md, disagreements := converter.MergeManifests(byPath)
+	*warnings = append(*warnings, disagreements...)
+	return md, nil
+}
+
+// readCopyrights reads the machine-readable copyright files of the
+// packages, from the copyrights of the source or otherwise from the rootfs.
+// Missing and non machine-readable files are skipped, as many packages do
+// not have one; the files that cannot be parsed are reported as warnings.
+func readCopyrights(source *Source, fsys fs.FS, packages []manifest.Package) (map[string]*copyright.Copyright, []string) {
+	copyrights := make(map[string]*copyright.Copyright)
+	var warnings []string
+	for _, p := range packages {
+		var data []byte
+		err := fs.ErrNotExist
+		if source.Copyrights != nil {
+			data, err = fs.ReadFile(source.Copyrights, p.Name+"/copyright")
+		}
+		if errors.Is(err, fs.ErrNotExist) && fsys != nil {
+			data, err = fs.ReadFile(fsys, path.Join(docPath, p.Name, "copyright"))
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		var c *copyright.Copyright
+		if err == nil {
+			c, err = copyright.Parse(data)
+		}
+		if errors.Is(err, copyright.ErrNotMachineReadable) {
+			continue
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("cannot read copyright file of package %s: %v", p.Name, err))
+			continue
+		}
+		copyrights[p.Name] = c
+	}
+	return copyrights, warnings
+}
+
+// readAptIndexes reads the apt indexes and the mirror of the source, and
+// the dpkg status database of the rootfs, if any, which describes the
+// packages installed with dpkg. It returns nil if there are none. Status
+// files that cannot be read are reported as warnings.
+func readAptIndexes(source *Source, fsys fs.FS) (*apt.Index, []string, error) {
+	idx := apt.NewIndex()
+	for _, r := range source.AptIndexes {
+		if err := idx.Read(r); err != nil {
+			return nil, nil, err
+		}
+	}
+	if source.AptMirror != nil {
+		if err := idx.ReadMirror(source.AptMirror); err != nil {
+			return nil, nil, err
+		}
+	}
+	var warnings []string
+	if fsys != nil {
+		names, _ := fs.Glob(fsys, dpkgStatusPath+".d/*")
+		for _, name := range append([]string{dpkgStatusPath}, names...) {
+			if strings.HasSuffix(name, ".md5sums") {
+				continue
+			}
+			data, err := fs.ReadFile(fsys, name)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err == nil {
+				err = idx.Read(bytes.NewReader(data))
+			}
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("cannot read dpkg status file /%s: %v", name, err))
+			}
+		}
+	}
+	if idx.Len() == 0 {
+		return nil, warnings, nil
+	}
+	return idx, warnings, nil
+}
+
+// dpkgStatusPath is the path of the dpkg status database in a rootfs.
+// Distroless images have a file per package in the status.d directory
+// instead.
+const dpkgStatusPath = "var/lib/dpkg/status"
+
+// docPath is the directory of the documentation of packages, with their
+// copyright files.
+const docPath = "usr/share/doc"
+
+// wantFile selects the files whose content is needed from archives.
+func wantFile(name string) bool {
+	if name == OSReleasePath || path.Base(name) == converter.ManifestName {
+		return true
+	}
+	if name == dpkgStatusPath || path.Dir(name) == dpkgStatusPath+".d" {
+		return true
+	}
+	matched, _ := path.Match(docPath+"/*/copyright", name)
+	return matched
+}
@@ -0,0 +1,766 @@
+package sbom_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/canonical/ssbom/internal/builder"
+	"github.com/canonical/ssbom/sbom"
+	"github.com/canonical/ssbom/sbom/cyclonedx"
+	"github.com/canonical/ssbom/sbom/spdx3"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+	. "gopkg.in/check.v1"
+)
+
+var sampleManifest = strings.Join([]string{
+	`{"jsonwall":"1.0","schema":"1.0","count":5}`,
+	`{"kind":"content","slice":"hello_bins","path":"/usr/bin/hello"}`,
+	`{"kind":"package","name":"hello","version":"2.10-3build1","sha256":"aaaa","arch":"amd64"}`,
+	`{"kind":"path","path":"/usr/bin/hello","mode":"0755","slices":["hello_bins"],"sha256":"bbbb","size":100}`,
+	`{"kind":"slice","name":"hello_bins"}`,
+}, "\n")
+
+const sampleOSRelease = "NAME=\"Ubuntu\"\nVERSION_ID=\"24.04\"\nID=ubuntu\n"
+
+var sampleRootfs = fstest.MapFS{
+	"var/lib/chisel/manifest.wall": &fstest.MapFile{Data: []byte(sampleManifest)},
+	"etc/os-release":               &fstest.MapFile{Data: []byte(sampleOSRelease)},
+}
+
+func (s *S) TestGenerateFS(c *C) {
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: sampleRootfs}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result.Format, Equals, sbom.SPDX)
+	c.Assert(result.Manifests, DeepEquals, []string{"/var/lib/chisel/manifest.wall"})
+	c.Assert(result.Warnings, HasLen, 0)
+	c.Assert(result.SPDX3, IsNil)
+	c.Assert(result.CycloneDX, IsNil)
+
+	var names []string
+	for _, p := range result.SPDX.Packages {
+		names = append(names, p.PackageName)
+	}
+	c.Assert(names, DeepEquals, []string{"ubuntu", "hello", "hello_bins"})
+	c.Assert(result.SPDX.Files, HasLen, 1)
+}
+
+func (s *S) TestGenerateManifest(c *C) {
+	source := &sbom.Source{Manifest: strings.NewReader(sampleManifest)}
+	result, err := sbom.Generate(context.Background(), source, &sbom.Options{Format: sbom.CycloneDX15})
+	c.Assert(err, IsNil)
+	c.Assert(result.SPDX, IsNil)
+	c.Assert(result.CycloneDX.SpecVersion, Equals, cyclonedx.SpecVersion15)
+	c.Assert(result.Manifests, HasLen, 0)
+	c.Assert(result.Warnings, DeepEquals, []string{
+		"OS release file not found; the generated SBOM will be incomplete for vulnerability identification",
+	})
+
+	var buf bytes.Buffer
+	c.Assert(result.Write(&buf), IsNil)
+	c.Assert(buf.String(), Matches, `\{"bomFormat":"CycloneDX","specVersion":"1.5",.*\n`)
+}
+
+func (s *S) TestGeneratePath(c *C) {
+	dir := c.MkDir()
+	for name, file := range sampleRootfs {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(os.WriteFile(p, file.Data, 0644), IsNil)
+	}
+	source := &sbom.Source{
+		Path:      dir,
+		OSRelease: strings.NewReader("VERSION_ID=22.04\n"),
+	}
+	result, err := sbom.Generate(context.Background(), source, &sbom.Options{Format: sbom.SPDX3})
+	c.Assert(err, IsNil)
+	c.Assert(result.SPDX3, NotNil)
+
+	var buf bytes.Buffer
+	c.Assert(result.Write(&buf), IsNil)
+	c.Assert(buf.String(), Matches, `(?s).*"software_packageUrl":"pkg:deb/ubuntu/hello@2.10-3build1\?arch=amd64&distro=ubuntu-22.04".*`)
+}
+
+func (s *S) TestGenerateErrors(c *C) {
+	ctx := context.Background()
+	_, err := sbom.Generate(ctx, &sbom.Source{}, nil)
+	c.Assert(err, ErrorMatches, "cannot generate SBOM: no rootfs or manifest given")
+
+	_, err = sbom.Generate(ctx, &sbom.Source{FS: sampleRootfs}, &sbom.Options{Format: "xml"})
+	c.Assert(err, ErrorMatches, `cannot generate SBOM: unknown format "xml"`)
+
+	_, err = sbom.Generate(ctx, &sbom.Source{FS: fstest.MapFS{"etc/os-release": sampleRootfs["etc/os-release"]}}, nil)
+	c.Assert(err, ErrorMatches, "cannot find a chisel manifest in the rootfs")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = sbom.Generate(cancelled, &sbom.Source{FS: sampleRootfs}, nil)
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *S) TestGenerateLenient(c *C) {
+	corrupt := strings.Replace(sampleManifest, `"size":100`, `"size":"100"`, 1)
+	rootfs := fstest.MapFS{
+		"var/lib/chisel/manifest.wall": &fstest.MapFile{Data: []byte(corrupt)},
+		"etc/os-release":               sampleRootfs["etc/os-release"],
+	}
+	_, err := sbom.Generate(context.Background(), &sbom.Source{FS: rootfs}, nil)
+	c.Assert(err, ErrorMatches, "cannot read path entry 1 of /var/lib/chisel/manifest.wall: json: .*")
+
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: rootfs}, &sbom.Options{Lenient: true})
+	c.Assert(err, IsNil)
+	c.Assert(result.Warnings, HasLen, 2)
+	c.Assert(result.Warnings[0], Matches, "cannot read path entry 1 of /var/lib/chisel/manifest.wall: json: .*")
+	c.Assert(result.Warnings[1], Equals, "inconsistent manifest: content hello_bins:/usr/bin/hello: path /usr/bin/hello has no path entry")
+	c.Assert(result.SPDX.Files, HasLen, 0)
+	c.Assert(result.SPDX.Annotations, HasLen, 1)
+}
+
+func (s *S) TestValidateManifest(c *C) {
+	report, err := sbom.ValidateManifest(context.Background(), &sbom.Source{FS: sampleRootfs}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Manifests, DeepEquals, []string{"/var/lib/chisel/manifest.wall"})
+	c.Assert(report.Issues, HasLen, 0)
+
+	corrupt := strings.Replace(sampleManifest, `"slice":"hello_bins"`, `"slice":"hello_libs"`, 1)
+	report, err = sbom.ValidateManifest(context.Background(), &sbom.Source{Manifest: strings.NewReader(corrupt)}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Issues, DeepEquals, []string{
+		"path /usr/bin/hello: no content entry for slice hello_bins",
+		"content hello_libs:/usr/bin/hello: slice hello_libs has no slice entry",
+		"content hello_libs:/usr/bin/hello: path /usr/bin/hello is not in slice hello_libs",
+	})
+}
+
+// tarDir writes the files of dir to a tar archive and returns its path.
+func tarDir(c *C, dir string) string {
+	archive := filepath.Join(c.MkDir(), "rootfs.tar")
+	f, err := os.Create(archive)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		name, err := filepath.Rel(dir, p)
+		c.Assert(err, IsNil)
+		hdr, err := tar.FileInfoHeader(info, "")
+		c.Assert(err, IsNil)
+		hdr.Name = filepath.ToSlash(name)
+		c.Assert(tw.WriteHeader(hdr), IsNil)
+		data, err := os.ReadFile(p)
+		c.Assert(err, IsNil)
+		_, err = tw.Write(data)
+		return err
+	})
+	c.Assert(err, IsNil)
+	c.Assert(tw.Close(), IsNil)
+	return archive
+}
+
+func (s *S) TestGenerateVerify(c *C) {
+	dir := c.MkDir()
+	for name, data := range map[string]string{
+		"var/lib/chisel/manifest.wall": sampleManifest,
+		"etc/os-release":               sampleOSRelease,
+		"usr/bin/hello":                "tampered",
+	} {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(os.WriteFile(p, []byte(data), 0755), IsNil)
+	}
+
+	result, err := sbom.Generate(context.Background(), &sbom.Source{Path: dir}, &sbom.Options{Verify: true})
+	c.Assert(err, IsNil)
+	c.Assert(result.SPDX, NotNil)
+	var discrepancies []string
+	for _, d := range result.Discrepancies {
+		discrepancies = append(discrepancies, d.String())
+	}
+	c.Assert(discrepancies, DeepEquals, []string{
+		"tampered /usr/bin/hello: sha256 d121be3103007b41edf96f8262925f8c7d61894afe9a041843b631f69445bc57, expected bbbb",
+	})
+
+	_, err = sbom.Generate(context.Background(), &sbom.Source{Path: dir}, &sbom.Options{VerifyStrict: true})
+	c.Assert(err, ErrorMatches, "rootfs does not match its manifest: 1 discrepancies found")
+	verifyErr, ok := err.(*sbom.VerifyError)
+	c.Assert(ok, Equals, true)
+	c.Assert(verifyErr.Discrepancies, DeepEquals, result.Discrepancies)
+
+	// The manifest is never reported as extra.
+	extra, err := sbom.Generate(context.Background(), &sbom.Source{Path: dir}, &sbom.Options{VerifyExtra: true})
+	c.Assert(err, IsNil)
+	c.Assert(extra.Discrepancies, HasLen, 2)
+	c.Assert(extra.Discrepancies[0], DeepEquals, &sbom.Discrepancy{Kind: sbom.Extra, Path: "/etc/os-release"})
+
+	// Files of archives are hashed as they are read.
+	archive := tarDir(c, dir)
+	archived, err := sbom.Generate(context.Background(), &sbom.Source{Path: archive}, &sbom.Options{Verify: true})
+	c.Assert(err, IsNil)
+	c.Assert(archived.Discrepancies, DeepEquals, result.Discrepancies)
+
+	source := &sbom.Source{Manifest: strings.NewReader(sampleManifest)}
+	_, err = sbom.Generate(context.Background(), source, &sbom.Options{Verify: true})
+	c.Assert(err, ErrorMatches, "cannot verify rootfs: no rootfs given, or it does not support symbolic links")
+}
+
+func (s *S) TestGenerateUnmanaged(c *C) {
+	dir := c.MkDir()
+	for name, data := range map[string]string{
+		"var/lib/chisel/manifest.wall": sampleManifest,
+		"etc/os-release":               sampleOSRelease,
+		"usr/bin/hello":                "hello\n",
+	} {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(os.WriteFile(p, []byte(data), 0644), IsNil)
+	}
+
+	result, err := sbom.Generate(context.Background(), &sbom.Source{Path: dir}, &sbom.Options{Unmanaged: true})
+	c.Assert(err, IsNil)
+	var paths []string
+	for _, f := range result.Unmanaged {
+		paths = append(paths, f.Path)
+	}
+	c.Assert(paths, DeepEquals, []string{"/etc/os-release", "/var/lib/chisel/manifest.wall"})
+
+	archived, err := sbom.Generate(context.Background(), &sbom.Source{Path: tarDir(c, dir)}, &sbom.Options{Unmanaged: true})
+	c.Assert(err, IsNil)
+	c.Assert(archived.Unmanaged, DeepEquals, result.Unmanaged)
+
+	doc := result.SPDX
+	c.Assert(doc.Packages[len(doc.Packages)-1].PackageName, Equals, "unmanaged-content")
+	c.Assert(doc.Files, HasLen, 3)
+	file := doc.Files[0]
+	c.Assert(file.FileName, Equals, "/etc/os-release")
+	c.Assert(file.Checksums, HasLen, 2)
+	c.Assert(file.Checksums[1].Value, Equals, result.Unmanaged[0].SHA256)
+	var contained []string
+	for _, rln := range doc.Relationships {
+		if rln.RefA.ElementRefID == "UnmanagedContent" && rln.Relationship == "CONTAINS" {
+			contained = append(contained, string(rln.RefB.ElementRefID))
+		}
+	}
+	c.Assert(contained, DeepEquals, []string{"File-etc-os-release-da273c23", "File-var-lib-chisel-manifest.wall-e3f9a584"})
+
+	result, err = sbom.Generate(context.Background(), &sbom.Source{Path: dir}, &sbom.Options{Format: sbom.CycloneDX, Unmanaged: true})
+	c.Assert(err, IsNil)
+	components := result.CycloneDX.Components
+	group := components[len(components)-1]
+	c.Assert(group.Name, Equals, "unmanaged-content")
+	c.Assert(group.Components, HasLen, 2)
+	c.Assert(group.Components[0].Hashes[0].Algorithm, Equals, cyclonedx.SHA1)
+
+	result, err = sbom.Generate(context.Background(), &sbom.Source{Path: dir}, &sbom.Options{Format: sbom.SPDX3, Unmanaged: true})
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	c.Assert(result.Write(&buf), IsNil)
+	c.Assert(buf.String(), Matches, `(?s).*"name":"unmanaged-content".*"name":"/etc/os-release".*"algorithm":"sha1".*`)
+
+	source := &sbom.Source{Manifest: strings.NewReader(sampleManifest)}
+	_, err = sbom.Generate(context.Background(), source, &sbom.Options{Unmanaged: true})
+	c.Assert(err, ErrorMatches, "cannot find unmanaged files: no rootfs given, or it does not support symbolic links")
+}
+
+func (s *S) TestGenerateGoModules(c *C) {
+	// The test executable is itself built by Go.
+	exe, err := os.Executable()
+	c.Assert(err, IsNil)
+	data, err := os.ReadFile(exe)
+	c.Assert(err, IsNil)
+	fsys := fstest.MapFS{"opt/gotool": &fstest.MapFile{Data: data, Mode: 0755}}
+	for name, file := range sampleRootfs {
+		fsys[name] = file
+	}
+
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, &sbom.Options{GoModules: true})
+	c.Assert(err, IsNil)
+	c.Assert(result.GoBinaries, HasLen, 1)
+	c.Assert(result.GoBinaries[0].Path, Equals, "/opt/gotool")
+
+	doc := result.SPDX
+	c.Assert(doc.Files[0].FileName, Equals, "/opt/gotool")
+	purls := make(map[string]string)
+	var stdlib string
+	for _, p := range doc.Packages {
+		for _, ref := range p.PackageExternalReferences {
+			if ref.RefType == "purl" {
+				purls[string(p.PackageSPDXIdentifier)] = ref.Locator
+			}
+		}
+		if p.PackageName == "stdlib" {
+			stdlib = purls[string(p.PackageSPDXIdentifier)]
+		}
+	}
+	c.Assert(stdlib, Equals, "pkg:golang/stdlib@"+result.GoBinaries[0].StdlibVersion())
+	var linked []string
+	for _, rln := range doc.Relationships {
+		if rln.RefA.ElementRefID == "File-opt-gotool-8ccc6377" {
+			c.Assert(rln.Relationship, Equals, "CONTAINS")
+			linked = append(linked, string(rln.RefB.ElementRefID))
+		}
+	}
+	c.Assert(linked, HasLen, len(result.GoBinaries[0].Deps)+1)
+	for _, id := range linked {
+		c.Assert(strings.HasPrefix(purls[id], "pkg:golang/"), Equals, true)
+	}
+
+	result, err = sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, &sbom.Options{Format: sbom.CycloneDX, GoModules: true})
+	c.Assert(err, IsNil)
+	c.Assert(result.CycloneDX.Dependencies, HasLen, 1)
+	c.Assert(result.CycloneDX.Dependencies[0].Ref, Equals, "File-opt-gotool-8ccc6377")
+	c.Assert(result.CycloneDX.Dependencies[0].DependsOn, HasLen, len(linked))
+
+	source := &sbom.Source{Manifest: strings.NewReader(sampleManifest)}
+	_, err = sbom.Generate(context.Background(), source, &sbom.Options{GoModules: true})
+	c.Assert(err, ErrorMatches, "cannot scan Go executables: no rootfs given")
+}
+
+func (s *S) TestGenerateCataloguers(c *C) {
+	fsys := fstest.MapFS{
+		"usr/lib/python3/dist-packages/six-1.16.0.dist-info/METADATA": &fstest.MapFile{Data: []byte("Name: six\nVersion: 1.16.0\n")},
+		"srv/app/node_modules/six/package.json":                       &fstest.MapFile{Data: []byte(`{"name": "six", "version": "1.16.0"}`)},
+	}
+	for name, file := range sampleRootfs {
+		fsys[name] = file
+	}
+
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, &sbom.Options{Cataloguers: sbom.Cataloguers()})
+	c.Assert(err, IsNil)
+	c.Assert(result.EcosystemPackages, HasLen, 2)
+	var purls []string
+	for _, p := range result.SPDX.Packages {
+		for _, ref := range p.PackageExternalReferences {
+			if ref.RefType == "purl" {
+				purls = append(purls, ref.Locator)
+			}
+		}
+	}
+	c.Assert(purls, DeepEquals, []string{
+		"pkg:deb/ubuntu/hello@2.10-3build1?arch=amd64&distro=ubuntu-24.04",
+		"pkg:npm/six@1.16.0",
+		"pkg:pypi/six@1.16.0",
+	})
+
+	node, err := sbom.LookupCataloguer("node")
+	c.Assert(err, IsNil)
+	result, err = sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, &sbom.Options{Format: sbom.CycloneDX, Cataloguers: []sbom.Cataloguer{node}})
+	c.Assert(err, IsNil)
+	comp := result.CycloneDX.Components[len(result.CycloneDX.Components)-1]
+	c.Assert(comp.PURL, Equals, "pkg:npm/six@1.16.0")
+	c.Assert(comp.Evidence.Occurrences, DeepEquals, []cyclonedx.Occurrence{{Location: "/srv/app/node_modules/six/package.json"}})
+}
+
+const sampleCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+
+Files: *
+Copyright: 1992-2022 Free Software Foundation, Inc.
+License: GPL-3+
+
+Files: debian/*
+Copyright: 2024 Debian Developers
+License: public-domain
+`
+
+func (s *S) TestGenerateLicenses(c *C) {
+	fsys := fstest.MapFS{
+		"usr/share/doc/hello/copyright": &fstest.MapFile{Data: []byte(sampleCopyright)},
+	}
+	for name, file := range sampleRootfs {
+		fsys[name] = file
+	}
+
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result.Warnings, HasLen, 0)
+	doc := result.SPDX
+	hello := doc.Packages[1]
+	c.Assert(hello.PackageName, Equals, "hello")
+	c.Assert(hello.PackageLicenseDeclared, Equals, "GPL-3.0-or-later AND LicenseRef-public-domain")
+	c.Assert(hello.PackageLicenseConcluded, Equals, hello.PackageLicenseDeclared)
+	c.Assert(doc.Files[0].LicenseConcluded, Equals, "GPL-3.0-or-later")
+	c.Assert(doc.Files[0].FileCopyrightText, Equals, "1992-2022 Free Software Foundation, Inc.")
+	c.Assert(doc.OtherLicenses, HasLen, 1)
+	c.Assert(doc.OtherLicenses[0].LicenseIdentifier, Equals, "LicenseRef-public-domain")
+
+	// Copyright files given with the source take precedence.
+	source := &sbom.Source{
+		FS:         fsys,
+		Copyrights: fstest.MapFS{"hello/copyright": &fstest.MapFile{Data: []byte("Format: dep5\n\nFiles: *\nCopyright: none\nLicense: Expat\n")}},
+	}
+	result, err = sbom.Generate(context.Background(), source, &sbom.Options{Format: sbom.CycloneDX})
+	c.Assert(err, IsNil)
+	comp := result.CycloneDX.Components[0]
+	c.Assert(comp.Licenses, DeepEquals, []cyclonedx.LicenseChoice{{Expression: "MIT"}})
+	file := comp.Components[0].Components[0]
+	c.Assert(file.Copyright, Equals, "none")
+
+	fsys["usr/share/doc/hello/copyright"] = &fstest.MapFile{Data: []byte("Format: dep5\n\nnot a field\n")}
+	result, err = sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result.Warnings, DeepEquals, []string{"cannot read copyright file of package hello: cannot parse copyright file: line 3: expected a field"})
+}
+
+const samplePackages = `Package: hello
+Architecture: amd64
+Version: 2.10-3build1
+Source: hello (2.10-3)
+Maintainer: Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>
+Original-Maintainer: Santiago Vila <sanvila@debian.org>
+Filename: pool/main/h/hello/hello_2.10-3build1_amd64.deb
+Description: example package based on GNU hello
+ The GNU hello program produces a familiar, friendly greeting.
+`
+
+const sampleSources = `Package: hello
+Binary: hello
+Version: 2.10-3
+Homepage: https://www.gnu.org/software/hello/
+`
+
+func (s *S) TestGenerateAptIndexes(c *C) {
+	source := &sbom.Source{
+		FS:         sampleRootfs,
+		AptIndexes: []io.Reader{strings.NewReader(samplePackages), strings.NewReader(sampleSources)},
+	}
+	result, err := sbom.Generate(context.Background(), source, nil)
+	c.Assert(err, IsNil)
+	hello := result.SPDX.Packages[1]
+	c.Assert(hello.PackageName, Equals, "hello")
+	c.Assert(hello.PackageSourceInfo, Equals, "built package from: hello 2.10-3")
+	c.Assert(hello.PackageHomePage, Equals, "https://www.gnu.org/software/hello/")
+	c.Assert(hello.PackageSummary, Equals, "example package based on GNU hello")
+	c.Assert(hello.PackageDownloadLocation, Equals, "http://archive.ubuntu.com/ubuntu/pool/main/h/hello/hello_2.10-3build1_amd64.deb")
+	c.Assert(hello.PackageSupplier.SupplierType, Equals, "Person")
+	c.Assert(hello.PackageSupplier.Supplier, Equals, "Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>")
+	c.Assert(hello.PackageOriginator.Originator, Equals, "Santiago Vila <sanvila@debian.org>")
+
+	source = &sbom.Source{
+		FS: sampleRootfs,
+		AptMirror: fstest.MapFS{
+			"dists/noble/main/binary-amd64/Packages": &fstest.MapFile{Data: []byte(samplePackages)},
+		},
+	}
+	result, err = sbom.Generate(context.Background(), source, &sbom.Options{
+		Format:     sbom.CycloneDX,
+		ArchiveURL: "https://mirror.example.com/ubuntu/",
+	})
+	c.Assert(err, IsNil)
+	comp := result.CycloneDX.Components[0]
+	c.Assert(comp.Description, Equals, "example package based on GNU hello")
+	c.Assert(comp.Supplier.Name, Equals, "Ubuntu Developers")
+	c.Assert(comp.ExternalReferences, DeepEquals, []cyclonedx.ExternalReference{{
+		Type: cyclonedx.ReferenceDistribution,
+		URL:  "https://mirror.example.com/ubuntu/pool/main/h/hello/hello_2.10-3build1_amd64.deb",
+	}})
+}
+
+func (s *S) TestGenerateSourcePackages(c *C) {
+	fsys := fstest.MapFS{
+		"var/lib/dpkg/status.d/hello":         &fstest.MapFile{Data: []byte(samplePackages)},
+		"var/lib/dpkg/status.d/hello.md5sums": &fstest.MapFile{Data: []byte("bbbb  usr/bin/hello\n")},
+	}
+	for name, file := range sampleRootfs {
+		fsys[name] = file
+	}
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result.Warnings, HasLen, 0)
+	doc := result.SPDX
+	hello := doc.Packages[1]
+	c.Assert(hello.PackageExternalReferences[1].Locator, Equals, "pkg:deb/ubuntu/hello@2.10-3build1?arch=amd64&distro=ubuntu-24.04&upstream=hello%402.10-3")
+	source := doc.Packages[3]
+	c.Assert(source.PackageSPDXIdentifier, Equals, common.ElementID("SourcePackage-hello-2.10-3-084a192a"))
+	c.Assert(source.PackageVersion, Equals, "2.10-3")
+	c.Assert(source.PrimaryPackagePurpose, Equals, "SOURCE")
+	c.Assert(source.PackageExternalReferences[0].Locator, Equals, "pkg:deb/ubuntu/hello@2.10-3?arch=source&distro=ubuntu-24.04")
+	var generatedFrom []string
+	for _, rln := range doc.Relationships {
+		if rln.Relationship == "GENERATED_FROM" {
+			generatedFrom = append(generatedFrom, string(rln.RefA.ElementRefID)+" "+string(rln.RefB.ElementRefID))
+		}
+	}
+	c.Assert(generatedFrom, DeepEquals, []string{"Package-hello SourcePackage-hello-2.10-3-084a192a"})
+
+	// Without a source, the purl has no upstream qualifier.
+	result, err = sbom.Generate(context.Background(), &sbom.Source{FS: sampleRootfs}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result.SPDX.Packages[1].PackageExternalReferences[1].Locator, Equals, "pkg:deb/ubuntu/hello@2.10-3build1?arch=amd64&distro=ubuntu-24.04")
+}
+
+func (s *S) TestGenerateCPEs(c *C) {
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: sampleRootfs}, nil)
+	c.Assert(err, IsNil)
+	refs := result.SPDX.Packages[1].PackageExternalReferences
+	c.Assert(refs[0].RefType, Equals, "cpe23Type")
+	c.Assert(refs[0].Locator, Equals, "cpe:2.3:a:hello:hello:2.10:*:*:*:*:*:*:*")
+
+	cpeMap, err := sbom.ParseCPEMap([]byte("hello gnu:hello gnu:greeter\n"))
+	c.Assert(err, IsNil)
+	result, err = sbom.Generate(context.Background(), &sbom.Source{FS: sampleRootfs}, &sbom.Options{
+		Format: sbom.CycloneDX,
+		CPEMap: cpeMap,
+	})
+	c.Assert(err, IsNil)
+	comp := result.CycloneDX.Components[0]
+	c.Assert(comp.CPE, Equals, "cpe:2.3:a:gnu:hello:2.10:*:*:*:*:*:*:*")
+	c.Assert(comp.Properties, DeepEquals, []cyclonedx.Property{{Name: "ssbom:cpe23", Value: "cpe:2.3:a:gnu:greeter:2.10:*:*:*:*:*:*:*"}})
+}
+
+func (s *S) TestGenerateUbuntuPro(c *C) {
+	manifest := strings.Replace(sampleManifest, `"version":"2.10-3build1"`, `"version":"2.10-3build1+esm1"`, 1)
+	source := &sbom.Source{
+		Manifest:  strings.NewReader(manifest),
+		OSRelease: strings.NewReader(sampleOSRelease),
+	}
+	result, err := sbom.Generate(context.Background(), source, &sbom.Options{ToolVersion: "v1.2.3"})
+	c.Assert(err, IsNil)
+	doc := result.SPDX
+	c.Assert(doc.Packages[0].PackageComment, Equals, "This package is the distribution of the rootfs, with packages from the Ubuntu Pro esm-infra pockets.")
+	hello := doc.Packages[1]
+	c.Assert(hello.PackageSupplier.Supplier, Equals, "Canonical Ltd.")
+	c.Assert(hello.PackageExternalReferences[1].Locator, Equals, "pkg:deb/ubuntu/hello@2.10-3build1+esm1?arch=amd64&distro=ubuntu-24.04&repository_url=https%3A%2F%2Fesm.ubuntu.com%2Finfra%2Fubuntu")
+	c.Assert(doc.Annotations, HasLen, 1)
+	c.Assert(doc.Annotations[0].AnnotationSPDXIdentifier.ElementRefID, Equals, common.ElementID("Package-hello"))
+	c.Assert(doc.Annotations[0].AnnotationComment, Equals, "This package comes from the Ubuntu Pro esm-infra pocket.")
+	c.Assert(doc.Annotations[0].Annotator, Equals, common.Annotator{Annotator: "ssbom-v1.2.3", AnnotatorType: "Tool"})
+
+	source = &sbom.Source{
+		Manifest:  strings.NewReader(manifest),
+		OSRelease: strings.NewReader(sampleOSRelease),
+	}
+	result, err = sbom.Generate(context.Background(), source, &sbom.Options{Format: sbom.CycloneDX, ProPocket: "esm-apps", ToolVersion: "v1.2.3"})
+	c.Assert(err, IsNil)
+	comp := result.CycloneDX.Components[0]
+	c.Assert(comp.Supplier.Name, Equals, "Canonical Ltd.")
+	c.Assert(strings.HasSuffix(comp.PURL, "&repository_url=https%3A%2F%2Fesm.ubuntu.com%2Fapps%2Fubuntu"), Equals, true)
+	c.Assert(result.CycloneDX.Annotations[0].Text, Equals, "This package comes from the Ubuntu Pro esm-apps pocket.")
+	annotator := result.CycloneDX.Annotations[0].Annotator.Component
+	c.Assert(annotator.Name+"-"+annotator.Version, Equals, "ssbom-v1.2.3")
+
+	_, err = sbom.Generate(context.Background(), source, &sbom.Options{ProPocket: "esm"})
+	c.Assert(err, ErrorMatches, `cannot generate SBOM: unknown Ubuntu Pro pocket "esm", .*`)
+}
+
+func (s *S) TestGenerateDebian(c *C) {
+	source := &sbom.Source{
+		Manifest:   strings.NewReader(sampleManifest),
+		OSRelease:  strings.NewReader("PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nNAME=\"Debian GNU/Linux\"\nVERSION_ID=\"12\"\nVERSION_CODENAME=bookworm\nID=debian\nHOME_URL=\"https://www.debian.org/\"\n"),
+		AptIndexes: []io.Reader{strings.NewReader(samplePackages)},
+	}
+	result, err := sbom.Generate(context.Background(), source, nil)
+	c.Assert(err, IsNil)
+	doc := result.SPDX
+	osPackage := doc.Packages[0]
+	c.Assert(osPackage.PackageSPDXIdentifier, Equals, common.ElementID("OperatingSystem-debian-12"))
+	c.Assert(osPackage.PackageName, Equals, "debian")
+	c.Assert(osPackage.PackageVersion, Equals, "12")
+	c.Assert(osPackage.PackageSummary, Equals, "Debian GNU/Linux 12 (bookworm)")
+	c.Assert(osPackage.PackageHomePage, Equals, "https://www.debian.org/")
+	c.Assert(osPackage.PackageSupplier.Supplier, Equals, "Debian GNU/Linux")
+	hello := doc.Packages[1]
+	c.Assert(hello.PackageExternalReferences[1].Locator, Equals, "pkg:deb/debian/hello@2.10-3build1?arch=amd64&distro=debian-12&upstream=hello%402.10-3")
+	c.Assert(hello.PackageDownloadLocation, Equals, "http://deb.debian.org/debian/pool/main/h/hello/hello_2.10-3build1_amd64.deb")
+}
+
+func (s *S) TestGenerateDerivatives(c *C) {
+	generate := func(osRelease string) *spdx.Package {
+		source := &sbom.Source{
+			Manifest:   strings.NewReader(sampleManifest),
+			OSRelease:  strings.NewReader(osRelease),
+			AptIndexes: []io.Reader{strings.NewReader(samplePackages)},
+		}
+		result, err := sbom.Generate(context.Background(), source, nil)
+		c.Assert(err, IsNil)
+		return result.SPDX.Packages[1]
+	}
+
+	// Derivatives of Debian use its archive and purl namespace.
+	hello := generate("NAME=\"Raspbian GNU/Linux\"\nVERSION_ID=\"12\"\nID=raspbian\nID_LIKE=debian\n")
+	c.Assert(hello.PackageExternalReferences[1].Locator, Equals, "pkg:deb/debian/hello@2.10-3build1?arch=amd64&distro=raspbian-12&upstream=hello%402.10-3")
+	c.Assert(hello.PackageDownloadLocation, Equals, "http://deb.debian.org/debian/pool/main/h/hello/hello_2.10-3build1_amd64.deb")
+
+	// Derivatives of Ubuntu use its archive, purl namespace and supplier.
+	hello = generate("NAME=\"Pop!_OS\"\nVERSION_ID=\"22.04\"\nID=pop\nID_LIKE=\"ubuntu debian\"\n")
+	c.Assert(hello.PackageExternalReferences[1].Locator, Equals, "pkg:deb/ubuntu/hello@2.10-3build1?arch=amd64&distro=pop-22.04&upstream=hello%402.10-3")
+	c.Assert(hello.PackageDownloadLocation, Equals, "http://archive.ubuntu.com/ubuntu/pool/main/h/hello/hello_2.10-3build1_amd64.deb")
+}
+
+func (s *S) TestGenerateDocumentInfo(c *C) {
+	opts := &sbom.Options{
+		DocumentName:        "My Image",
+		NamespacePrefix:     "https://sbom.example.com/",
+		CreatorOrganization: "Example Inc.",
+		ToolVersion:         "v1.2.3",
+	}
+	result, err := sbom.Generate(context.Background(), &sbom.Source{Manifest: strings.NewReader(sampleManifest)}, opts)
+	c.Assert(err, IsNil)
+	doc := result.SPDX
+	c.Assert(doc.DocumentName, Equals, "My Image")
+	c.Assert(doc.DocumentNamespace, Matches, `https://sbom\.example\.com/my-image-[0-9a-f-]{36}`)
+	c.Assert(doc.CreationInfo.Creators, DeepEquals, []common.Creator{
+		{Creator: "ssbom-v1.2.3", CreatorType: "Tool"},
+		{Creator: "Example Inc.", CreatorType: "Organization"},
+	})
+	created, err := time.Parse(time.RFC3339, doc.CreationInfo.Created)
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(created) < time.Minute, Equals, true)
+
+	// Every document has its own namespace.
+	other, err := sbom.Generate(context.Background(), &sbom.Source{Manifest: strings.NewReader(sampleManifest)}, opts)
+	c.Assert(err, IsNil)
+	c.Assert(other.SPDX.DocumentNamespace, Not(Equals), doc.DocumentNamespace)
+
+	opts.Format = sbom.CycloneDX
+	result, err = sbom.Generate(context.Background(), &sbom.Source{Manifest: strings.NewReader(sampleManifest)}, opts)
+	c.Assert(err, IsNil)
+	bom := result.CycloneDX
+	c.Assert(bom.SerialNumber, Matches, `urn:uuid:[0-9a-f-]{36}`)
+	c.Assert(bom.Metadata.Timestamp, Equals, doc.CreationInfo.Created)
+	c.Assert(bom.Metadata.Tools.Components[0].Name, Equals, "ssbom")
+	c.Assert(bom.Metadata.Tools.Components[0].Version, Equals, "v1.2.3")
+	c.Assert(builder.ChiselSbomCycloneDXTool.Version, Equals, "")
+
+	opts.Format = sbom.SPDX3
+	result, err = sbom.Generate(context.Background(), &sbom.Source{Manifest: strings.NewReader(sampleManifest)}, opts)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, element := range result.SPDX3.Graph[1:] {
+		switch e := element.(type) {
+		case *spdx3.SpdxDocument:
+			c.Assert(e.Name, Equals, "My Image")
+		case *spdx3.Agent:
+			names = append(names, e.Type+": "+e.Name)
+		}
+	}
+	c.Assert(names, DeepEquals, []string{
+		"SoftwareAgent: ssbom-v1.2.3",
+		"Tool: ssbom-v1.2.3",
+		"Organization: Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>",
+		"Organization: Example Inc.",
+	})
+	creationInfo := result.SPDX3.Graph[0].(*spdx3.CreationInfo)
+	c.Assert(creationInfo.CreatedBy, HasLen, 2)
+}
+
+func (s *S) TestGenerateReproducible(c *C) {
+	rootfs := fstest.MapFS{
+		"var/lib/dpkg/status.d/hello": &fstest.MapFile{Data: []byte(samplePackages)},
+	}
+	for name, file := range sampleRootfs {
+		rootfs[name] = file
+	}
+	generate := func(format sbom.Format, fsys fs.FS) []byte {
+		result, err := sbom.Generate(context.Background(), &sbom.Source{FS: fsys}, &sbom.Options{
+			Format:       format,
+			Reproducible: true,
+		})
+		c.Assert(err, IsNil)
+		var buf bytes.Buffer
+		c.Assert(result.Write(&buf), IsNil)
+		return buf.Bytes()
+	}
+
+	defer os.Unsetenv("SOURCE_DATE_EPOCH")
+	os.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	for _, format := range []sbom.Format{sbom.SPDX, sbom.SPDX3, sbom.CycloneDX} {
+		c.Logf("Format: %s", format)
+		first := generate(format, rootfs)
+		second := generate(format, rootfs)
+		c.Assert(string(second), Equals, string(first))
+		c.Assert(string(first), Matches, `(?s).*"2023-11-14T22:13:20Z".*`)
+	}
+
+	doc := generate(sbom.SPDX, rootfs)
+	c.Assert(string(doc), Matches, `(?s).*"documentNamespace":"https://spdx.org/spdxdocs/chiselled-ubuntu-rootfs-[0-9a-f]{64}".*`)
+	other := fstest.MapFS{
+		"var/lib/chisel/manifest.wall": &fstest.MapFile{Data: []byte(strings.Replace(sampleManifest, "bbbb", "cccc", 1))},
+	}
+	c.Assert(generate(sbom.SPDX, other), Not(DeepEquals), doc)
+
+	os.Setenv("SOURCE_DATE_EPOCH", "")
+	c.Assert(string(generate(sbom.SPDX, rootfs)), Matches, `(?s).*"created":"1970-01-01T00:00:00Z".*`)
+
+	// The namespace and serial number depend on the content found besides
+	// the manifest and on the options, not only on the manifest.
+	dir := c.MkDir()
+	for name, data := range map[string]string{
+		"var/lib/chisel/manifest.wall": sampleManifest,
+		"etc/os-release":               sampleOSRelease,
+	} {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(os.WriteFile(p, []byte(data), 0644), IsNil)
+	}
+	identify := func(opts *sbom.Options) string {
+		opts.Reproducible = true
+		result, err := sbom.Generate(context.Background(), &sbom.Source{Path: dir}, opts)
+		c.Assert(err, IsNil)
+		switch {
+		case result.CycloneDX != nil:
+			return result.CycloneDX.SerialNumber
+		case result.SPDX3 != nil:
+			return result.SPDX3.Graph[1].(*spdx3.SpdxDocument).SpdxID
+		}
+		return result.SPDX.DocumentNamespace
+	}
+	for _, format := range []sbom.Format{sbom.SPDX, sbom.SPDX3, sbom.CycloneDX} {
+		c.Logf("Format: %s", format)
+		plain := identify(&sbom.Options{Format: format})
+		c.Assert(identify(&sbom.Options{Format: format}), Equals, plain)
+		c.Assert(identify(&sbom.Options{Format: format, Unmanaged: true}), Not(Equals), plain)
+	}
+	c.Assert(identify(&sbom.Options{CreatorOrganization: "Example Inc."}), Not(Equals), identify(&sbom.Options{}))
+	c.Assert(identify(&sbom.Options{Format: sbom.SPDX3, NamespacePrefix: "https://sbom.example.com"}), Matches,
+		`https://sbom.example.com/chiselled-ubuntu-rootfs-[0-9a-f]{64}#DOCUMENT`)
+
+	os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, err := sbom.Generate(context.Background(), &sbom.Source{FS: rootfs}, nil)
+	c.Assert(err, ErrorMatches, `cannot generate SBOM: invalid SOURCE_DATE_EPOCH "yesterday"`)
+}
+
+func (s *S) TestGenerateValidation(c *C) {
+	manifest := strings.Join([]string{
+		`{"jsonwall":"1.0","schema":"1.0","count":9}`,
+		`{"kind":"content","slice":"hello_bins","path":"/usr/bin/"}`,
+		`{"kind":"content","slice":"hello_bins","path":"/usr/bin/hello"}`,
+		`{"kind":"content","slice":"hello_bins","path":"/usr/bin/hi"}`,
+		`{"kind":"package","name":"hello","version":"2.10-3build1","sha256":"` + builder.EmptySHA256 + `","arch":"amd64"}`,
+		`{"kind":"path","path":"/usr/bin/","mode":"0755","slices":["hello_bins"]}`,
+		`{"kind":"path","path":"/usr/bin/hello","mode":"0755","slices":["hello_bins"],"sha256":"` + builder.EmptySHA256 + `","size":0}`,
+		`{"kind":"path","path":"/usr/bin/hi","mode":"0777","slices":["hello_bins"],"link":"/usr/bin/hello"}`,
+		`{"kind":"slice","name":"hello_bins"}`,
+	}, "\n")
+	rootfs := fstest.MapFS{
+		"var/lib/chisel/manifest.wall": &fstest.MapFile{Data: []byte(manifest)},
+		"etc/os-release":               &fstest.MapFile{Data: []byte(sampleOSRelease)},
+	}
+	for _, format := range []sbom.Format{sbom.SPDX, sbom.SPDX3, sbom.CycloneDX, sbom.CycloneDX15} {
+		c.Logf("Format: %s", format)
+		result, err := sbom.Generate(context.Background(), &sbom.Source{FS: rootfs}, &sbom.Options{Format: format})
+		c.Assert(err, IsNil)
+		c.Assert(result.Validation, HasLen, 0)
+	}
+
+	// The sample manifest has digests that are too short.
+	result, err := sbom.Generate(context.Background(), &sbom.Source{FS: sampleRootfs}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result.Validation, DeepEquals, []*sbom.ValidationIssue{{
+		Severity: sbom.ValidationError,
+		Element:  "SPDXRef-Package-hello",
+		Message:  `SHA256 checksum "aaaa" has 4 digits instead of 64`,
+	}, {
+		Severity: sbom.ValidationError,
+		Element:  "SPDXRef-File-usr-bin-hello-86a8fab9",
+		Message:  `SHA256 checksum "bbbb" has 4 digits instead of 64`,
+	}})
+}
@@ -0,0 +1,190 @@
+// Package spdx3 contains the subset of the SPDX 3.0 model, serialized as
+// JSON-LD, that is needed to describe a chiselled rootfs.
+// See https://spdx.github.io/spdx-spec/v3.0.1/
+package spdx3
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	Context     = "https://spdx.org/rdf/3.0.1/spdx-context.jsonld"
+	SpecVersion = "3.0.1"
+	DataLicense = "https://spdx.org/licenses/CC0-1.0"
+)
+
+// Element types.
+const (
+	TypeCreationInfo  = "CreationInfo"
+	TypeSpdxDocument  = "SpdxDocument"
+	TypeOrganization  = "Organization"
+	TypePerson        = "Person"
+	TypeSoftwareAgent = "SoftwareAgent"
+	TypeTool          = "Tool"
+	TypePackage       = "software_Package"
+	TypeFile          = "software_File"
+	TypeRelationship  = "Relationship"
+	TypeAnnotation    = "Annotation"
+
+	TypeLicenseExpression = "simplelicensing_LicenseExpression"
+)
+
+// Profiles.
+const (
+	ProfileCore            = "core"
+	ProfileSoftware        = "software"
+	ProfileSimpleLicensing = "simpleLicensing"
+)
+
+// Software purposes.
+const (
+	PurposeOperatingSystem = "operatingSystem"
+	PurposeLibrary         = "library"
+	PurposeFile            = "file"
+	PurposeOther           = "other"
+	PurposeSource          = "source"
+)
+
+// Relationship types.
+const (
+	RelationshipContains   = "contains"
+	RelationshipModifiedBy = "modifiedBy"
+	RelationshipDescribes  = "describes"
+	RelationshipDependsOn  = "dependsOn"
+	RelationshipGenerates  = "generates"
+
+	RelationshipHasDeclaredLicense  = "hasDeclaredLicense"
+	RelationshipHasConcludedLicense = "hasConcludedLicense"
+)
+
+// Annotation types.
+const (
+	AnnotationOther  = "other"
+	AnnotationReview = "review"
+)
+
+// External identifier types.
+const (
+	ExternalIdentifierCPE23 = "cpe23"
+	ExternalIdentifierPURL  = "packageUrl"
+)
+
+// Document is an SPDX 3.0 JSON-LD serialization: a context and a flat graph
+// of elements.
+type Document struct {
+	Context string `json:"@context"`
+	Graph   []any  `json:"@graph"`
+}
+
+type CreationInfo struct {
+	Type         string   `json:"type"`
+	ID           string   `json:"@id"`
+	SpecVersion  string   `json:"specVersion"`
+	Created      string   `json:"created"`
+	CreatedBy    []string `json:"createdBy"`
+	CreatedUsing []string `json:"createdUsing,omitempty"`
+}
+
+// Element holds the properties shared by every SPDX 3.0 element.
+type Element struct {
+	Type         string `json:"type"`
+	SpdxID       string `json:"spdxId"`
+	CreationInfo string `json:"creationInfo"`
+	Name         string `json:"name,omitempty"`
+	Summary      string `json:"summary,omitempty"`
+	Comment      string `json:"comment,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type Agent struct {
+	Element
+	ExternalIdentifier []ExternalIdentifier `json:"externalIdentifier,omitempty"`
+}
+
+type SpdxDocument struct {
+	Element
+	DataLicense        string   `json:"dataLicense"`
+	ProfileConformance []string `json:"profileConformance"`
+	RootElement        []string `json:"rootElement,omitempty"`
+	Elements           []string `json:"element,omitempty"`
+}
+
+type Package struct {
+	Element
+	SuppliedBy         string               `json:"suppliedBy,omitempty"`
+	OriginatedBy       []string             `json:"originatedBy,omitempty"`
+	VerifiedUsing      []Hash               `json:"verifiedUsing,omitempty"`
+	ExternalIdentifier []ExternalIdentifier `json:"externalIdentifier,omitempty"`
+	PrimaryPurpose     string               `json:"software_primaryPurpose,omitempty"`
+	PackageVersion     string               `json:"software_packageVersion,omitempty"`
+	PackageURL         string               `json:"software_packageUrl,omitempty"`
+	DownloadLocation   string               `json:"software_downloadLocation,omitempty"`
+	HomePage           string               `json:"software_homePage,omitempty"`
+	SourceInfo         string               `json:"software_sourceInfo,omitempty"`
+	CopyrightText      string               `json:"software_copyrightText,omitempty"`
+}
+
+type File struct {
+	Element
+	VerifiedUsing  []Hash `json:"verifiedUsing,omitempty"`
+	PrimaryPurpose string `json:"software_primaryPurpose,omitempty"`
+	CopyrightText  string `json:"software_copyrightText,omitempty"`
+}
+
+type LicenseExpression struct {
+	Element
+	LicenseExpression string `json:"simplelicensing_licenseExpression"`
+}
+
+type Relationship struct {
+	Element
+	From             string   `json:"from"`
+	To               []string `json:"to"`
+	RelationshipType string   `json:"relationshipType"`
+}
+
+type Annotation struct {
+	Element
+	AnnotationType string `json:"annotationType"`
+	Subject        string `json:"subject"`
+	Statement      string `json:"statement,omitempty"`
+}
+
+type Hash struct {
+	Type      string `json:"type"`
+	Algorithm string `json:"algorithm"`
+	HashValue string `json:"hashValue"`
+}
+
+type ExternalIdentifier struct {
+	Type                   string `json:"type"`
+	ExternalIdentifierType string `json:"externalIdentifierType"`
+	Identifier             string `json:"identifier"`
+}
+
+// SHA1 returns a Hash integrity method for a sha1 digest.
+func SHA1(value string) Hash {
+	return Hash{Type: "Hash", Algorithm: "sha1", HashValue: value}
+}
+
+// SHA256 returns a Hash integrity method for a sha256 digest.
+func SHA256(value string) Hash {
+	return Hash{Type: "Hash", Algorithm: "sha256", HashValue: value}
+}
+
+// NewExternalIdentifier returns an ExternalIdentifier of the given type.
+func NewExternalIdentifier(idType, identifier string) ExternalIdentifier {
+	return ExternalIdentifier{
+		Type:                   "ExternalIdentifier",
+		ExternalIdentifierType: idType,
+		Identifier:             identifier,
+	}
+}
+
+// Write writes the document to w in JSON-LD format.
+func Write(doc *Document, w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	return e.Encode(doc)
+}
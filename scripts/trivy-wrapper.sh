@@ -14,7 +14,7 @@ chiselled_rootfs=$1
 shift 1
 
 # Generate SBOM
-ssbom $chiselled_rootfs $sbom_file
+ssbom generate -o "$sbom_file" "$chiselled_rootfs" || exit 1
 
 # Run trivy
 trivy sbom $@ $sbom_file